@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloud"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8s"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	v1 "k8s.io/api/core/v1"
+)
+
+// shouldAbortRollout checks whether at least one of the given non-ready updated instances was launched more than
+// RolloutAbortTimeout ago, which means that the updated instances are unlikely to ever become ready (e.g. the ASG's
+// launch template references a broken AMI).
+//
+// Returns the launch time of the oldest non-ready updated instance, and whether the rollout should be aborted
+func shouldAbortRollout(ctx context.Context, ec2Service ec2iface.EC2API, autoScalingGroup *autoscaling.Group, nonReadyUpdatedInstances []*autoscaling.Instance) (time.Time, bool, error) {
+	timeout := config.Get().RolloutAbortTimeout
+	if timeout == 0 || len(nonReadyUpdatedInstances) == 0 {
+		return time.Time{}, false, nil
+	}
+	var instanceIDs []string
+	for _, instance := range nonReadyUpdatedInstances {
+		instanceIDs = append(instanceIDs, aws.StringValue(instance.InstanceId))
+	}
+	launchTimes, err := cloud.DescribeInstanceLaunchTimes(ctx, ec2Service, instanceIDs)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("unable to retrieve the launch time of non-ready updated instances: %w", err)
+	}
+	var oldestLaunchTime time.Time
+	for _, launchTime := range launchTimes {
+		if !launchTime.IsZero() && (oldestLaunchTime.IsZero() || launchTime.Before(oldestLaunchTime)) {
+			oldestLaunchTime = launchTime
+		}
+	}
+	if oldestLaunchTime.IsZero() || time.Since(oldestLaunchTime) < timeout {
+		return oldestLaunchTime, false, nil
+	}
+	return oldestLaunchTime, true, nil
+}
+
+// abortRollout stops the rollout of an ASG whose updated nodes aren't becoming ready by reverting every outdated
+// node whose rollout has started, but that hasn't been scheduled for termination yet: the node is uncordoned, the
+// label excluding it from external load balancers is removed if applicable, and so are the rolling update
+// annotations, all in a single patch.
+//
+// Aborts are never planned in dry run mode, since shouldAbortRollout describes the non-ready updated instances.
+//
+// No new step is started for as long as the updated instances remain non-ready, so the rollout only resumes once
+// the ASG's launch template or launch configuration has been fixed.
+func abortRollout(ctx context.Context, client k8s.ClientAPI, autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig, outdatedInstances []*autoscaling.Instance, numberOfNonReadyUpdatedInstances int, notReadySince time.Time) {
+	metrics.Server.AbortedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(1)
+	reason := fmt.Sprintf("%d updated instance(s) have not become ready since %s", numberOfNonReadyUpdatedInstances, notReadySince.Format(time.RFC3339))
+	log.Printf("[%s] Rollout is aborted because %s, make sure that the ASG's launch template or launch configuration is valid", aws.StringValue(autoScalingGroup.AutoScalingGroupName), reason)
+	for _, outdatedInstance := range outdatedInstances {
+		node, err := client.GetNodeByAutoScalingInstance(outdatedInstance)
+		if err != nil {
+			continue
+		}
+		if _, ok := node.Annotations[k8s.AnnotationRollingUpdateStartedTimestamp]; !ok {
+			// The node's rollout hasn't started, or has already been reverted
+			continue
+		}
+		if _, ok := node.Annotations[k8s.AnnotationRollingUpdateTerminatedTimestamp]; ok {
+			// It's too late to revert a node that has been scheduled for termination
+			continue
+		}
+		log.Printf("[%s][%s] Reverting node because rollout is aborted", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+		patch := k8s.NodePatch{Annotations: map[string]*string{
+			k8s.AnnotationRollingUpdateDrainedTimestamp: nil,
+			k8s.AnnotationRollingUpdateStartedTimestamp: nil,
+		}}
+		if node.Spec.Unschedulable {
+			patch.Unschedulable = aws.Bool(false)
+		}
+		if asgConfig.ExcludeFromExternalLoadBalancers {
+			patch.Labels = map[string]*string{k8s.LabelExcludeFromExternalLoadBalancers: nil}
+		}
+		// Since every change is made at once, the node is reverted again on the next execution if the patch failed
+		if err := k8s.PatchNodeByAutoScalingInstance(ctx, client, outdatedInstance, patch); err != nil {
+			metrics.Server.Errors.Inc()
+			log.Printf("[%s][%s] Unable to revert node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
+			continue
+		}
+		client.RecordEvent(node, v1.EventTypeWarning, k8s.EventReasonRolloutAborted, fmt.Sprintf("Rolling update of ASG %s was aborted because %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), reason))
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloud"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8s"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// QueryParameterAutoScalingGroup is the query parameter used to target a single ASG. If omitted, every ASG is
+	// targeted.
+	QueryParameterAutoScalingGroup = "asg"
+)
+
+// AdminAPI exposes endpoints to inspect the rolling updates in progress, and to pause and resume them
+//
+// Note that the pause state is held in memory by the controller, which means that it is lost when the application
+// restarts. With leader election enabled, only the leader accepts requests to pause and resume rolling updates, since
+// the controllers of the other replicas aren't running.
+//
+// The admin API has no authentication, so it must not be reachable from outside the cluster.
+type AdminAPI struct {
+	controller *Controller
+	// health is used to tell whether this replica is the leader
+	health *Health
+}
+
+// NewAdminAPI creates a new AdminAPI
+func NewAdminAPI(controller *Controller, health *Health) *AdminAPI {
+	return &AdminAPI{controller: controller, health: health}
+}
+
+// Register registers every endpoint of the admin API on the metrics server
+func (a *AdminAPI) Register() {
+	metrics.Server.Handle("/status", http.HandlerFunc(a.handleStatus))
+	metrics.Server.Handle("/pause", http.HandlerFunc(a.handlePause))
+	metrics.Server.Handle("/resume", http.HandlerFunc(a.handleResume))
+	metrics.Server.Handle("/plan", http.HandlerFunc(a.handlePlan))
+}
+
+// Status is the status of every ASG managed by the handler
+type Status struct {
+	Paused            bool                     `json:"paused"`
+	AutoScalingGroups []AutoScalingGroupStatus `json:"autoScalingGroups"`
+}
+
+// AutoScalingGroupStatus is the status of the rolling update of an ASG
+type AutoScalingGroupStatus struct {
+	Name            string       `json:"name"`
+	Paused          bool         `json:"paused"`
+	OutdatedNodes   []NodeStatus `json:"outdatedNodes"`
+	InProgressNodes []NodeStatus `json:"inProgressNodes"`
+	UpdatedNodes    []NodeStatus `json:"updatedNodes"`
+	Error           string       `json:"error,omitempty"`
+}
+
+// NodeStatus is the status of the rolling update of a single node
+type NodeStatus struct {
+	InstanceID string `json:"instanceId"`
+	NodeName   string `json:"nodeName,omitempty"`
+	// RolloutStep is the last rolling update step that the node went through, if any
+	RolloutStep string `json:"rolloutStep,omitempty"`
+	// RolloutStepTimestamp is when the node went through RolloutStep
+	RolloutStepTimestamp string `json:"rolloutStepTimestamp,omitempty"`
+}
+
+// handleStatus returns the status of every ASG, or of the ASG passed as query parameter
+func (a *AdminAPI) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	autoScalingGroupNames, ok := a.getAutoScalingGroupNames(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), config.Get().ExecutionTimeout)
+	defer cancel()
+	status := Status{
+		Paused:            a.controller.IsPaused(""),
+		AutoScalingGroups: []AutoScalingGroupStatus{},
+	}
+	autoScalingGroups, err := a.describeAutoScalingGroups(ctx, autoScalingGroupNames)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, autoScalingGroup := range autoScalingGroups {
+		status.AutoScalingGroups = append(status.AutoScalingGroups, a.getAutoScalingGroupStatus(ctx, autoScalingGroup))
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// getAutoScalingGroupStatus returns the status of the rolling update of the given ASG
+func (a *AdminAPI) getAutoScalingGroupStatus(ctx context.Context, autoScalingGroup *autoscaling.Group) AutoScalingGroupStatus {
+	autoScalingGroupName := aws.StringValue(autoScalingGroup.AutoScalingGroupName)
+	status := AutoScalingGroupStatus{
+		Name:            autoScalingGroupName,
+		Paused:          a.controller.IsPaused(autoScalingGroupName),
+		OutdatedNodes:   []NodeStatus{},
+		InProgressNodes: []NodeStatus{},
+		UpdatedNodes:    []NodeStatus{},
+	}
+	outdatedInstances, updatedInstances, err := SeparateOutdatedFromUpdatedInstances(ctx, autoScalingGroup, a.controller.ec2Service)
+	if err != nil {
+		status.Error = "unable to separate outdated instances from updated instances: " + err.Error()
+		return status
+	}
+	for _, outdatedInstance := range outdatedInstances {
+		nodeStatus := a.getNodeStatus(outdatedInstance)
+		if len(nodeStatus.RolloutStep) > 0 {
+			status.InProgressNodes = append(status.InProgressNodes, nodeStatus)
+		} else {
+			status.OutdatedNodes = append(status.OutdatedNodes, nodeStatus)
+		}
+	}
+	for _, updatedInstance := range updatedInstances {
+		status.UpdatedNodes = append(status.UpdatedNodes, a.getNodeStatus(updatedInstance))
+	}
+	return status
+}
+
+// getNodeStatus returns the status of the node of the given instance, based on its rolling update annotations
+func (a *AdminAPI) getNodeStatus(instance *autoscaling.Instance) NodeStatus {
+	nodeStatus := NodeStatus{InstanceID: aws.StringValue(instance.InstanceId)}
+	node, err := a.controller.client.GetNodeByAutoScalingInstance(instance)
+	if err != nil {
+		// The instance hasn't joined the cluster yet, or has already left it
+		return nodeStatus
+	}
+	nodeStatus.NodeName = node.Name
+	nodeStatus.RolloutStep, nodeStatus.RolloutStepTimestamp = getRolloutStep(node)
+	return nodeStatus
+}
+
+// getRolloutStep returns the last rolling update step that the given node went through, and when it did, or empty
+// strings if the node's rollout hasn't started
+func getRolloutStep(node *v1.Node) (string, string) {
+	for _, step := range []struct {
+		name       string
+		annotation string
+	}{
+		{name: "terminated", annotation: k8s.AnnotationRollingUpdateTerminatedTimestamp},
+		{name: "drained", annotation: k8s.AnnotationRollingUpdateDrainedTimestamp},
+		{name: "started", annotation: k8s.AnnotationRollingUpdateStartedTimestamp},
+	} {
+		if timestamp, ok := node.Annotations[step.annotation]; ok {
+			return step.name, timestamp
+		}
+	}
+	return "", ""
+}
+
+// handlePause pauses the rolling update of every ASG, or of the ASG passed as query parameter
+func (a *AdminAPI) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !a.checkLeader(w) {
+		return
+	}
+	autoScalingGroupName := r.URL.Query().Get(QueryParameterAutoScalingGroup)
+	if _, ok := a.getAutoScalingGroupNames(w, r); !ok {
+		return
+	}
+	a.controller.Pause(autoScalingGroupName)
+	log.Printf("Paused rolling update of %s through the admin API", describeTarget(autoScalingGroupName))
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+// handleResume resumes the rolling update of every ASG, or of the ASG passed as query parameter
+func (a *AdminAPI) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !a.checkLeader(w) {
+		return
+	}
+	autoScalingGroupName := r.URL.Query().Get(QueryParameterAutoScalingGroup)
+	if _, ok := a.getAutoScalingGroupNames(w, r); !ok {
+		return
+	}
+	a.controller.Resume(autoScalingGroupName)
+	log.Printf("Resumed rolling update of %s through the admin API", describeTarget(autoScalingGroupName))
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": a.controller.IsPaused(autoScalingGroupName)})
+}
+
+// handlePlan returns the actions that the next execution would take for every ASG, or for the ASG passed as query
+// parameter, without executing any of them
+//
+// Like during executions, ASGs whose rolling update is paused through the admin API are skipped.
+func (a *AdminAPI) handlePlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	autoScalingGroupNames, ok := a.getAutoScalingGroupNames(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), config.Get().ExecutionTimeout)
+	defer cancel()
+	autoScalingGroups, err := a.describeAutoScalingGroups(ctx, autoScalingGroupNames)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan := NewPlan(true)
+	for _, autoScalingGroup := range autoScalingGroups {
+		if a.controller.IsPaused(aws.StringValue(autoScalingGroup.AutoScalingGroupName)) {
+			continue
+		}
+		if _, err := ReconcileAutoScalingGroup(ctx, a.controller.client, a.controller.ec2Service, a.controller.autoScalingService, autoScalingGroup, plan); err != nil {
+			http.Error(w, fmt.Sprintf("unable to plan the rolling update of %s: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), err), http.StatusInternalServerError)
+			return
+		}
+	}
+	actions := plan.Actions
+	if actions == nil {
+		actions = []Action{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]Action{"actions": actions})
+}
+
+// checkLeader checks whether this replica can pause and resume rolling updates, which, with leader election enabled,
+// only the leader can, since the pause state is held in memory by the controller
+//
+// Writes a 503 response naming the current leader and returns false if this replica isn't the leader
+func (a *AdminAPI) checkLeader(w http.ResponseWriter) bool {
+	if !config.Get().LeaderElection || a.health.leading.Load() {
+		return true
+	}
+	leader := "unknown"
+	if currentLeader := a.health.leader.Load(); currentLeader != nil {
+		leader = *currentLeader
+	}
+	http.Error(w, fmt.Sprintf("this replica isn't the leader, send the request to the leader instead: %s", leader), http.StatusServiceUnavailable)
+	return false
+}
+
+// getAutoScalingGroupNames returns the ASG passed as query parameter, or every ASG discovered during the last resync
+// if none was passed
+//
+// Writes a 404 response and returns false if the ASG passed as query parameter isn't managed by the handler
+func (a *AdminAPI) getAutoScalingGroupNames(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	autoScalingGroupNames := a.controller.AutoScalingGroupNames()
+	autoScalingGroupName := r.URL.Query().Get(QueryParameterAutoScalingGroup)
+	if len(autoScalingGroupName) == 0 {
+		return autoScalingGroupNames, true
+	}
+	for _, name := range autoScalingGroupNames {
+		if name == autoScalingGroupName {
+			return []string{autoScalingGroupName}, true
+		}
+	}
+	http.Error(w, "AutoScalingGroup "+autoScalingGroupName+" is not managed by the handler", http.StatusNotFound)
+	return nil, false
+}
+
+// describeAutoScalingGroups describes the given ASGs
+func (a *AdminAPI) describeAutoScalingGroups(ctx context.Context, autoScalingGroupNames []string) ([]*autoscaling.Group, error) {
+	if len(autoScalingGroupNames) == 0 {
+		return nil, nil
+	}
+	autoScalingGroups, err := cloud.DescribeAutoScalingGroupsByNames(ctx, a.controller.autoScalingService, autoScalingGroupNames)
+	if err != nil {
+		return nil, errors.New("unable to describe AutoScalingGroups: " + err.Error())
+	}
+	return autoScalingGroups, nil
+}
+
+// describeTarget returns a human-readable description of the ASG(s) targeted by a request
+func describeTarget(autoScalingGroupName string) string {
+	if len(autoScalingGroupName) == 0 {
+		return "every AutoScalingGroup"
+	}
+	return "AutoScalingGroup " + autoScalingGroupName
+}
+
+// writeJSON writes the given value as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Unable to write admin API response: %s", err.Error())
+	}
+}
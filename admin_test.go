@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloudtest"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8s"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8stest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestAdminAPI_pauseAndResume(t *testing.T) {
+	controller := NewController(nil, nil, nil)
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg-a": true, "asg-b": true}
+	adminAPI := NewAdminAPI(controller, NewHealth())
+
+	if code := serveAdminAPI(adminAPI.handlePause, http.MethodGet, "/pause?asg=asg-a", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+	if code := serveAdminAPI(adminAPI.handlePause, http.MethodPost, "/pause?asg=asg-c", nil); code != http.StatusNotFound {
+		t.Errorf("expected %d for an unknown ASG, got %d", http.StatusNotFound, code)
+	}
+	if code := serveAdminAPI(adminAPI.handlePause, http.MethodPost, "/pause?asg=asg-a", nil); code != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, code)
+	}
+	if !controller.IsPaused("asg-a") || controller.IsPaused("asg-b") {
+		t.Error("expected only asg-a to be paused")
+	}
+	serveAdminAPI(adminAPI.handlePause, http.MethodPost, "/pause", nil)
+	if !controller.IsPaused("asg-a") || !controller.IsPaused("asg-b") {
+		t.Error("expected every ASG to be paused")
+	}
+	if result, err := controller.reconcile(context.Background(), "asg-b"); err != nil || !result.Requeue {
+		t.Errorf("expected paused ASG to be skipped and requeued, got result=%+v and err=%v", result, err)
+	}
+
+	serveAdminAPI(adminAPI.handleResume, http.MethodPost, "/resume", nil)
+	if !controller.IsPaused("asg-a") || controller.IsPaused("asg-b") {
+		t.Error("expected asg-a to remain paused after resuming every ASG, because it was paused individually")
+	}
+	if keys := drainQueue(controller); len(keys) != 1 || keys[0] != "asg-b" {
+		t.Errorf("expected only asg-b to be enqueued, got %v", keys)
+	}
+	serveAdminAPI(adminAPI.handleResume, http.MethodPost, "/resume?asg=asg-a", nil)
+	if controller.IsPaused("asg-a") {
+		t.Error("expected asg-a to be resumed")
+	}
+	if keys := drainQueue(controller); len(keys) != 1 || keys[0] != "asg-a" {
+		t.Errorf("expected asg-a to be enqueued, got %v", keys)
+	}
+}
+
+func TestAdminAPI_pauseAndResume_whenNotLeader(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	config.Get().LeaderElection = true
+	defer config.Set(nil, true, true, false, false)
+	controller := NewController(nil, nil, nil)
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg-a": true}
+	health := NewHealth()
+	leader := "replica-2"
+	health.leader.Store(&leader)
+	adminAPI := NewAdminAPI(controller, health)
+
+	recorder := httptest.NewRecorder()
+	adminAPI.handlePause(recorder, httptest.NewRequest(http.MethodPost, "/pause?asg=asg-a", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), leader) {
+		t.Errorf("expected the response to name the leader, got %q", recorder.Body.String())
+	}
+	if controller.IsPaused("asg-a") {
+		t.Error("a replica that isn't the leader shouldn't have paused asg-a")
+	}
+
+	health.leading.Store(true)
+	if code := serveAdminAPI(adminAPI.handlePause, http.MethodPost, "/pause?asg=asg-a", nil); code != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, code)
+	}
+	health.leading.Store(false)
+	if code := serveAdminAPI(adminAPI.handleResume, http.MethodPost, "/resume?asg=asg-a", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, got %d", http.StatusServiceUnavailable, code)
+	}
+	if !controller.IsPaused("asg-a") {
+		t.Error("a replica that isn't the leader shouldn't have resumed asg-a")
+	}
+}
+
+func TestAdminAPI_status(t *testing.T) {
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	startedInstance := cloudtest.CreateTestAutoScalingInstance("old-2", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, startedInstance, newInstance}, false)
+
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	drainedNode := k8stest.CreateTestNode("old-node-2", aws.StringValue(startedInstance.AvailabilityZone), aws.StringValue(startedInstance.InstanceId), "1000m", "1000Mi")
+	drainedAt := time.Now().Format(time.RFC3339)
+	drainedNode.Annotations[k8s.AnnotationRollingUpdateStartedTimestamp] = drainedAt
+	drainedNode.Annotations[k8s.AnnotationRollingUpdateDrainedTimestamp] = drainedAt
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, drainedNode, newNode}, nil)
+	controller := NewController(mockClient, cloudtest.NewMockEC2Service(nil), cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg}))
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg": true}
+	controller.Pause("asg")
+
+	var status Status
+	if code := serveAdminAPI(NewAdminAPI(controller, NewHealth()).handleStatus, http.MethodGet, "/status", &status); code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, code)
+	}
+	if status.Paused || len(status.AutoScalingGroups) != 1 || !status.AutoScalingGroups[0].Paused {
+		t.Fatalf("expected only asg to be paused, got %+v", status)
+	}
+	asgStatus := status.AutoScalingGroups[0]
+	if len(asgStatus.OutdatedNodes) != 1 || asgStatus.OutdatedNodes[0].NodeName != oldNode.Name {
+		t.Errorf("expected %s to be outdated, got %+v", oldNode.Name, asgStatus.OutdatedNodes)
+	}
+	if len(asgStatus.InProgressNodes) != 1 || asgStatus.InProgressNodes[0].RolloutStep != "drained" || asgStatus.InProgressNodes[0].RolloutStepTimestamp != drainedAt {
+		t.Errorf("expected %s to be in progress and drained, got %+v", drainedNode.Name, asgStatus.InProgressNodes)
+	}
+	if len(asgStatus.UpdatedNodes) != 1 || asgStatus.UpdatedNodes[0].InstanceID != aws.StringValue(newInstance.InstanceId) {
+		t.Errorf("expected %s to be updated, got %+v", newNode.Name, asgStatus.UpdatedNodes)
+	}
+}
+
+func TestAdminAPI_plan(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance}, false)
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode}, nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+	controller := NewController(mockClient, cloudtest.NewMockEC2Service(nil), mockAutoScalingService)
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg": true}
+
+	var response struct {
+		Actions []Action `json:"actions"`
+	}
+	if code := serveAdminAPI(NewAdminAPI(controller, NewHealth()).handlePlan, http.MethodGet, "/plan?asg=asg", &response); code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, code)
+	}
+	if len(response.Actions) != 1 || response.Actions[0].Step != StepStartRollout || response.Actions[0].NodeName != oldNode.Name {
+		t.Errorf("expected the rollout of %s to be planned, got %+v", oldNode.Name, response.Actions)
+	}
+	if mockClient.Counter["PatchNode"] != 0 || mockAutoScalingService.Counter["SetDesiredCapacity"] != 0 {
+		t.Error("no action should've been executed")
+	}
+}
+
+func TestAdminAPI_plan_whenPaused(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance}, false)
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+
+	controller := NewController(k8stest.NewMockClient([]v1.Node{oldNode}, nil), cloudtest.NewMockEC2Service(nil), cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg}))
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg": true}
+	controller.Pause("asg")
+
+	var response struct {
+		Actions []Action `json:"actions"`
+	}
+	if code := serveAdminAPI(NewAdminAPI(controller, NewHealth()).handlePlan, http.MethodGet, "/plan?asg=asg", &response); code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, code)
+	}
+	if len(response.Actions) != 0 {
+		t.Errorf("expected no action to be planned for a paused ASG, got %+v", response.Actions)
+	}
+}
+
+func TestAdminAPI_plan_whenMaxConcurrentDrainsReached(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	config.Get().MaxConcurrentDrains = 1
+	defer config.Set(nil, true, true, false, false)
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, nil)
+	controller := NewController(mockClient, cloudtest.NewMockEC2Service(nil), cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg}))
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg": true}
+
+	// Simulate a node from another ASG currently being drained
+	if !drainingNodes.TryAcquire() {
+		t.Fatal("expected to be able to acquire a drain slot")
+	}
+	defer drainingNodes.Release()
+	var response struct {
+		Actions []Action `json:"actions"`
+	}
+	if code := serveAdminAPI(NewAdminAPI(controller, NewHealth()).handlePlan, http.MethodGet, "/plan?asg=asg", &response); code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, code)
+	}
+	if len(response.Actions) == 0 || response.Actions[0].Step != StepDrain {
+		t.Errorf("expected the drain of %s to be planned, got %+v", oldNode.Name, response.Actions)
+	}
+	if drainingNodes.draining != 1 {
+		t.Error("planning shouldn't have reserved any drain slot, but", drainingNodes.draining, "are in use")
+	}
+	if mockClient.Counter["Drain"] != 0 {
+		t.Error("no node should've been drained")
+	}
+}
+
+// serveAdminAPI sends a request to the given handler, decodes the JSON response into the given value if it isn't nil,
+// and returns the status code of the response
+func serveAdminAPI(handler http.HandlerFunc, method, target string, value interface{}) int {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(method, target, nil))
+	if value != nil && recorder.Code == http.StatusOK {
+		_ = json.NewDecoder(recorder.Body).Decode(value)
+	}
+	return recorder.Code
+}
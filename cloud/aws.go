@@ -1,9 +1,11 @@
 package cloud
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -13,6 +15,11 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 )
 
+const (
+	LifecycleTransitionInstanceTerminating = "autoscaling:EC2_INSTANCE_TERMINATING"
+	LifecycleActionResultContinue          = "CONTINUE"
+)
+
 var (
 	ErrCannotIncreaseDesiredCountAboveMax = errors.New("cannot increase ASG desired size above max ASG size")
 )
@@ -27,12 +34,12 @@ func GetServices(awsRegion string) (ec2iface.EC2API, autoscalingiface.AutoScalin
 	return ec2.New(awsSession), autoscaling.New(awsSession), nil
 }
 
-func DescribeAutoScalingGroupsByNames(svc autoscalingiface.AutoScalingAPI, names []string) ([]*autoscaling.Group, error) {
+func DescribeAutoScalingGroupsByNames(ctx context.Context, svc autoscalingiface.AutoScalingAPI, names []string) ([]*autoscaling.Group, error) {
 	input := &autoscaling.DescribeAutoScalingGroupsInput{
 		AutoScalingGroupNames: aws.StringSlice(names),
 		MaxRecords:            aws.Int64(100),
 	}
-	result, err := svc.DescribeAutoScalingGroups(input)
+	result, err := svc.DescribeAutoScalingGroupsWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -48,11 +55,20 @@ func filterAutoScalingGroupsByTag(autoScalingGroups []*autoscaling.Group, filter
 	return
 }
 
+// GetAutoScalingGroupTags returns the tags of an AutoScalingGroup as a map of keys to values
+func GetAutoScalingGroupTags(autoScalingGroup *autoscaling.Group) map[string]string {
+	tags := make(map[string]string, len(autoScalingGroup.Tags))
+	for _, tag := range autoScalingGroup.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags
+}
+
 // DescribeEnabledAutoScalingGroupsByTags Gets AutoScalingGroups that match the given tags
-func DescribeEnabledAutoScalingGroupsByTags(svc autoscalingiface.AutoScalingAPI, autodiscoveryTags string) ([]*autoscaling.Group, error) {
+func DescribeEnabledAutoScalingGroupsByTags(ctx context.Context, svc autoscalingiface.AutoScalingAPI, autodiscoveryTags string) ([]*autoscaling.Group, error) {
 	input := &autoscaling.DescribeAutoScalingGroupsInput{}
 	var result []*autoscaling.Group
-	err := svc.DescribeAutoScalingGroupsPages(input, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+	err := svc.DescribeAutoScalingGroupsPagesWithContext(ctx, input, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
 		tagFilter := func(tagDescriptions []*autoscaling.TagDescription) bool {
 			var matches []bool
 			for _, tag := range strings.Split(autodiscoveryTags, ",") {
@@ -82,29 +98,29 @@ func DescribeEnabledAutoScalingGroupsByTags(svc autoscalingiface.AutoScalingAPI,
 	return result, nil
 }
 
-func DescribeLaunchTemplateByID(svc ec2iface.EC2API, id string) (*ec2.LaunchTemplate, error) {
+func DescribeLaunchTemplateByID(ctx context.Context, svc ec2iface.EC2API, id string) (*ec2.LaunchTemplate, error) {
 	input := &ec2.DescribeLaunchTemplatesInput{
 		LaunchTemplateIds: []*string{
 			aws.String(id),
 		},
 	}
-	return DescribeLaunchTemplate(svc, input)
+	return DescribeLaunchTemplate(ctx, svc, input)
 }
 
-func DescribeLaunchTemplateByName(svc ec2iface.EC2API, name string) (*ec2.LaunchTemplate, error) {
+func DescribeLaunchTemplateByName(ctx context.Context, svc ec2iface.EC2API, name string) (*ec2.LaunchTemplate, error) {
 	input := &ec2.DescribeLaunchTemplatesInput{
 		LaunchTemplateNames: []*string{
 			aws.String(name),
 		},
 	}
-	return DescribeLaunchTemplate(svc, input)
+	return DescribeLaunchTemplate(ctx, svc, input)
 }
 
-func DescribeLaunchTemplate(svc ec2iface.EC2API, input *ec2.DescribeLaunchTemplatesInput) (*ec2.LaunchTemplate, error) {
-	templatesOutput, err := svc.DescribeLaunchTemplates(input)
+func DescribeLaunchTemplate(ctx context.Context, svc ec2iface.EC2API, input *ec2.DescribeLaunchTemplatesInput) (*ec2.LaunchTemplate, error) {
+	templatesOutput, err := svc.DescribeLaunchTemplatesWithContext(ctx, input)
 	descriptiveMsg := fmt.Sprintf("%v / %v", aws.StringValueSlice(input.LaunchTemplateIds), aws.StringValueSlice(input.LaunchTemplateNames))
 	if err != nil {
-		return nil, fmt.Errorf("unable to get description for Launch Templates %s: %v", descriptiveMsg, err)
+		return nil, fmt.Errorf("unable to get description for Launch Templates %s: %w", descriptiveMsg, err)
 	}
 	if len(templatesOutput.LaunchTemplates) < 1 {
 		return nil, nil
@@ -113,39 +129,102 @@ func DescribeLaunchTemplate(svc ec2iface.EC2API, input *ec2.DescribeLaunchTempla
 }
 
 // IncrementAutoScalingGroupDesiredCount retrieves the latest definition of the ASG and increments its current
-// desired capacity by 1. The reason why we retrieve the ASG again even though we already have it is to avoid a
-// scenario in which the ASG had already been scaled up or down since the last time it was retrieved.
+// desired capacity by the given increment, without going above the ASG's max size. The reason why we retrieve the ASG
+// again even though we already have it is to avoid a scenario in which the ASG had already been scaled up or down
+// since the last time it was retrieved.
 // See https://github.com/TwiN/aws-eks-asg-rolling-update-handler/issues/129 for more information.
-func IncrementAutoScalingGroupDesiredCount(svc autoscalingiface.AutoScalingAPI, autoScalingGroupName string) error {
-	latestASGs, err := DescribeAutoScalingGroupsByNames(svc, []string{autoScalingGroupName})
+//
+// Returns the number by which the desired capacity was actually incremented
+func IncrementAutoScalingGroupDesiredCount(ctx context.Context, svc autoscalingiface.AutoScalingAPI, autoScalingGroupName string, increment int64) (int64, error) {
+	latestASGs, err := DescribeAutoScalingGroupsByNames(ctx, svc, []string{autoScalingGroupName})
 	if err != nil {
-		return fmt.Errorf("failed to retrieve latest asg with name '%s': %w", autoScalingGroupName, err)
+		return 0, fmt.Errorf("failed to retrieve latest asg with name '%s': %w", autoScalingGroupName, err)
 	}
 	if len(latestASGs) != 1 {
 		// ASG names are unique per region and account, so if there isn't exactly one ASG, there's a problem.
-		return errors.New("failed to retrieve latest asg with name: " + autoScalingGroupName)
+		return 0, errors.New("failed to retrieve latest asg with name: " + autoScalingGroupName)
 	}
 	asg := latestASGs[0]
-	newDesiredCapacity := aws.Int64Value(asg.DesiredCapacity) + 1
+	currentDesiredCapacity := aws.Int64Value(asg.DesiredCapacity)
+	newDesiredCapacity := currentDesiredCapacity + increment
 	if newDesiredCapacity > aws.Int64Value(asg.MaxSize) {
-		return ErrCannotIncreaseDesiredCountAboveMax
+		newDesiredCapacity = aws.Int64Value(asg.MaxSize)
+	}
+	if newDesiredCapacity <= currentDesiredCapacity {
+		return 0, ErrCannotIncreaseDesiredCountAboveMax
 	}
 	desiredInput := &autoscaling.SetDesiredCapacityInput{
 		AutoScalingGroupName: asg.AutoScalingGroupName,
 		DesiredCapacity:      aws.Int64(newDesiredCapacity),
 		HonorCooldown:        aws.Bool(true),
 	}
-	_, err = svc.SetDesiredCapacity(desiredInput)
+	_, err = svc.SetDesiredCapacityWithContext(ctx, desiredInput)
 	if err != nil {
-		return fmt.Errorf("unable to increase ASG %s desired count to %d: %w", autoScalingGroupName, newDesiredCapacity, err)
+		return 0, fmt.Errorf("unable to increase ASG %s desired count to %d: %w", autoScalingGroupName, newDesiredCapacity, err)
 	}
-	return nil
+	return newDesiredCapacity - currentDesiredCapacity, nil
 }
 
-func TerminateEc2Instance(svc autoscalingiface.AutoScalingAPI, instance *autoscaling.Instance, shouldDecrementDesiredCapacity bool) error {
-	_, err := svc.TerminateInstanceInAutoScalingGroup(&autoscaling.TerminateInstanceInAutoScalingGroupInput{
+func TerminateEc2Instance(ctx context.Context, svc autoscalingiface.AutoScalingAPI, instance *autoscaling.Instance, shouldDecrementDesiredCapacity bool) error {
+	_, err := svc.TerminateInstanceInAutoScalingGroupWithContext(ctx, &autoscaling.TerminateInstanceInAutoScalingGroupInput{
 		InstanceId:                     instance.InstanceId,
 		ShouldDecrementDesiredCapacity: aws.Bool(shouldDecrementDesiredCapacity),
 	})
 	return err
 }
+
+// CompleteTerminationLifecycleActions completes the pending lifecycle actions of every termination lifecycle hook of
+// the given ASG for the given instance, which allows the termination of an instance stuck in the
+// 'Terminating:Wait' lifecycle state to proceed.
+//
+// Returns the number of lifecycle actions completed. Lifecycle hooks without a pending lifecycle action for the
+// instance are ignored, and an error is only returned if no lifecycle action could be completed.
+func CompleteTerminationLifecycleActions(ctx context.Context, svc autoscalingiface.AutoScalingAPI, autoScalingGroupName, instanceID string) (int, error) {
+	output, err := svc.DescribeLifecycleHooksWithContext(ctx, &autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: aws.String(autoScalingGroupName),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to describe lifecycle hooks of asg with name '%s': %w", autoScalingGroupName, err)
+	}
+	var (
+		completed int
+		lastErr   error
+	)
+	for _, lifecycleHook := range output.LifecycleHooks {
+		if aws.StringValue(lifecycleHook.LifecycleTransition) != LifecycleTransitionInstanceTerminating {
+			continue
+		}
+		_, err := svc.CompleteLifecycleActionWithContext(ctx, &autoscaling.CompleteLifecycleActionInput{
+			AutoScalingGroupName:  aws.String(autoScalingGroupName),
+			LifecycleHookName:     lifecycleHook.LifecycleHookName,
+			InstanceId:            aws.String(instanceID),
+			LifecycleActionResult: aws.String(LifecycleActionResultContinue),
+		})
+		if err != nil {
+			lastErr = fmt.Errorf("unable to complete lifecycle action of lifecycle hook '%s': %w", aws.StringValue(lifecycleHook.LifecycleHookName), err)
+			continue
+		}
+		completed++
+	}
+	if completed == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+	return completed, nil
+}
+
+// DescribeInstanceLaunchTimes retrieves the launch time of each of the given EC2 instances, indexed by instance ID
+func DescribeInstanceLaunchTimes(ctx context.Context, svc ec2iface.EC2API, instanceIDs []string) (map[string]time.Time, error) {
+	launchTimes := make(map[string]time.Time, len(instanceIDs))
+	err := svc.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice(instanceIDs)}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				launchTimes[aws.StringValue(instance.InstanceId)] = aws.TimeValue(instance.LaunchTime)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return launchTimes, nil
+}
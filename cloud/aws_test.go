@@ -1,10 +1,13 @@
 package cloud_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloud"
 	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloudtest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 )
 
@@ -128,7 +131,7 @@ func TestDescribeEnabledAutoScalingGroupsByTags(t *testing.T) {
 			autoScalingGroups = append(autoScalingGroups, &autoScalingGroup)
 		}
 		svc := cloudtest.NewMockAutoScalingService(autoScalingGroups)
-		output, err := cloud.DescribeEnabledAutoScalingGroupsByTags(svc, test.inputTags)
+		output, err := cloud.DescribeEnabledAutoScalingGroupsByTags(context.Background(), svc, test.inputTags)
 		if err != nil {
 			t.Error(err)
 		}
@@ -151,3 +154,40 @@ func TestDescribeEnabledAutoScalingGroupsByTags(t *testing.T) {
 		}
 	}
 }
+
+func TestIncrementAutoScalingGroupDesiredCount(t *testing.T) {
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v1", nil, nil, false)
+	asg.SetDesiredCapacity(2)
+	asg.SetMaxSize(5)
+	svc := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+	scaledUpBy, err := cloud.IncrementAutoScalingGroupDesiredCount(context.Background(), svc, "asg", 2)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if scaledUpBy != 2 || aws.Int64Value(asg.DesiredCapacity) != 4 {
+		t.Errorf("desired capacity should've been increased from 2 to 4, got %d", aws.Int64Value(asg.DesiredCapacity))
+	}
+	// Only one more instance can be added before reaching the max size
+	scaledUpBy, err = cloud.IncrementAutoScalingGroupDesiredCount(context.Background(), svc, "asg", 2)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if scaledUpBy != 1 || aws.Int64Value(asg.DesiredCapacity) != 5 {
+		t.Errorf("desired capacity should've been capped to 5, got %d", aws.Int64Value(asg.DesiredCapacity))
+	}
+	if _, err = cloud.IncrementAutoScalingGroupDesiredCount(context.Background(), svc, "asg", 1); err != cloud.ErrCannotIncreaseDesiredCountAboveMax {
+		t.Error("expected ErrCannotIncreaseDesiredCountAboveMax, got", err)
+	}
+}
+
+func TestDescribeLaunchTemplateByName_whenThrottled(t *testing.T) {
+	svc := cloudtest.NewMockEC2Service(nil)
+	svc.DescribeLaunchTemplatesError = awserr.New("RequestLimitExceeded", "Request limit exceeded", nil)
+	_, err := cloud.DescribeLaunchTemplateByName(context.Background(), svc, "lt")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if class := cloud.ClassifyError(err); class != cloud.ErrorClassThrottling {
+		t.Errorf("expected %s, got %s", cloud.ErrorClassThrottling, class)
+	}
+}
@@ -0,0 +1,61 @@
+package cloud
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// ErrorClass is the class of an error returned by AWS, which determines whether retrying can resolve it
+type ErrorClass string
+
+const (
+	// ErrorClassThrottling is the class of errors caused by AWS throttling requests, which are resolved by backing off
+	ErrorClassThrottling ErrorClass = "throttling"
+	// ErrorClassAuth is the class of errors caused by missing or invalid credentials, or by missing permissions, which
+	// retrying cannot resolve
+	ErrorClassAuth ErrorClass = "auth"
+	// ErrorClassNotFound is the class of errors caused by a resource that no longer exists, which are resolved once
+	// the resources to manage are rediscovered
+	ErrorClassNotFound ErrorClass = "not-found"
+	// ErrorClassUnknown is the class of every other error, which are assumed to be transient (e.g. network errors)
+	ErrorClassUnknown ErrorClass = "unknown"
+)
+
+var authErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"AuthFailure":                 true,
+	"InvalidClientTokenId":        true,
+	"MissingAuthenticationToken":  true,
+	"NoCredentialProviders":       true,
+	"SignatureDoesNotMatch":       true,
+	"UnauthorizedOperation":       true,
+	"UnrecognizedClientException": true,
+}
+
+// ClassifyError returns the class of the given error, which may wrap an error returned by AWS
+//
+// Note that expired credentials aren't considered as auth errors, because the AWS SDK refreshes them.
+func ClassifyError(err error) ErrorClass {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return ErrorClassUnknown
+	}
+	switch {
+	case request.IsErrorThrottle(awsErr):
+		return ErrorClassThrottling
+	case authErrorCodes[awsErr.Code()]:
+		return ErrorClassAuth
+	case strings.Contains(awsErr.Code(), "NotFound"):
+		return ErrorClassNotFound
+	}
+	return ErrorClassUnknown
+}
+
+// IsUnrecoverableError checks whether the given error cannot be resolved by retrying
+func IsUnrecoverableError(err error) bool {
+	return ClassifyError(err) == ErrorClassAuth
+}
@@ -0,0 +1,37 @@
+package cloud_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloud"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestClassifyError(t *testing.T) {
+	scenarios := []struct {
+		name                  string
+		err                   error
+		expectedClass         cloud.ErrorClass
+		expectedUnrecoverable bool
+	}{
+		{name: "throttling", err: awserr.New("Throttling", "Rate exceeded", nil), expectedClass: cloud.ErrorClassThrottling},
+		{name: "wrapped-throttling", err: fmt.Errorf("unable to describe AutoScalingGroups: %w", awserr.New("RequestLimitExceeded", "Request limit exceeded", nil)), expectedClass: cloud.ErrorClassThrottling},
+		{name: "access-denied", err: awserr.New("AccessDenied", "User is not authorized", nil), expectedClass: cloud.ErrorClassAuth, expectedUnrecoverable: true},
+		{name: "no-credentials", err: awserr.New("NoCredentialProviders", "no valid providers in chain", nil), expectedClass: cloud.ErrorClassAuth, expectedUnrecoverable: true},
+		{name: "expired-credentials", err: awserr.New("ExpiredToken", "The security token included in the request is expired", nil), expectedClass: cloud.ErrorClassUnknown},
+		{name: "not-found", err: awserr.New("InvalidLaunchTemplateId.NotFound", "The specified launch template does not exist", nil), expectedClass: cloud.ErrorClassNotFound},
+		{name: "not-aws", err: errors.New("connection reset by peer"), expectedClass: cloud.ErrorClassUnknown},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if class := cloud.ClassifyError(scenario.err); class != scenario.expectedClass {
+				t.Errorf("expected %s, got %s", scenario.expectedClass, class)
+			}
+			if unrecoverable := cloud.IsUnrecoverableError(scenario.err); unrecoverable != scenario.expectedUnrecoverable {
+				t.Errorf("expected unrecoverable to be %v, got %v", scenario.expectedUnrecoverable, unrecoverable)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package cloudtest
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -15,6 +17,12 @@ type MockEC2Service struct {
 
 	Counter   map[string]int64
 	Templates []*ec2.LaunchTemplate
+	Instances []*ec2.Instance
+
+	// DescribeLaunchTemplatesError is the error returned by DescribeLaunchTemplatesWithContext
+	DescribeLaunchTemplatesError error
+
+	mutex sync.Mutex
 }
 
 func NewMockEC2Service(templates []*ec2.LaunchTemplate) *MockEC2Service {
@@ -24,15 +32,38 @@ func NewMockEC2Service(templates []*ec2.LaunchTemplate) *MockEC2Service {
 	}
 }
 
-func (m *MockEC2Service) DescribeLaunchTemplates(_ *ec2.DescribeLaunchTemplatesInput) (*ec2.DescribeLaunchTemplatesOutput, error) {
+func (m *MockEC2Service) DescribeLaunchTemplatesWithContext(_ aws.Context, _ *ec2.DescribeLaunchTemplatesInput, _ ...request.Option) (*ec2.DescribeLaunchTemplatesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.Counter["DescribeLaunchTemplates"]++
+	if m.DescribeLaunchTemplatesError != nil {
+		return nil, m.DescribeLaunchTemplatesError
+	}
 	output := &ec2.DescribeLaunchTemplatesOutput{
 		LaunchTemplates: m.Templates,
 	}
 	return output, nil
 }
 
+func (m *MockEC2Service) DescribeInstancesPagesWithContext(_ aws.Context, input *ec2.DescribeInstancesInput, f func(*ec2.DescribeInstancesOutput, bool) bool, _ ...request.Option) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Counter["DescribeInstances"]++
+	var instances []*ec2.Instance
+	for _, instanceID := range input.InstanceIds {
+		for _, instance := range m.Instances {
+			if aws.StringValue(instanceID) == aws.StringValue(instance.InstanceId) {
+				instances = append(instances, instance)
+			}
+		}
+	}
+	f(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: instances}}}, true)
+	return nil
+}
+
 func (m *MockEC2Service) DescribeLaunchTemplateByID(input *ec2.DescribeLaunchTemplatesInput) (*ec2.LaunchTemplate, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.Counter["DescribeLaunchTemplateByID"]++
 	for _, template := range m.Templates {
 		if template.LaunchTemplateId == input.LaunchTemplateIds[0] {
@@ -57,12 +88,19 @@ type MockAutoScalingService struct {
 
 	Counter           map[string]int64
 	AutoScalingGroups map[string]*autoscaling.Group
+	LifecycleHooks    map[string][]*autoscaling.LifecycleHook // Indexed by ASG name
+
+	// TerminateInstanceInAutoScalingGroupError is the error returned by TerminateInstanceInAutoScalingGroupWithContext
+	TerminateInstanceInAutoScalingGroupError error
+
+	mutex sync.Mutex
 }
 
 func NewMockAutoScalingService(autoScalingGroups []*autoscaling.Group) *MockAutoScalingService {
 	service := &MockAutoScalingService{
 		Counter:           make(map[string]int64),
 		AutoScalingGroups: make(map[string]*autoscaling.Group),
+		LifecycleHooks:    make(map[string][]*autoscaling.LifecycleHook),
 	}
 	for _, autoScalingGroup := range autoScalingGroups {
 		service.AutoScalingGroups[aws.StringValue(autoScalingGroup.AutoScalingGroupName)] = autoScalingGroup
@@ -70,12 +108,19 @@ func NewMockAutoScalingService(autoScalingGroups []*autoscaling.Group) *MockAuto
 	return service
 }
 
-func (m *MockAutoScalingService) TerminateInstanceInAutoScalingGroup(_ *autoscaling.TerminateInstanceInAutoScalingGroupInput) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+func (m *MockAutoScalingService) TerminateInstanceInAutoScalingGroupWithContext(_ aws.Context, _ *autoscaling.TerminateInstanceInAutoScalingGroupInput, _ ...request.Option) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.Counter["TerminateInstanceInAutoScalingGroup"]++
+	if m.TerminateInstanceInAutoScalingGroupError != nil {
+		return nil, m.TerminateInstanceInAutoScalingGroupError
+	}
 	return &autoscaling.TerminateInstanceInAutoScalingGroupOutput{}, nil
 }
 
-func (m *MockAutoScalingService) DescribeAutoScalingGroups(input *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+func (m *MockAutoScalingService) DescribeAutoScalingGroupsWithContext(_ aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, _ ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.Counter["DescribeAutoScalingGroups"]++
 	var autoScalingGroups []*autoscaling.Group
 	for _, autoScalingGroupName := range input.AutoScalingGroupNames {
@@ -90,7 +135,7 @@ func (m *MockAutoScalingService) DescribeAutoScalingGroups(input *autoscaling.De
 	}, nil
 }
 
-func (m *MockAutoScalingService) DescribeAutoScalingGroupsPages(input *autoscaling.DescribeAutoScalingGroupsInput, f func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+func (m *MockAutoScalingService) DescribeAutoScalingGroupsPagesWithContext(_ aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, f func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool, _ ...request.Option) error {
 	idx := 0
 	for _, asg := range m.AutoScalingGroups {
 		x := &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{asg}}
@@ -100,13 +145,33 @@ func (m *MockAutoScalingService) DescribeAutoScalingGroupsPages(input *autoscali
 	return nil
 }
 
-func (m *MockAutoScalingService) SetDesiredCapacity(input *autoscaling.SetDesiredCapacityInput) (*autoscaling.SetDesiredCapacityOutput, error) {
+func (m *MockAutoScalingService) SetDesiredCapacityWithContext(_ aws.Context, input *autoscaling.SetDesiredCapacityInput, _ ...request.Option) (*autoscaling.SetDesiredCapacityOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.Counter["SetDesiredCapacity"]++
 	m.AutoScalingGroups[aws.StringValue(input.AutoScalingGroupName)].SetDesiredCapacity(aws.Int64Value(input.DesiredCapacity))
 	return &autoscaling.SetDesiredCapacityOutput{}, nil
 }
 
+func (m *MockAutoScalingService) DescribeLifecycleHooksWithContext(_ aws.Context, input *autoscaling.DescribeLifecycleHooksInput, _ ...request.Option) (*autoscaling.DescribeLifecycleHooksOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Counter["DescribeLifecycleHooks"]++
+	return &autoscaling.DescribeLifecycleHooksOutput{
+		LifecycleHooks: m.LifecycleHooks[aws.StringValue(input.AutoScalingGroupName)],
+	}, nil
+}
+
+func (m *MockAutoScalingService) CompleteLifecycleActionWithContext(_ aws.Context, _ *autoscaling.CompleteLifecycleActionInput, _ ...request.Option) (*autoscaling.CompleteLifecycleActionOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Counter["CompleteLifecycleAction"]++
+	return &autoscaling.CompleteLifecycleActionOutput{}, nil
+}
+
 func (m *MockAutoScalingService) UpdateAutoScalingGroup(_ *autoscaling.UpdateAutoScalingGroupInput) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.Counter["UpdateAutoScalingGroup"]++
 	return &autoscaling.UpdateAutoScalingGroupOutput{}, nil
 }
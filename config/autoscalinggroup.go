@@ -0,0 +1,319 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// AutoScalingGroupTagPrefix is the prefix of every tag that can be used to override the configuration of a single ASG
+const AutoScalingGroupTagPrefix = "aws-eks-asg-rolling-update-handler.twin.sh/"
+
+const (
+	TagSlowMode                         = AutoScalingGroupTagPrefix + "slow-mode"
+	TagEagerCordoning                   = AutoScalingGroupTagPrefix + "eager-cordoning"
+	TagExcludeFromExternalLoadBalancers = AutoScalingGroupTagPrefix + "exclude-from-external-load-balancers"
+	TagPodTerminationGracePeriod        = AutoScalingGroupTagPrefix + "pod-termination-grace-period"
+	TagIgnoreDaemonSets                 = AutoScalingGroupTagPrefix + "ignore-daemon-sets"
+	TagDeleteEmptyDirData               = AutoScalingGroupTagPrefix + "delete-empty-dir-data"
+	TagMaxUnavailable                   = AutoScalingGroupTagPrefix + "max-unavailable"
+	TagMaxSurge                         = AutoScalingGroupTagPrefix + "max-surge"
+	TagDrainEscalationPolicy            = AutoScalingGroupTagPrefix + "drain-escalation-policy"
+	TagMaxUpdatedNonReadyNodes          = AutoScalingGroupTagPrefix + "max-updated-non-ready-nodes"
+	TagMaxUpdatedNonReadyNodesRatio     = AutoScalingGroupTagPrefix + "max-updated-non-ready-nodes-ratio"
+	TagUpdatedNodeMinReadyDuration      = AutoScalingGroupTagPrefix + "updated-node-min-ready-duration"
+	TagPaused                           = AutoScalingGroupTagPrefix + "paused"
+	TagMaintenanceWindow                = AutoScalingGroupTagPrefix + "maintenance-window"
+	TagBlackoutPeriod                   = AutoScalingGroupTagPrefix + "blackout-period"
+)
+
+// AutoScalingGroupConfig is the effective configuration of a single ASG, which is the global configuration with the
+// ASG's overrides applied on top of it
+type AutoScalingGroupConfig struct {
+	SlowMode                         bool
+	EagerCordoning                   bool
+	ExcludeFromExternalLoadBalancers bool
+	PodTerminationGracePeriod        int
+	IgnoreDaemonSets                 bool
+	DeleteEmptyDirData               bool
+	MaxUnavailable                   intstr.IntOrString
+	MaxSurge                         intstr.IntOrString
+	DrainEscalationPolicy            string
+	MaxUpdatedNonReadyNodes          int
+	MaxUpdatedNonReadyNodesRatio     float64
+	UpdatedNodeMinReadyDuration      time.Duration
+	HealthGates                      HealthGates
+	Paused                           bool
+	RolloutSchedule                  RolloutSchedule
+}
+
+// AutoScalingGroupOverrides are the overrides of a single ASG from the configuration file.
+// Fields that are nil are inherited from the global configuration.
+type AutoScalingGroupOverrides struct {
+	SlowMode                         *bool               `json:"slowMode,omitempty"`
+	EagerCordoning                   *bool               `json:"eagerCordoning,omitempty"`
+	ExcludeFromExternalLoadBalancers *bool               `json:"excludeFromExternalLoadBalancers,omitempty"`
+	PodTerminationGracePeriod        *int                `json:"podTerminationGracePeriod,omitempty"`
+	IgnoreDaemonSets                 *bool               `json:"ignoreDaemonSets,omitempty"`
+	DeleteEmptyDirData               *bool               `json:"deleteEmptyDirData,omitempty"`
+	MaxUnavailable                   *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	MaxSurge                         *intstr.IntOrString `json:"maxSurge,omitempty"`
+	DrainEscalationPolicy            *string             `json:"drainEscalationPolicy,omitempty"`
+	MaxUpdatedNonReadyNodes          *int                `json:"maxUpdatedNonReadyNodes,omitempty"`
+	MaxUpdatedNonReadyNodesRatio     *float64            `json:"maxUpdatedNonReadyNodesRatio,omitempty"`
+	UpdatedNodeMinReadyDuration      *int                `json:"updatedNodeMinReadyDuration,omitempty"` // In seconds
+	HealthGateNodeConditions         []string            `json:"healthGateNodeConditions,omitempty"`
+	HealthGateNodeLabels             []string            `json:"healthGateNodeLabels,omitempty"`
+	HealthGateStartupTaints          []string            `json:"healthGateStartupTaints,omitempty"`
+	HealthGateDaemonSets             []string            `json:"healthGateDaemonSets,omitempty"`
+	Paused                           *bool               `json:"paused,omitempty"`
+	MaintenanceWindows               []string            `json:"maintenanceWindows,omitempty"`
+	BlackoutPeriods                  []string            `json:"blackoutPeriods,omitempty"`
+}
+
+func (o *AutoScalingGroupOverrides) validate() error {
+	if o.MaxUnavailable != nil {
+		maxUnavailable, err := ParseIntOrPercentage(o.MaxUnavailable.String())
+		if err != nil {
+			return fmt.Errorf("maxUnavailable: %w", err)
+		}
+		o.MaxUnavailable = &maxUnavailable
+	}
+	if o.MaxSurge != nil {
+		maxSurge, err := ParseIntOrPercentage(o.MaxSurge.String())
+		if err != nil {
+			return fmt.Errorf("maxSurge: %w", err)
+		}
+		o.MaxSurge = &maxSurge
+	}
+	if o.DrainEscalationPolicy != nil {
+		drainEscalationPolicy := strings.ToLower(strings.TrimSpace(*o.DrainEscalationPolicy))
+		if !IsValidDrainEscalationPolicy(drainEscalationPolicy) {
+			return fmt.Errorf("drainEscalationPolicy: must be one of %s", strings.Join(DrainEscalationPolicies, ", "))
+		}
+		o.DrainEscalationPolicy = &drainEscalationPolicy
+	}
+	if o.MaxUpdatedNonReadyNodes != nil && *o.MaxUpdatedNonReadyNodes < 0 {
+		return fmt.Errorf("maxUpdatedNonReadyNodes: must be a non-negative integer")
+	}
+	if o.MaxUpdatedNonReadyNodesRatio != nil && *o.MaxUpdatedNonReadyNodesRatio < 0 {
+		return fmt.Errorf("maxUpdatedNonReadyNodesRatio: must be a non-negative number")
+	}
+	if o.UpdatedNodeMinReadyDuration != nil && *o.UpdatedNodeMinReadyDuration < 0 {
+		return fmt.Errorf("updatedNodeMinReadyDuration: must be a non-negative integer")
+	}
+	if _, err := parseNodeConditions(o.HealthGateNodeConditions); err != nil {
+		return fmt.Errorf("healthGateNodeConditions: %w", err)
+	}
+	if _, err := parseNodeLabels(o.HealthGateNodeLabels); err != nil {
+		return fmt.Errorf("healthGateNodeLabels: %w", err)
+	}
+	if _, err := parseDaemonSets(o.HealthGateDaemonSets); err != nil {
+		return fmt.Errorf("healthGateDaemonSets: %w", err)
+	}
+	if _, err := parseMaintenanceWindows(o.MaintenanceWindows); err != nil {
+		return fmt.Errorf("maintenanceWindows: %w", err)
+	}
+	if _, err := parseBlackoutPeriods(o.BlackoutPeriods); err != nil {
+		return fmt.Errorf("blackoutPeriods: %w", err)
+	}
+	return nil
+}
+
+// apply applies the overrides on top of the given ASG configuration
+func (o *AutoScalingGroupOverrides) apply(asgConfig *AutoScalingGroupConfig) {
+	if o.SlowMode != nil {
+		asgConfig.SlowMode = *o.SlowMode
+	}
+	if o.EagerCordoning != nil {
+		asgConfig.EagerCordoning = *o.EagerCordoning
+	}
+	if o.ExcludeFromExternalLoadBalancers != nil {
+		asgConfig.ExcludeFromExternalLoadBalancers = *o.ExcludeFromExternalLoadBalancers
+	}
+	if o.PodTerminationGracePeriod != nil {
+		asgConfig.PodTerminationGracePeriod = *o.PodTerminationGracePeriod
+	}
+	if o.IgnoreDaemonSets != nil {
+		asgConfig.IgnoreDaemonSets = *o.IgnoreDaemonSets
+	}
+	if o.DeleteEmptyDirData != nil {
+		asgConfig.DeleteEmptyDirData = *o.DeleteEmptyDirData
+	}
+	if o.MaxUnavailable != nil {
+		asgConfig.MaxUnavailable = *o.MaxUnavailable
+	}
+	if o.MaxSurge != nil {
+		asgConfig.MaxSurge = *o.MaxSurge
+	}
+	if o.DrainEscalationPolicy != nil {
+		asgConfig.DrainEscalationPolicy = *o.DrainEscalationPolicy
+	}
+	if o.MaxUpdatedNonReadyNodes != nil {
+		asgConfig.MaxUpdatedNonReadyNodes = *o.MaxUpdatedNonReadyNodes
+	}
+	if o.MaxUpdatedNonReadyNodesRatio != nil {
+		asgConfig.MaxUpdatedNonReadyNodesRatio = *o.MaxUpdatedNonReadyNodesRatio
+	}
+	if o.UpdatedNodeMinReadyDuration != nil {
+		asgConfig.UpdatedNodeMinReadyDuration = time.Second * time.Duration(*o.UpdatedNodeMinReadyDuration)
+	}
+	// Health gates replace the global ones rather than being merged with them. Errors are ignored, because the
+	// overrides have already been validated.
+	if o.HealthGateNodeConditions != nil {
+		asgConfig.HealthGates.NodeConditions, _ = parseNodeConditions(o.HealthGateNodeConditions)
+	}
+	if o.HealthGateNodeLabels != nil {
+		asgConfig.HealthGates.NodeLabels, _ = parseNodeLabels(o.HealthGateNodeLabels)
+	}
+	if o.HealthGateStartupTaints != nil {
+		asgConfig.HealthGates.StartupTaints = splitList(o.HealthGateStartupTaints)
+	}
+	if o.HealthGateDaemonSets != nil {
+		asgConfig.HealthGates.DaemonSets, _ = parseDaemonSets(o.HealthGateDaemonSets)
+	}
+	if o.Paused != nil {
+		asgConfig.Paused = *o.Paused
+	}
+	// Like health gates, maintenance windows and blackout periods replace the global ones
+	if o.MaintenanceWindows != nil {
+		asgConfig.RolloutSchedule.MaintenanceWindows, _ = parseMaintenanceWindows(o.MaintenanceWindows)
+	}
+	if o.BlackoutPeriods != nil {
+		asgConfig.RolloutSchedule.BlackoutPeriods, _ = parseBlackoutPeriods(o.BlackoutPeriods)
+	}
+}
+
+// ForAutoScalingGroup resolves the effective configuration of an ASG by applying, on top of the global
+// configuration, the ASG's overrides from the configuration file followed by the overrides from the ASG's tags.
+//
+// Tags with an invalid value are ignored, in which case the global configuration is used instead.
+func (c *config) ForAutoScalingGroup(autoScalingGroupName string, tags map[string]string) *AutoScalingGroupConfig {
+	asgConfig := &AutoScalingGroupConfig{
+		SlowMode:                         c.SlowMode,
+		EagerCordoning:                   c.EagerCordoning,
+		ExcludeFromExternalLoadBalancers: c.ExcludeFromExternalLoadBalancers,
+		PodTerminationGracePeriod:        c.PodTerminationGracePeriod,
+		IgnoreDaemonSets:                 c.IgnoreDaemonSets,
+		DeleteEmptyDirData:               c.DeleteEmptyDirData,
+		MaxUnavailable:                   c.MaxUnavailable,
+		MaxSurge:                         c.MaxSurge,
+		DrainEscalationPolicy:            c.DrainEscalationPolicy,
+		MaxUpdatedNonReadyNodes:          c.MaxUpdatedNonReadyNodes,
+		MaxUpdatedNonReadyNodesRatio:     c.MaxUpdatedNonReadyNodesRatio,
+		UpdatedNodeMinReadyDuration:      c.UpdatedNodeMinReadyDuration,
+		HealthGates:                      c.HealthGates,
+		Paused:                           c.Paused,
+		RolloutSchedule:                  c.RolloutSchedule,
+	}
+	if overrides, ok := c.AutoScalingGroups[autoScalingGroupName]; ok {
+		overrides.apply(asgConfig)
+	}
+	overrideBool(autoScalingGroupName, tags, TagSlowMode, &asgConfig.SlowMode)
+	overrideBool(autoScalingGroupName, tags, TagEagerCordoning, &asgConfig.EagerCordoning)
+	overrideBool(autoScalingGroupName, tags, TagExcludeFromExternalLoadBalancers, &asgConfig.ExcludeFromExternalLoadBalancers)
+	overrideBool(autoScalingGroupName, tags, TagIgnoreDaemonSets, &asgConfig.IgnoreDaemonSets)
+	overrideBool(autoScalingGroupName, tags, TagDeleteEmptyDirData, &asgConfig.DeleteEmptyDirData)
+	overrideBool(autoScalingGroupName, tags, TagPaused, &asgConfig.Paused)
+	if value, ok := tags[TagPodTerminationGracePeriod]; ok {
+		if gracePeriod, err := strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			log.Printf("[%s] Ignoring tag '%s' because its value '%s' is not an integer", autoScalingGroupName, TagPodTerminationGracePeriod, value)
+		} else {
+			asgConfig.PodTerminationGracePeriod = gracePeriod
+		}
+	}
+	overrideIntOrPercentage(autoScalingGroupName, tags, TagMaxUnavailable, &asgConfig.MaxUnavailable)
+	overrideIntOrPercentage(autoScalingGroupName, tags, TagMaxSurge, &asgConfig.MaxSurge)
+	if value, ok := tags[TagDrainEscalationPolicy]; ok {
+		if drainEscalationPolicy := strings.ToLower(strings.TrimSpace(value)); !IsValidDrainEscalationPolicy(drainEscalationPolicy) {
+			log.Printf("[%s] Ignoring tag '%s' because its value '%s' is not one of %s", autoScalingGroupName, TagDrainEscalationPolicy, value, strings.Join(DrainEscalationPolicies, ", "))
+		} else {
+			asgConfig.DrainEscalationPolicy = drainEscalationPolicy
+		}
+	}
+	if value, ok := tags[TagMaxUpdatedNonReadyNodes]; ok {
+		if maximum, err := strconv.Atoi(strings.TrimSpace(value)); err != nil || maximum < 0 {
+			log.Printf("[%s] Ignoring tag '%s' because its value '%s' is not a non-negative integer", autoScalingGroupName, TagMaxUpdatedNonReadyNodes, value)
+		} else {
+			asgConfig.MaxUpdatedNonReadyNodes = maximum
+		}
+	}
+	if value, ok := tags[TagMaxUpdatedNonReadyNodesRatio]; ok {
+		if ratio, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil || ratio < 0 {
+			log.Printf("[%s] Ignoring tag '%s' because its value '%s' is not a non-negative number", autoScalingGroupName, TagMaxUpdatedNonReadyNodesRatio, value)
+		} else {
+			asgConfig.MaxUpdatedNonReadyNodesRatio = ratio
+		}
+	}
+	if value, ok := tags[TagUpdatedNodeMinReadyDuration]; ok {
+		if duration, err := strconv.Atoi(strings.TrimSpace(value)); err != nil || duration < 0 {
+			log.Printf("[%s] Ignoring tag '%s' because its value '%s' is not a non-negative integer", autoScalingGroupName, TagUpdatedNodeMinReadyDuration, value)
+		} else {
+			asgConfig.UpdatedNodeMinReadyDuration = time.Second * time.Duration(duration)
+		}
+	}
+	// Tag values cannot contain commas, so these tags only support a single maintenance window or blackout period
+	if value, ok := tags[TagMaintenanceWindow]; ok {
+		if maintenanceWindows, err := parseMaintenanceWindows([]string{value}); err != nil {
+			log.Printf("[%s] Ignoring tag '%s': %v", autoScalingGroupName, TagMaintenanceWindow, err)
+		} else {
+			asgConfig.RolloutSchedule.MaintenanceWindows = maintenanceWindows
+		}
+	}
+	if value, ok := tags[TagBlackoutPeriod]; ok {
+		if blackoutPeriods, err := parseBlackoutPeriods([]string{value}); err != nil {
+			log.Printf("[%s] Ignoring tag '%s': %v", autoScalingGroupName, TagBlackoutPeriod, err)
+		} else {
+			asgConfig.RolloutSchedule.BlackoutPeriods = blackoutPeriods
+		}
+	}
+	return asgConfig
+}
+
+// IsPausedByTag checks whether the rolling update of an ASG is paused by its TagPaused tag, as opposed to by the
+// configuration
+func IsPausedByTag(tags map[string]string) bool {
+	paused, ok := parseBoolTag(tags[TagPaused])
+	return ok && paused
+}
+
+func overrideBool(autoScalingGroupName string, tags map[string]string, tag string, target *bool) {
+	value, ok := tags[tag]
+	if !ok {
+		return
+	}
+	if parsedValue, ok := parseBoolTag(value); ok {
+		*target = parsedValue
+	} else {
+		log.Printf("[%s] Ignoring tag '%s' because its value '%s' is neither 'true' nor 'false'", autoScalingGroupName, tag, value)
+	}
+}
+
+// parseBoolTag parses the value of a boolean tag, returning false as second value if it's neither true nor false
+func parseBoolTag(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func overrideIntOrPercentage(autoScalingGroupName string, tags map[string]string, tag string, target *intstr.IntOrString) {
+	value, ok := tags[tag]
+	if !ok {
+		return
+	}
+	parsedValue, err := ParseIntOrPercentage(strings.TrimSpace(value))
+	if err != nil {
+		log.Printf("[%s] Ignoring tag '%s': %v", autoScalingGroupName, tag, err)
+		return
+	}
+	*target = parsedValue
+}
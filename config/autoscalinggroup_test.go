@@ -0,0 +1,116 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestConfig_ForAutoScalingGroup(t *testing.T) {
+	Set(nil, true, true, false, false)
+	defer Set(nil, true, true, false, false)
+	Get().PodTerminationGracePeriod = -1
+	asgConfig := Get().ForAutoScalingGroup("asg", nil)
+	if asgConfig.SlowMode || asgConfig.EagerCordoning || asgConfig.ExcludeFromExternalLoadBalancers {
+		t.Error("SlowMode, EagerCordoning and ExcludeFromExternalLoadBalancers should've been inherited from the global configuration")
+	}
+	if !asgConfig.IgnoreDaemonSets || !asgConfig.DeleteEmptyDirData {
+		t.Error("IgnoreDaemonSets and DeleteEmptyDirData should've been inherited from the global configuration")
+	}
+	if asgConfig.PodTerminationGracePeriod != -1 {
+		t.Error("PodTerminationGracePeriod should've been inherited from the global configuration")
+	}
+	asgConfig = Get().ForAutoScalingGroup("asg", map[string]string{
+		TagSlowMode:                         "true",
+		TagEagerCordoning:                   "TRUE",
+		TagExcludeFromExternalLoadBalancers: "true",
+		TagPodTerminationGracePeriod:        "600",
+		TagIgnoreDaemonSets:                 "false",
+		TagDeleteEmptyDirData:               "false",
+		TagMaxUnavailable:                   "25%",
+		TagMaxSurge:                         "3",
+		TagDrainEscalationPolicy:            "Skip",
+		TagMaxUpdatedNonReadyNodes:          "1",
+		TagMaxUpdatedNonReadyNodesRatio:     "0.5",
+		TagUpdatedNodeMinReadyDuration:      "120",
+		TagPaused:                           "true",
+		TagMaintenanceWindow:                "Sat-Sun 00:00-24:00 America/New_York",
+		TagBlackoutPeriod:                   "2024-12-20/2025-01-05",
+		"unrelated-tag":                     "value",
+	})
+	if !asgConfig.SlowMode || !asgConfig.EagerCordoning || !asgConfig.ExcludeFromExternalLoadBalancers {
+		t.Error("SlowMode, EagerCordoning and ExcludeFromExternalLoadBalancers should've been overridden by the ASG's tags")
+	}
+	if asgConfig.IgnoreDaemonSets || asgConfig.DeleteEmptyDirData {
+		t.Error("IgnoreDaemonSets and DeleteEmptyDirData should've been overridden by the ASG's tags")
+	}
+	if asgConfig.PodTerminationGracePeriod != 600 {
+		t.Error("PodTerminationGracePeriod should've been overridden by the ASG's tags, got", asgConfig.PodTerminationGracePeriod)
+	}
+	if asgConfig.MaxUnavailable != intstr.FromString("25%") || asgConfig.MaxSurge != intstr.FromInt32(3) {
+		t.Error("MaxUnavailable and MaxSurge should've been overridden by the ASG's tags")
+	}
+	if asgConfig.DrainEscalationPolicy != DrainEscalationPolicySkip {
+		t.Error("DrainEscalationPolicy should've been overridden by the ASG's tags, got", asgConfig.DrainEscalationPolicy)
+	}
+	if asgConfig.MaxUpdatedNonReadyNodes != 1 || asgConfig.MaxUpdatedNonReadyNodesRatio != 0.5 || asgConfig.UpdatedNodeMinReadyDuration != 2*time.Minute {
+		t.Error("the readiness thresholds should've been overridden by the ASG's tags")
+	}
+	if !asgConfig.Paused {
+		t.Error("Paused should've been overridden by the ASG's tags")
+	}
+	if len(asgConfig.RolloutSchedule.MaintenanceWindows) != 1 || len(asgConfig.RolloutSchedule.BlackoutPeriods) != 1 {
+		t.Error("the rollout schedule should've been overridden by the ASG's tags, got", asgConfig.RolloutSchedule)
+	}
+	if Get().PodTerminationGracePeriod != -1 || Get().SlowMode {
+		t.Error("the global configuration shouldn't have been modified")
+	}
+}
+
+func TestConfig_ForAutoScalingGroup_withInvalidTagValues(t *testing.T) {
+	Set(nil, true, true, true, false)
+	defer Set(nil, true, true, false, false)
+	Get().PodTerminationGracePeriod = 30
+	asgConfig := Get().ForAutoScalingGroup("asg", map[string]string{
+		TagEagerCordoning:              "yes",
+		TagPodTerminationGracePeriod:   "ten",
+		TagMaxUnavailable:              "0",
+		TagDrainEscalationPolicy:       "retry",
+		TagMaxUpdatedNonReadyNodes:     "-1",
+		TagUpdatedNodeMinReadyDuration: "soon",
+	})
+	if !asgConfig.EagerCordoning {
+		t.Error("an invalid tag value should've been ignored in favor of the global configuration")
+	}
+	if asgConfig.PodTerminationGracePeriod != 30 {
+		t.Error("an invalid tag value should've been ignored in favor of the global configuration, got", asgConfig.PodTerminationGracePeriod)
+	}
+	if asgConfig.MaxUnavailable != intstr.FromInt32(1) {
+		t.Error("an invalid tag value should've been ignored in favor of the global configuration")
+	}
+	if asgConfig.DrainEscalationPolicy != DrainEscalationPolicyNone {
+		t.Error("an invalid tag value should've been ignored in favor of the global configuration, got", asgConfig.DrainEscalationPolicy)
+	}
+	if asgConfig.MaxUpdatedNonReadyNodes != 5 || asgConfig.UpdatedNodeMinReadyDuration != 0 {
+		t.Error("an invalid tag value should've been ignored in favor of the global configuration")
+	}
+}
+
+func TestIsPausedByTag(t *testing.T) {
+	scenarios := []struct {
+		tags     map[string]string
+		expected bool
+	}{
+		{tags: nil, expected: false},
+		{tags: map[string]string{TagPaused: "true"}, expected: true},
+		{tags: map[string]string{TagPaused: " TRUE "}, expected: true},
+		{tags: map[string]string{TagPaused: "false"}, expected: false},
+		{tags: map[string]string{TagPaused: "yes"}, expected: false},
+	}
+	for _, scenario := range scenarios {
+		if actual := IsPausedByTag(scenario.tags); actual != scenario.expected {
+			t.Errorf("expected IsPausedByTag(%v) to return %v, got %v", scenario.tags, scenario.expected, actual)
+		}
+	}
+}
@@ -6,10 +6,16 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-var cfg *config
+var (
+	cfg        atomic.Pointer[config]
+	pendingCfg atomic.Pointer[config]
+)
 
 const (
 	EnvEnvironment                      = "ENVIRONMENT"
@@ -23,89 +29,217 @@ const (
 	EnvAwsRegion                        = "AWS_REGION"
 	EnvExecutionInterval                = "EXECUTION_INTERVAL"
 	EnvExecutionTimeout                 = "EXECUTION_TIMEOUT"
+	EnvResyncInterval                   = "RESYNC_INTERVAL"
+	EnvWorkers                          = "WORKERS"
+	EnvMaxConcurrentDrains              = "MAX_CONCURRENT_DRAINS"
+	EnvShutdownTimeout                  = "SHUTDOWN_TIMEOUT"
+	EnvStuckTerminationThreshold        = "STUCK_TERMINATION_THRESHOLD"
+	EnvCompleteStuckLifecycleActions    = "COMPLETE_STUCK_LIFECYCLE_ACTIONS"
+	EnvDrainEscalationPolicy            = "DRAIN_ESCALATION_POLICY"
+	EnvDrainEscalationAttempts          = "DRAIN_ESCALATION_ATTEMPTS"
+	EnvDrainEscalationTimeout           = "DRAIN_ESCALATION_TIMEOUT"
+	EnvRolloutAbortTimeout              = "ROLLOUT_ABORT_TIMEOUT"
+	EnvMaxUpdatedNonReadyNodes          = "MAX_UPDATED_NON_READY_NODES"
+	EnvMaxUpdatedNonReadyNodesRatio     = "MAX_UPDATED_NON_READY_NODES_RATIO"
+	EnvUpdatedNodeMinReadyDuration      = "UPDATED_NODE_MIN_READY_DURATION"
+	EnvMaxFailedExecutions              = "MAX_FAILED_EXECUTIONS"
+	EnvLivenessMaxMissedIntervals       = "LIVENESS_MAX_MISSED_INTERVALS"
+	EnvHealthGateNodeConditions         = "HEALTH_GATE_NODE_CONDITIONS"
+	EnvHealthGateNodeLabels             = "HEALTH_GATE_NODE_LABELS"
+	EnvHealthGateStartupTaints          = "HEALTH_GATE_STARTUP_TAINTS"
+	EnvHealthGateDaemonSets             = "HEALTH_GATE_DAEMON_SETS"
+	EnvMaintenanceWindows               = "MAINTENANCE_WINDOWS"
+	EnvBlackoutPeriods                  = "BLACKOUT_PERIODS"
 	EnvPodTerminationGracePeriod        = "POD_TERMINATION_GRACE_PERIOD"
 	EnvMetrics                          = "METRICS"
 	EnvMetricsPort                      = "METRICS_PORT"
+	EnvAdminAPI                         = "ADMIN_API"
+	EnvHealthProbes                     = "HEALTH_PROBES"
 	EnvSlowMode                         = "SLOW_MODE"
 	EnvEagerCordoning                   = "EAGER_CORDONING"
 	EnvExcludeFromExternalLoadBalancers = "EXCLUDE_FROM_EXTERNAL_LOAD_BALANCERS"
+	EnvDryRun                           = "DRY_RUN"
+	EnvPaused                           = "PAUSED"
+	EnvLeaderElection                   = "LEADER_ELECTION"
+	EnvLeaderElectionNamespace          = "LEADER_ELECTION_NAMESPACE"
+	EnvLeaderElectionLeaseName          = "LEADER_ELECTION_LEASE_NAME"
+	EnvMaxUnavailable                   = "MAX_UNAVAILABLE"
+	EnvMaxSurge                         = "MAX_SURGE"
+	EnvConfigFile                       = "CONFIG_FILE"
+)
+
+const (
+	// DrainEscalationPolicyNone keeps retrying to drain the node on every execution
+	DrainEscalationPolicyNone = "none"
+	// DrainEscalationPolicySkip uncordons the node and skips it, so that the other outdated nodes can be rolled out
+	DrainEscalationPolicySkip = "skip"
+	// DrainEscalationPolicyForce drains the node by deleting its pods instead of evicting them, which bypasses
+	// PodDisruptionBudgets
+	DrainEscalationPolicyForce = "force"
+	// DrainEscalationPolicyBlock stops rolling out the node's ASG until the node's failed drain attempts are reset
+	DrainEscalationPolicyBlock = "block"
 )
 
+// DrainEscalationPolicies is the list of every valid drain escalation policy
+var DrainEscalationPolicies = []string{DrainEscalationPolicyNone, DrainEscalationPolicySkip, DrainEscalationPolicyForce, DrainEscalationPolicyBlock}
+
 type config struct {
-	Environment                      string        // Optional
-	Debug                            bool          // Defaults to false
-	AutoScalingGroupNames            []string      // Required if AutodiscoveryTags not provided
-	AutodiscoveryTags                string        // Required if AutoScalingGroupNames not provided
-	AwsRegion                        string        // Defaults to us-west-2
-	IgnoreDaemonSets                 bool          // Defaults to true
-	DeleteEmptyDirData               bool          // Defaults to true
-	ExecutionInterval                time.Duration // Defaults to 20s
-	ExecutionTimeout                 time.Duration // Defaults to 900s
-	PodTerminationGracePeriod        int           // Defaults to -1
-	Metrics                          bool          // Defaults to false
-	MetricsPort                      int           // Defaults to 8080
-	SlowMode                         bool          // Defaults to false
-	EagerCordoning                   bool          // Defaults to false
-	ExcludeFromExternalLoadBalancers bool          // Defaults to false
+	Environment                      string             // Optional
+	Debug                            bool               // Defaults to false
+	AutoScalingGroupNames            []string           // Required if AutodiscoveryTags not provided
+	AutodiscoveryTags                string             // Required if AutoScalingGroupNames not provided
+	AwsRegion                        string             // Defaults to us-west-2
+	IgnoreDaemonSets                 bool               // Defaults to true
+	DeleteEmptyDirData               bool               // Defaults to true
+	ExecutionInterval                time.Duration      // Defaults to 20s
+	ExecutionTimeout                 time.Duration      // Defaults to 900s
+	ResyncInterval                   time.Duration      // Defaults to 300s
+	Workers                          int                // Defaults to 5
+	MaxConcurrentDrains              int                // Defaults to 5
+	ShutdownTimeout                  time.Duration      // Defaults to 25s
+	StuckTerminationThreshold        time.Duration      // Defaults to 600s
+	CompleteStuckLifecycleActions    bool               // Defaults to false
+	DrainEscalationPolicy            string             // Defaults to none
+	DrainEscalationAttempts          int                // Defaults to 5
+	DrainEscalationTimeout           time.Duration      // Defaults to 0s (disabled)
+	RolloutAbortTimeout              time.Duration      // Defaults to 0s (disabled)
+	MaxUpdatedNonReadyNodes          int                // Defaults to 5
+	MaxUpdatedNonReadyNodesRatio     float64            // Defaults to 0.11
+	UpdatedNodeMinReadyDuration      time.Duration      // Defaults to 0s (disabled)
+	MaxFailedExecutions              int                // Defaults to 10
+	LivenessMaxMissedIntervals       int                // Defaults to 3
+	HealthGates                      HealthGates        // Optional
+	RolloutSchedule                  RolloutSchedule    // Optional
+	PodTerminationGracePeriod        int                // Defaults to -1
+	Metrics                          bool               // Defaults to false
+	MetricsPort                      int                // Defaults to 8080
+	AdminAPI                         bool               // Defaults to false
+	HealthProbes                     bool               // Defaults to true
+	SlowMode                         bool               // Defaults to false
+	EagerCordoning                   bool               // Defaults to false
+	ExcludeFromExternalLoadBalancers bool               // Defaults to false
+	DryRun                           bool               // Defaults to false
+	Paused                           bool               // Defaults to false
+	LeaderElection                   bool               // Defaults to false
+	LeaderElectionNamespace          string             // Defaults to kube-system
+	LeaderElectionLeaseName          string             // Defaults to aws-eks-asg-rolling-update-handler
+	MaxUnavailable                   intstr.IntOrString // Defaults to 1
+	MaxSurge                         intstr.IntOrString // Defaults to 1
+	ConfigFile                       string             // Optional
+
+	// AutoScalingGroups contains the overrides of specific ASGs, indexed by ASG name.
+	// Can only be set through the configuration file.
+	AutoScalingGroups map[string]*AutoScalingGroupOverrides
 }
 
 // Initialize is used to initialize the application's configuration
+//
+// If the CONFIG_FILE environment variable is set, the configuration file is loaded as well, but environment
+// variables always take precedence over the configuration file.
 func Initialize() error {
-	cfg = &config{
-		Environment:                      strings.ToLower(os.Getenv(EnvEnvironment)),
-		Debug:                            strings.ToLower(os.Getenv(EnvDebug)) == "true",
-		SlowMode:                         strings.ToLower(os.Getenv(EnvSlowMode)) == "true",
-		EagerCordoning:                   strings.ToLower(os.Getenv(EnvEagerCordoning)) == "true",
-		ExcludeFromExternalLoadBalancers: strings.ToLower(os.Getenv(EnvExcludeFromExternalLoadBalancers)) == "true",
-	}
-	if clusterName := os.Getenv(EnvClusterName); len(clusterName) > 0 {
+	loadedConfig, err := load()
+	if err != nil {
+		return err
+	}
+	cfg.Store(loadedConfig)
+	return nil
+}
+
+// load creates a new configuration based on the environment variables and, if specified, the configuration file
+func load() (*config, error) {
+	var (
+		err        error
+		fileValues map[string]string
+		fileConfig *configFile
+	)
+	configFilePath := os.Getenv(EnvConfigFile)
+	if len(configFilePath) > 0 {
+		if fileConfig, err = readConfigFile(configFilePath); err != nil {
+			return nil, err
+		}
+		fileValues = fileConfig.toEnvironmentVariables()
+	}
+	// getenv returns the value of the environment variable if it is set, or the value from the configuration file
+	// otherwise.
+	getenv := func(key string) string {
+		if value := os.Getenv(key); len(value) > 0 {
+			return value
+		}
+		return fileValues[key]
+	}
+	if len(os.Getenv(EnvClusterName)) > 0 || len(os.Getenv(EnvAutodiscoveryTags)) > 0 || len(os.Getenv(EnvAutoScalingGroupNames)) > 0 {
+		// If the ASGs are specified through environment variables, the ones from the configuration file are ignored
+		delete(fileValues, EnvClusterName)
+		delete(fileValues, EnvAutodiscoveryTags)
+		delete(fileValues, EnvAutoScalingGroupNames)
+	}
+	cfg := &config{
+		Environment:                      strings.ToLower(getenv(EnvEnvironment)),
+		Debug:                            strings.ToLower(getenv(EnvDebug)) == "true",
+		SlowMode:                         strings.ToLower(getenv(EnvSlowMode)) == "true",
+		EagerCordoning:                   strings.ToLower(getenv(EnvEagerCordoning)) == "true",
+		ExcludeFromExternalLoadBalancers: strings.ToLower(getenv(EnvExcludeFromExternalLoadBalancers)) == "true",
+		DryRun:                           strings.ToLower(getenv(EnvDryRun)) == "true",
+		Paused:                           strings.ToLower(getenv(EnvPaused)) == "true",
+		LeaderElection:                   strings.ToLower(getenv(EnvLeaderElection)) == "true",
+		CompleteStuckLifecycleActions:    strings.ToLower(getenv(EnvCompleteStuckLifecycleActions)) == "true",
+		AdminAPI:                         strings.ToLower(getenv(EnvAdminAPI)) == "true",
+		HealthProbes:                     strings.ToLower(getenv(EnvHealthProbes)) != "false",
+		ConfigFile:                       configFilePath,
+	}
+	if fileConfig != nil {
+		cfg.AutoScalingGroups = fileConfig.AutoScalingGroups
+	}
+	if clusterName := getenv(EnvClusterName); len(clusterName) > 0 {
 		// See "Prerequisites" in https://docs.aws.amazon.com/eks/latest/userguide/autoscaling.html
 		cfg.AutodiscoveryTags = fmt.Sprintf("k8s.io/cluster-autoscaler/%s=owned,k8s.io/cluster-autoscaler/enabled=true", clusterName)
-	} else if autodiscoveryTags := os.Getenv(EnvAutodiscoveryTags); len(autodiscoveryTags) > 0 {
+	} else if autodiscoveryTags := getenv(EnvAutodiscoveryTags); len(autodiscoveryTags) > 0 {
 		cfg.AutodiscoveryTags = autodiscoveryTags
-	} else if autoScalingGroupNames := os.Getenv(EnvAutoScalingGroupNames); len(autoScalingGroupNames) > 0 {
+	} else if autoScalingGroupNames := getenv(EnvAutoScalingGroupNames); len(autoScalingGroupNames) > 0 {
 		cfg.AutoScalingGroupNames = strings.Split(strings.TrimSpace(autoScalingGroupNames), ",")
 	} else {
-		return fmt.Errorf("environment variables '%s', '%s' or '%s' are not set", EnvAutoScalingGroupNames, EnvClusterName, EnvAutodiscoveryTags)
+		return nil, fmt.Errorf("environment variables '%s', '%s' or '%s' are not set", EnvAutoScalingGroupNames, EnvClusterName, EnvAutodiscoveryTags)
 	}
-	if ignoreDaemonSets := strings.ToLower(os.Getenv(EnvIgnoreDaemonSets)); len(ignoreDaemonSets) == 0 || ignoreDaemonSets == "true" {
+	if ignoreDaemonSets := strings.ToLower(getenv(EnvIgnoreDaemonSets)); len(ignoreDaemonSets) == 0 || ignoreDaemonSets == "true" {
 		cfg.IgnoreDaemonSets = true
 	}
+	deleteEmptyDirData := strings.ToLower(getenv(EnvDeleteEmptyDirData))
 	// if the deprecated EnvDeleteLocalData is set, we need to set EnvDeleteEmptyDirData to its value
-	if deleteLocalData := strings.ToLower(os.Getenv(EnvDeleteLocalData)); len(deleteLocalData) > 0 {
+	if deleteLocalData := strings.ToLower(getenv(EnvDeleteLocalData)); len(deleteLocalData) > 0 {
 		log.Println("NOTICE: Environment variable '" + EnvDeleteLocalData + "' has been deprecated in favor of '" + EnvDeleteEmptyDirData + "'.")
 		log.Println("NOTICE: Make sure to update your configuration, as said deprecated environment variable will be removed in a future release.")
-		if len(os.Getenv(EnvDeleteEmptyDirData)) == 0 {
-			_ = os.Setenv(EnvDeleteEmptyDirData, deleteLocalData)
+		if len(deleteEmptyDirData) == 0 {
+			deleteEmptyDirData = deleteLocalData
 		} else {
 			log.Println("WARNING: Both '" + EnvDeleteLocalData + "' and '" + EnvDeleteEmptyDirData + "' are set. The former is deprecated, and will be ignored.")
 		}
 	}
-	if deleteEmptyDirData := strings.ToLower(os.Getenv(EnvDeleteEmptyDirData)); len(deleteEmptyDirData) == 0 || deleteEmptyDirData == "true" {
+	if len(deleteEmptyDirData) == 0 || deleteEmptyDirData == "true" {
 		cfg.DeleteEmptyDirData = true
 	}
-	if awsRegion := strings.ToLower(os.Getenv(EnvAwsRegion)); len(awsRegion) == 0 {
+	if awsRegion := strings.ToLower(getenv(EnvAwsRegion)); len(awsRegion) == 0 {
 		log.Printf("Environment variable '%s' not specified, defaulting to us-west-2", EnvAwsRegion)
 		cfg.AwsRegion = "us-west-2"
 	} else {
 		cfg.AwsRegion = awsRegion
 	}
-	if metricsPort := os.Getenv(EnvMetricsPort); len(metricsPort) == 0 {
+	if metricsPort := getenv(EnvMetricsPort); len(metricsPort) == 0 {
 		log.Printf("Environment variable '%s' not specified, defaulting to 8080", EnvMetricsPort)
 		cfg.MetricsPort = 8080
 	} else {
 		port, err := strconv.Atoi(metricsPort)
 		if err != nil {
-			return fmt.Errorf("invalid value for '%s': %s", EnvMetricsPort, err)
+			return nil, fmt.Errorf("invalid value for '%s': %s", EnvMetricsPort, err)
 		}
 		cfg.MetricsPort = port
 	}
-	if metrics := strings.ToLower(os.Getenv(EnvMetrics)); len(metrics) != 0 {
+	if metrics := strings.ToLower(getenv(EnvMetrics)); len(metrics) != 0 {
 		cfg.Metrics = true
 	}
-	if executionInterval := os.Getenv(EnvExecutionInterval); len(executionInterval) > 0 {
-		if interval, err := strconv.Atoi(executionInterval); err != nil {
-			return fmt.Errorf("environment variable '%s' must be an integer", EnvExecutionInterval)
+	if executionInterval := getenv(EnvExecutionInterval); len(executionInterval) > 0 {
+		if interval, err := strconv.Atoi(executionInterval); err != nil || interval <= 0 {
+			// The execution interval is also the initial backoff, so it can't be 0
+			return nil, fmt.Errorf("environment variable '%s' must be a positive integer", EnvExecutionInterval)
 		} else {
 			cfg.ExecutionInterval = time.Second * time.Duration(interval)
 		}
@@ -113,9 +247,9 @@ func Initialize() error {
 		log.Printf("Environment variable '%s' not specified, defaulting to 20 seconds", EnvExecutionInterval)
 		cfg.ExecutionInterval = time.Second * 20
 	}
-	if executionTimeout := os.Getenv(EnvExecutionTimeout); len(executionTimeout) > 0 {
+	if executionTimeout := getenv(EnvExecutionTimeout); len(executionTimeout) > 0 {
 		if timeout, err := strconv.Atoi(executionTimeout); err != nil {
-			return fmt.Errorf("environment variable '%s' must be an integer", EnvExecutionTimeout)
+			return nil, fmt.Errorf("environment variable '%s' must be an integer", EnvExecutionTimeout)
 		} else {
 			cfg.ExecutionTimeout = time.Second * time.Duration(timeout)
 		}
@@ -123,9 +257,120 @@ func Initialize() error {
 		log.Printf("Environment variable '%s' not specified, defaulting to 900 seconds", EnvExecutionTimeout)
 		cfg.ExecutionTimeout = time.Second * 900
 	}
-	if terminationGracePeriod := os.Getenv(EnvPodTerminationGracePeriod); len(terminationGracePeriod) > 0 {
+	if resyncInterval := getenv(EnvResyncInterval); len(resyncInterval) > 0 {
+		if interval, err := strconv.Atoi(resyncInterval); err != nil || interval <= 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a positive integer", EnvResyncInterval)
+		} else {
+			cfg.ResyncInterval = time.Second * time.Duration(interval)
+		}
+	} else {
+		log.Printf("Environment variable '%s' not specified, defaulting to 300 seconds", EnvResyncInterval)
+		cfg.ResyncInterval = time.Second * 300
+	}
+	if cfg.Workers, err = parsePositiveInt(EnvWorkers, getenv(EnvWorkers), 5); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrentDrains, err = parsePositiveInt(EnvMaxConcurrentDrains, getenv(EnvMaxConcurrentDrains), 5); err != nil {
+		return nil, err
+	}
+	if shutdownTimeout := getenv(EnvShutdownTimeout); len(shutdownTimeout) > 0 {
+		if timeout, err := strconv.Atoi(shutdownTimeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a positive integer", EnvShutdownTimeout)
+		} else {
+			cfg.ShutdownTimeout = time.Second * time.Duration(timeout)
+		}
+	} else {
+		log.Printf("Environment variable '%s' not specified, defaulting to 25 seconds", EnvShutdownTimeout)
+		cfg.ShutdownTimeout = time.Second * 25
+	}
+	if stuckTerminationThreshold := getenv(EnvStuckTerminationThreshold); len(stuckTerminationThreshold) > 0 {
+		if threshold, err := strconv.Atoi(stuckTerminationThreshold); err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a positive integer", EnvStuckTerminationThreshold)
+		} else {
+			cfg.StuckTerminationThreshold = time.Second * time.Duration(threshold)
+		}
+	} else {
+		log.Printf("Environment variable '%s' not specified, defaulting to 600 seconds", EnvStuckTerminationThreshold)
+		cfg.StuckTerminationThreshold = time.Second * 600
+	}
+	if drainEscalationPolicy := strings.ToLower(strings.TrimSpace(getenv(EnvDrainEscalationPolicy))); len(drainEscalationPolicy) > 0 {
+		if !IsValidDrainEscalationPolicy(drainEscalationPolicy) {
+			return nil, fmt.Errorf("environment variable '%s' must be one of %s", EnvDrainEscalationPolicy, strings.Join(DrainEscalationPolicies, ", "))
+		}
+		cfg.DrainEscalationPolicy = drainEscalationPolicy
+	} else {
+		log.Printf("Environment variable '%s' not specified, defaulting to %s", EnvDrainEscalationPolicy, DrainEscalationPolicyNone)
+		cfg.DrainEscalationPolicy = DrainEscalationPolicyNone
+	}
+	if cfg.DrainEscalationAttempts, err = parsePositiveInt(EnvDrainEscalationAttempts, getenv(EnvDrainEscalationAttempts), 5); err != nil {
+		return nil, err
+	}
+	if drainEscalationTimeout := getenv(EnvDrainEscalationTimeout); len(drainEscalationTimeout) > 0 {
+		if timeout, err := strconv.Atoi(drainEscalationTimeout); err != nil || timeout < 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a non-negative integer", EnvDrainEscalationTimeout)
+		} else {
+			cfg.DrainEscalationTimeout = time.Second * time.Duration(timeout)
+		}
+	}
+	if rolloutAbortTimeout := getenv(EnvRolloutAbortTimeout); len(rolloutAbortTimeout) > 0 {
+		if timeout, err := strconv.Atoi(rolloutAbortTimeout); err != nil || timeout < 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a non-negative integer", EnvRolloutAbortTimeout)
+		} else {
+			cfg.RolloutAbortTimeout = time.Second * time.Duration(timeout)
+		}
+	}
+	if maxUpdatedNonReadyNodes := getenv(EnvMaxUpdatedNonReadyNodes); len(maxUpdatedNonReadyNodes) > 0 {
+		if maximum, err := strconv.Atoi(maxUpdatedNonReadyNodes); err != nil || maximum < 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a non-negative integer", EnvMaxUpdatedNonReadyNodes)
+		} else {
+			cfg.MaxUpdatedNonReadyNodes = maximum
+		}
+	} else {
+		log.Printf("Environment variable '%s' not specified, defaulting to 5", EnvMaxUpdatedNonReadyNodes)
+		cfg.MaxUpdatedNonReadyNodes = 5
+	}
+	if maxUpdatedNonReadyNodesRatio := getenv(EnvMaxUpdatedNonReadyNodesRatio); len(maxUpdatedNonReadyNodesRatio) > 0 {
+		if ratio, err := strconv.ParseFloat(maxUpdatedNonReadyNodesRatio, 64); err != nil || ratio < 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a non-negative number", EnvMaxUpdatedNonReadyNodesRatio)
+		} else {
+			cfg.MaxUpdatedNonReadyNodesRatio = ratio
+		}
+	} else {
+		log.Printf("Environment variable '%s' not specified, defaulting to 0.11", EnvMaxUpdatedNonReadyNodesRatio)
+		cfg.MaxUpdatedNonReadyNodesRatio = 0.11
+	}
+	if updatedNodeMinReadyDuration := getenv(EnvUpdatedNodeMinReadyDuration); len(updatedNodeMinReadyDuration) > 0 {
+		if duration, err := strconv.Atoi(updatedNodeMinReadyDuration); err != nil || duration < 0 {
+			return nil, fmt.Errorf("environment variable '%s' must be a non-negative integer", EnvUpdatedNodeMinReadyDuration)
+		} else {
+			cfg.UpdatedNodeMinReadyDuration = time.Second * time.Duration(duration)
+		}
+	}
+	if cfg.MaxFailedExecutions, err = parsePositiveInt(EnvMaxFailedExecutions, getenv(EnvMaxFailedExecutions), 10); err != nil {
+		return nil, err
+	}
+	if cfg.LivenessMaxMissedIntervals, err = parsePositiveInt(EnvLivenessMaxMissedIntervals, getenv(EnvLivenessMaxMissedIntervals), 3); err != nil {
+		return nil, err
+	}
+	if cfg.HealthGates.NodeConditions, err = parseNodeConditions([]string{getenv(EnvHealthGateNodeConditions)}); err != nil {
+		return nil, fmt.Errorf("invalid value for '%s': %w", EnvHealthGateNodeConditions, err)
+	}
+	if cfg.HealthGates.NodeLabels, err = parseNodeLabels([]string{getenv(EnvHealthGateNodeLabels)}); err != nil {
+		return nil, fmt.Errorf("invalid value for '%s': %w", EnvHealthGateNodeLabels, err)
+	}
+	cfg.HealthGates.StartupTaints = splitList([]string{getenv(EnvHealthGateStartupTaints)})
+	if cfg.HealthGates.DaemonSets, err = parseDaemonSets([]string{getenv(EnvHealthGateDaemonSets)}); err != nil {
+		return nil, fmt.Errorf("invalid value for '%s': %w", EnvHealthGateDaemonSets, err)
+	}
+	if cfg.RolloutSchedule.MaintenanceWindows, err = parseMaintenanceWindows([]string{getenv(EnvMaintenanceWindows)}); err != nil {
+		return nil, fmt.Errorf("invalid value for '%s': %w", EnvMaintenanceWindows, err)
+	}
+	if cfg.RolloutSchedule.BlackoutPeriods, err = parseBlackoutPeriods([]string{getenv(EnvBlackoutPeriods)}); err != nil {
+		return nil, fmt.Errorf("invalid value for '%s': %w", EnvBlackoutPeriods, err)
+	}
+	if terminationGracePeriod := getenv(EnvPodTerminationGracePeriod); len(terminationGracePeriod) > 0 {
 		if gracePeriod, err := strconv.Atoi(terminationGracePeriod); err != nil {
-			return fmt.Errorf("environment variable '%s' must be an integer", EnvPodTerminationGracePeriod)
+			return nil, fmt.Errorf("environment variable '%s' must be an integer", EnvPodTerminationGracePeriod)
 		} else {
 			cfg.PodTerminationGracePeriod = gracePeriod
 		}
@@ -133,13 +378,89 @@ func Initialize() error {
 		log.Printf("Environment variable '%s' not specified, defaulting to -1 (pod's terminationGracePeriodSeconds)", EnvPodTerminationGracePeriod)
 		cfg.PodTerminationGracePeriod = -1
 	}
-	return nil
+	if cfg.MaxUnavailable, err = parseIntOrPercentage(EnvMaxUnavailable, getenv(EnvMaxUnavailable)); err != nil {
+		return nil, err
+	}
+	if cfg.MaxSurge, err = parseIntOrPercentage(EnvMaxSurge, getenv(EnvMaxSurge)); err != nil {
+		return nil, err
+	}
+	if cfg.LeaderElection {
+		if leaderElectionNamespace := getenv(EnvLeaderElectionNamespace); len(leaderElectionNamespace) > 0 {
+			cfg.LeaderElectionNamespace = leaderElectionNamespace
+		} else {
+			log.Printf("Environment variable '%s' not specified, defaulting to kube-system", EnvLeaderElectionNamespace)
+			cfg.LeaderElectionNamespace = "kube-system"
+		}
+		if leaderElectionLeaseName := getenv(EnvLeaderElectionLeaseName); len(leaderElectionLeaseName) > 0 {
+			cfg.LeaderElectionLeaseName = leaderElectionLeaseName
+		} else {
+			log.Printf("Environment variable '%s' not specified, defaulting to aws-eks-asg-rolling-update-handler", EnvLeaderElectionLeaseName)
+			cfg.LeaderElectionLeaseName = "aws-eks-asg-rolling-update-handler"
+		}
+	}
+	return cfg, nil
+}
+
+// IsValidDrainEscalationPolicy checks whether the given value is one of DrainEscalationPolicies
+func IsValidDrainEscalationPolicy(value string) bool {
+	for _, policy := range DrainEscalationPolicies {
+		if value == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePositiveInt parses the value of an environment variable that must be a positive integer. Defaults to the given
+// default value if the environment variable isn't set.
+func parsePositiveInt(environmentVariable, value string, defaultValue int) (int, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		log.Printf("Environment variable '%s' not specified, defaulting to %d", environmentVariable, defaultValue)
+		return defaultValue, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("environment variable '%s' must be a positive integer", environmentVariable)
+	}
+	return number, nil
+}
+
+// parseIntOrPercentage parses the value of an environment variable that can either be an absolute number (e.g. 5)
+// or a percentage (e.g. 25%). Defaults to 1 if the environment variable isn't set.
+func parseIntOrPercentage(environmentVariable, value string) (intstr.IntOrString, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		log.Printf("Environment variable '%s' not specified, defaulting to 1", environmentVariable)
+		return intstr.FromInt32(1), nil
+	}
+	parsedValue, err := ParseIntOrPercentage(value)
+	if err != nil {
+		return intstr.IntOrString{}, fmt.Errorf("invalid value for '%s': %w", environmentVariable, err)
+	}
+	return parsedValue, nil
+}
+
+// ParseIntOrPercentage parses a value that can either be an absolute number (e.g. 5) or a percentage (e.g. 25%)
+func ParseIntOrPercentage(value string) (intstr.IntOrString, error) {
+	if strings.HasSuffix(value, "%") {
+		percentage, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil || percentage <= 0 || percentage > 100 {
+			return intstr.IntOrString{}, fmt.Errorf("invalid percentage '%s': must be between 1%% and 100%%", value)
+		}
+		return intstr.FromString(value), nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return intstr.IntOrString{}, fmt.Errorf("invalid value '%s': must be a positive integer or a percentage", value)
+	}
+	return intstr.FromInt32(int32(number)), nil
 }
 
 // Set sets the application's configuration and is intended to be used for testing purposes.
 // See Initialize() for production
 func Set(autoScalingGroupNames []string, ignoreDaemonSets, deleteEmptyDirData, eagerCordoning bool, excludeFromExternalLoadBalancers bool) {
-	cfg = &config{
+	cfg.Store(&config{
 		AutoScalingGroupNames:            autoScalingGroupNames,
 		IgnoreDaemonSets:                 ignoreDaemonSets,
 		DeleteEmptyDirData:               deleteEmptyDirData,
@@ -147,13 +468,27 @@ func Set(autoScalingGroupNames []string, ignoreDaemonSets, deleteEmptyDirData, e
 		ExcludeFromExternalLoadBalancers: excludeFromExternalLoadBalancers,
 		ExecutionInterval:                time.Second * 20,
 		ExecutionTimeout:                 time.Second * 900,
-	}
+		ResyncInterval:                   time.Second * 300,
+		Workers:                          5,
+		MaxConcurrentDrains:              5,
+		ShutdownTimeout:                  time.Second * 25,
+		StuckTerminationThreshold:        time.Second * 600,
+		DrainEscalationPolicy:            DrainEscalationPolicyNone,
+		DrainEscalationAttempts:          5,
+		MaxUpdatedNonReadyNodes:          5,
+		MaxUpdatedNonReadyNodesRatio:     0.11,
+		MaxFailedExecutions:              10,
+		LivenessMaxMissedIntervals:       3,
+		MaxUnavailable:                   intstr.FromInt32(1),
+		MaxSurge:                         intstr.FromInt32(1),
+		HealthProbes:                     true,
+	})
 }
 
 func Get() *config {
-	if cfg == nil {
+	if cfg.Load() == nil {
 		log.Println("Config wasn't initialized prior to being called. Assuming this is a test.")
 		Set(nil, true, true, false, false)
 	}
-	return cfg
+	return cfg.Load()
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestInitialize(t *testing.T) {
@@ -11,6 +12,7 @@ func TestInitialize(t *testing.T) {
 	_ = os.Setenv(EnvIgnoreDaemonSets, "false")
 	_ = os.Setenv(EnvDeleteLocalData, "false")
 	_ = os.Setenv(EnvSlowMode, "true")
+	_ = os.Setenv(EnvDryRun, "true")
 	defer os.Clearenv()
 	_ = Initialize()
 	config := Get()
@@ -26,6 +28,9 @@ func TestInitialize(t *testing.T) {
 	if !config.SlowMode {
 		t.Error("SlowMode should be true")
 	}
+	if !config.DryRun {
+		t.Error("DryRun should be true")
+	}
 }
 
 func TestInitialize_withDefaultNonRequiredValues(t *testing.T) {
@@ -45,6 +50,40 @@ func TestInitialize_withDefaultNonRequiredValues(t *testing.T) {
 	if config.SlowMode {
 		t.Error("SlowMode should be false")
 	}
+	if config.DryRun {
+		t.Error("DryRun should be false")
+	}
+	if config.Workers != 5 {
+		t.Error("Workers should've defaulted to 5, got", config.Workers)
+	}
+	if config.MaxConcurrentDrains != 5 {
+		t.Error("MaxConcurrentDrains should've defaulted to 5, got", config.MaxConcurrentDrains)
+	}
+	if config.DrainEscalationPolicy != DrainEscalationPolicyNone || config.DrainEscalationAttempts != 5 || config.DrainEscalationTimeout != 0 {
+		t.Error("the drain escalation should've defaulted to retrying forever")
+	}
+	if config.MaxUpdatedNonReadyNodes != 5 || config.MaxUpdatedNonReadyNodesRatio != 0.11 || config.UpdatedNodeMinReadyDuration != 0 {
+		t.Error("the readiness thresholds should've defaulted to 5 non-ready nodes, a ratio of 0.11 and no minimum ready duration")
+	}
+	if config.MaxFailedExecutions != 10 {
+		t.Error("MaxFailedExecutions should've defaulted to 10, got", config.MaxFailedExecutions)
+	}
+	if config.LivenessMaxMissedIntervals != 3 {
+		t.Error("LivenessMaxMissedIntervals should've defaulted to 3, got", config.LivenessMaxMissedIntervals)
+	}
+	if !config.HealthProbes {
+		t.Error("HealthProbes should've defaulted to true")
+	}
+}
+
+func TestInitialize_withHealthProbesDisabled(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	_ = os.Setenv(EnvHealthProbes, "false")
+	defer os.Clearenv()
+	_ = Initialize()
+	if Get().HealthProbes {
+		t.Error("HealthProbes should be false")
+	}
 }
 
 func TestInitialize_withMissingRequiredValues(t *testing.T) {
@@ -106,3 +145,184 @@ func TestInitialize_withAutoScalingGroupNames(t *testing.T) {
 		t.Error()
 	}
 }
+
+func TestInitialize_withLeaderElection(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	_ = os.Setenv(EnvLeaderElection, "true")
+	defer os.Clearenv()
+	_ = Initialize()
+	config := Get()
+	if !config.LeaderElection {
+		t.Error("LeaderElection should be true")
+	}
+	if config.LeaderElectionNamespace != "kube-system" {
+		t.Error("LeaderElectionNamespace should've defaulted to kube-system")
+	}
+	if config.LeaderElectionLeaseName != "aws-eks-asg-rolling-update-handler" {
+		t.Error("LeaderElectionLeaseName should've defaulted to aws-eks-asg-rolling-update-handler")
+	}
+}
+
+func TestInitialize_withMaxUnavailableAndMaxSurge(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	_ = os.Setenv(EnvMaxUnavailable, "3")
+	_ = os.Setenv(EnvMaxSurge, "25%")
+	defer os.Clearenv()
+	if err := Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	config := Get()
+	if config.MaxUnavailable.String() != "3" {
+		t.Error("MaxUnavailable should've been 3, got", config.MaxUnavailable.String())
+	}
+	if config.MaxSurge.String() != "25%" {
+		t.Error("MaxSurge should've been 25%, got", config.MaxSurge.String())
+	}
+}
+
+func TestInitialize_withInvalidMaxUnavailable(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	defer os.Clearenv()
+	for _, value := range []string{"0", "-1", "abc", "0%", "101%"} {
+		_ = os.Setenv(EnvMaxUnavailable, value)
+		if err := Initialize(); err == nil {
+			t.Errorf("expected error for %s=%s", EnvMaxUnavailable, value)
+		}
+	}
+}
+
+func TestInitialize_withInvalidWorkers(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	defer os.Clearenv()
+	for _, value := range []string{"0", "-1", "abc"} {
+		_ = os.Setenv(EnvWorkers, value)
+		if err := Initialize(); err == nil {
+			t.Errorf("expected error for %s=%s", EnvWorkers, value)
+		}
+	}
+}
+
+func TestInitialize_withInvalidExecutionInterval(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	defer os.Clearenv()
+	for _, value := range []string{"0", "-1", "abc"} {
+		_ = os.Setenv(EnvExecutionInterval, value)
+		if err := Initialize(); err == nil {
+			t.Errorf("expected error for %s=%s", EnvExecutionInterval, value)
+		}
+	}
+}
+
+func TestInitialize_withInvalidDrainEscalation(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	defer os.Clearenv()
+	for key, values := range map[string][]string{
+		EnvDrainEscalationPolicy:   {"retry", "true"},
+		EnvDrainEscalationAttempts: {"0", "-1", "abc"},
+		EnvDrainEscalationTimeout:  {"-1", "abc"},
+		EnvRolloutAbortTimeout:     {"-1", "abc"},
+	} {
+		for _, value := range values {
+			_ = os.Setenv(key, value)
+			if err := Initialize(); err == nil {
+				t.Errorf("expected error for %s=%s", key, value)
+			}
+			_ = os.Unsetenv(key)
+		}
+	}
+}
+
+func TestInitialize_withReadinessThresholds(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	_ = os.Setenv(EnvMaxUpdatedNonReadyNodes, "0")
+	_ = os.Setenv(EnvMaxUpdatedNonReadyNodesRatio, "0.5")
+	_ = os.Setenv(EnvUpdatedNodeMinReadyDuration, "300")
+	_ = os.Setenv(EnvMaxFailedExecutions, "3")
+	defer os.Clearenv()
+	if err := Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	config := Get()
+	if config.MaxUpdatedNonReadyNodes != 0 || config.MaxUpdatedNonReadyNodesRatio != 0.5 || config.UpdatedNodeMinReadyDuration != 5*time.Minute {
+		t.Error("the readiness thresholds should've been set from the environment variables")
+	}
+	if config.MaxFailedExecutions != 3 {
+		t.Error("MaxFailedExecutions should've been 3, got", config.MaxFailedExecutions)
+	}
+}
+
+func TestInitialize_withInvalidReadinessThresholds(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	defer os.Clearenv()
+	for key, values := range map[string][]string{
+		EnvMaxUpdatedNonReadyNodes:      {"-1", "abc"},
+		EnvMaxUpdatedNonReadyNodesRatio: {"-0.1", "abc"},
+		EnvUpdatedNodeMinReadyDuration:  {"-1", "abc"},
+		EnvMaxFailedExecutions:          {"0", "-1", "abc"},
+		EnvLivenessMaxMissedIntervals:   {"0", "abc"},
+	} {
+		for _, value := range values {
+			_ = os.Setenv(key, value)
+			if err := Initialize(); err == nil {
+				t.Errorf("expected error for %s=%s", key, value)
+			}
+			_ = os.Unsetenv(key)
+		}
+	}
+}
+
+func TestInitialize_withHealthGates(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	_ = os.Setenv(EnvHealthGateNodeConditions, "NetworkingReady, KernelDeadlock=false")
+	_ = os.Setenv(EnvHealthGateNodeLabels, "node.example.com/cni-ready=true,node.example.com/dns-ready")
+	_ = os.Setenv(EnvHealthGateStartupTaints, "node.example.com/csi-not-ready")
+	_ = os.Setenv(EnvHealthGateDaemonSets, "kube-system/aws-node,kube-system/ebs-csi-node")
+	defer os.Clearenv()
+	if err := Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	expectedHealthGates := HealthGates{
+		NodeConditions: map[string]string{"NetworkingReady": "True", "KernelDeadlock": "False"},
+		NodeLabels:     map[string]string{"node.example.com/cni-ready": "true", "node.example.com/dns-ready": ""},
+		StartupTaints:  []string{"node.example.com/csi-not-ready"},
+		DaemonSets:     []string{"kube-system/aws-node", "kube-system/ebs-csi-node"},
+	}
+	if !reflect.DeepEqual(Get().HealthGates, expectedHealthGates) {
+		t.Errorf("expected health gates %+v, got %+v", expectedHealthGates, Get().HealthGates)
+	}
+}
+
+func TestInitialize_withRolloutSchedule(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	_ = os.Setenv(EnvMaintenanceWindows, "Mon-Fri 22:00-06:00 Europe/Paris,Sat-Sun 00:00-24:00")
+	_ = os.Setenv(EnvBlackoutPeriods, "2024-12-20/2025-01-05")
+	defer os.Clearenv()
+	if err := Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(Get().RolloutSchedule.MaintenanceWindows) != 2 || len(Get().RolloutSchedule.BlackoutPeriods) != 1 {
+		t.Errorf("expected 2 maintenance windows and 1 blackout period, got %+v", Get().RolloutSchedule)
+	}
+	_ = os.Setenv(EnvMaintenanceWindows, "Mon-Fri 22:00")
+	if err := Initialize(); err == nil {
+		t.Errorf("expected error for %s=Mon-Fri 22:00", EnvMaintenanceWindows)
+	}
+}
+
+func TestInitialize_withInvalidHealthGates(t *testing.T) {
+	_ = os.Setenv(EnvAutoScalingGroupNames, "foo,bar")
+	defer os.Clearenv()
+	for key, values := range map[string][]string{
+		EnvHealthGateNodeConditions: {"NetworkingReady=yes", "=True"},
+		EnvHealthGateNodeLabels:     {"=true"},
+		EnvHealthGateDaemonSets:     {"aws-node", "kube-system/", "/aws-node", "kube-system/aws-node/extra"},
+	} {
+		for _, value := range values {
+			_ = os.Setenv(key, value)
+			if err := Initialize(); err == nil {
+				t.Errorf("expected error for %s=%s", key, value)
+			}
+			_ = os.Unsetenv(key)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+// configFile is the configuration file, which can either be in YAML or in JSON.
+//
+// Every field is a pointer so that fields that are not set can be differentiated from fields explicitly set to their
+// zero value.
+type configFile struct {
+	Environment                      *string             `json:"environment,omitempty"`
+	Debug                            *bool               `json:"debug,omitempty"`
+	ClusterName                      *string             `json:"clusterName,omitempty"`
+	AutodiscoveryTags                *string             `json:"autodiscoveryTags,omitempty"`
+	AutoScalingGroupNames            []string            `json:"autoScalingGroupNames,omitempty"`
+	AwsRegion                        *string             `json:"awsRegion,omitempty"`
+	IgnoreDaemonSets                 *bool               `json:"ignoreDaemonSets,omitempty"`
+	DeleteEmptyDirData               *bool               `json:"deleteEmptyDirData,omitempty"`
+	ExecutionInterval                *int                `json:"executionInterval,omitempty"` // In seconds
+	ExecutionTimeout                 *int                `json:"executionTimeout,omitempty"`  // In seconds
+	ResyncInterval                   *int                `json:"resyncInterval,omitempty"`    // In seconds
+	Workers                          *int                `json:"workers,omitempty"`
+	MaxConcurrentDrains              *int                `json:"maxConcurrentDrains,omitempty"`
+	ShutdownTimeout                  *int                `json:"shutdownTimeout,omitempty"`           // In seconds
+	StuckTerminationThreshold        *int                `json:"stuckTerminationThreshold,omitempty"` // In seconds
+	CompleteStuckLifecycleActions    *bool               `json:"completeStuckLifecycleActions,omitempty"`
+	DrainEscalationPolicy            *string             `json:"drainEscalationPolicy,omitempty"`
+	DrainEscalationAttempts          *int                `json:"drainEscalationAttempts,omitempty"`
+	DrainEscalationTimeout           *int                `json:"drainEscalationTimeout,omitempty"` // In seconds
+	RolloutAbortTimeout              *int                `json:"rolloutAbortTimeout,omitempty"`    // In seconds
+	MaxUpdatedNonReadyNodes          *int                `json:"maxUpdatedNonReadyNodes,omitempty"`
+	MaxUpdatedNonReadyNodesRatio     *float64            `json:"maxUpdatedNonReadyNodesRatio,omitempty"`
+	UpdatedNodeMinReadyDuration      *int                `json:"updatedNodeMinReadyDuration,omitempty"` // In seconds
+	MaxFailedExecutions              *int                `json:"maxFailedExecutions,omitempty"`
+	LivenessMaxMissedIntervals       *int                `json:"livenessMaxMissedIntervals,omitempty"`
+	HealthGateNodeConditions         []string            `json:"healthGateNodeConditions,omitempty"`
+	HealthGateNodeLabels             []string            `json:"healthGateNodeLabels,omitempty"`
+	HealthGateStartupTaints          []string            `json:"healthGateStartupTaints,omitempty"`
+	HealthGateDaemonSets             []string            `json:"healthGateDaemonSets,omitempty"`
+	MaintenanceWindows               []string            `json:"maintenanceWindows,omitempty"`
+	BlackoutPeriods                  []string            `json:"blackoutPeriods,omitempty"`
+	PodTerminationGracePeriod        *int                `json:"podTerminationGracePeriod,omitempty"`
+	Metrics                          *bool               `json:"metrics,omitempty"`
+	MetricsPort                      *int                `json:"metricsPort,omitempty"`
+	AdminAPI                         *bool               `json:"adminApi,omitempty"`
+	HealthProbes                     *bool               `json:"healthProbes,omitempty"`
+	SlowMode                         *bool               `json:"slowMode,omitempty"`
+	EagerCordoning                   *bool               `json:"eagerCordoning,omitempty"`
+	ExcludeFromExternalLoadBalancers *bool               `json:"excludeFromExternalLoadBalancers,omitempty"`
+	DryRun                           *bool               `json:"dryRun,omitempty"`
+	Paused                           *bool               `json:"paused,omitempty"`
+	LeaderElection                   *bool               `json:"leaderElection,omitempty"`
+	LeaderElectionNamespace          *string             `json:"leaderElectionNamespace,omitempty"`
+	LeaderElectionLeaseName          *string             `json:"leaderElectionLeaseName,omitempty"`
+	MaxUnavailable                   *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	MaxSurge                         *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// AutoScalingGroups contains the overrides of specific ASGs, indexed by ASG name
+	AutoScalingGroups map[string]*AutoScalingGroupOverrides `json:"autoScalingGroups,omitempty"`
+}
+
+// readConfigFile reads and validates the configuration file at the given path
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration file '%s': %w", path, err)
+	}
+	return parseConfigFile(data)
+}
+
+// parseConfigFile parses and validates the content of a configuration file
+func parseConfigFile(data []byte) (*configFile, error) {
+	fileConfig := &configFile{}
+	if err := yaml.UnmarshalStrict(data, fileConfig); err != nil {
+		return nil, fmt.Errorf("invalid configuration file: %w", err)
+	}
+	for autoScalingGroupName, overrides := range fileConfig.AutoScalingGroups {
+		if overrides == nil {
+			return nil, fmt.Errorf("invalid configuration file: autoScalingGroups.%s must not be empty", autoScalingGroupName)
+		}
+		if err := overrides.validate(); err != nil {
+			return nil, fmt.Errorf("invalid configuration file: autoScalingGroups.%s: %w", autoScalingGroupName, err)
+		}
+	}
+	return fileConfig, nil
+}
+
+// toEnvironmentVariables converts the configuration file into the environment variables it is equivalent to,
+// so that both can go through the same parsing and validation.
+func (f *configFile) toEnvironmentVariables() map[string]string {
+	values := make(map[string]string)
+	setString := func(key string, value *string) {
+		if value != nil {
+			values[key] = *value
+		}
+	}
+	setBool := func(key string, value *bool) {
+		if value != nil {
+			values[key] = strconv.FormatBool(*value)
+		}
+	}
+	setInt := func(key string, value *int) {
+		if value != nil {
+			values[key] = strconv.Itoa(*value)
+		}
+	}
+	setFloat := func(key string, value *float64) {
+		if value != nil {
+			values[key] = strconv.FormatFloat(*value, 'f', -1, 64)
+		}
+	}
+	setList := func(key string, value []string) {
+		if len(value) > 0 {
+			values[key] = strings.Join(value, ",")
+		}
+	}
+	setIntOrString := func(key string, value *intstr.IntOrString) {
+		if value != nil {
+			values[key] = value.String()
+		}
+	}
+	setString(EnvEnvironment, f.Environment)
+	setBool(EnvDebug, f.Debug)
+	setString(EnvClusterName, f.ClusterName)
+	setString(EnvAutodiscoveryTags, f.AutodiscoveryTags)
+	setList(EnvAutoScalingGroupNames, f.AutoScalingGroupNames)
+	setString(EnvAwsRegion, f.AwsRegion)
+	setBool(EnvIgnoreDaemonSets, f.IgnoreDaemonSets)
+	setBool(EnvDeleteEmptyDirData, f.DeleteEmptyDirData)
+	setInt(EnvExecutionInterval, f.ExecutionInterval)
+	setInt(EnvExecutionTimeout, f.ExecutionTimeout)
+	setInt(EnvResyncInterval, f.ResyncInterval)
+	setInt(EnvWorkers, f.Workers)
+	setInt(EnvMaxConcurrentDrains, f.MaxConcurrentDrains)
+	setInt(EnvShutdownTimeout, f.ShutdownTimeout)
+	setInt(EnvStuckTerminationThreshold, f.StuckTerminationThreshold)
+	setBool(EnvCompleteStuckLifecycleActions, f.CompleteStuckLifecycleActions)
+	setString(EnvDrainEscalationPolicy, f.DrainEscalationPolicy)
+	setInt(EnvDrainEscalationAttempts, f.DrainEscalationAttempts)
+	setInt(EnvDrainEscalationTimeout, f.DrainEscalationTimeout)
+	setInt(EnvRolloutAbortTimeout, f.RolloutAbortTimeout)
+	setInt(EnvMaxUpdatedNonReadyNodes, f.MaxUpdatedNonReadyNodes)
+	setFloat(EnvMaxUpdatedNonReadyNodesRatio, f.MaxUpdatedNonReadyNodesRatio)
+	setInt(EnvUpdatedNodeMinReadyDuration, f.UpdatedNodeMinReadyDuration)
+	setInt(EnvMaxFailedExecutions, f.MaxFailedExecutions)
+	setInt(EnvLivenessMaxMissedIntervals, f.LivenessMaxMissedIntervals)
+	setList(EnvHealthGateNodeConditions, f.HealthGateNodeConditions)
+	setList(EnvHealthGateNodeLabels, f.HealthGateNodeLabels)
+	setList(EnvHealthGateStartupTaints, f.HealthGateStartupTaints)
+	setList(EnvHealthGateDaemonSets, f.HealthGateDaemonSets)
+	setList(EnvMaintenanceWindows, f.MaintenanceWindows)
+	setList(EnvBlackoutPeriods, f.BlackoutPeriods)
+	setInt(EnvPodTerminationGracePeriod, f.PodTerminationGracePeriod)
+	if f.Metrics != nil && *f.Metrics {
+		// Metrics are enabled as long as the environment variable is set, regardless of its value
+		values[EnvMetrics] = "true"
+	}
+	setInt(EnvMetricsPort, f.MetricsPort)
+	setBool(EnvAdminAPI, f.AdminAPI)
+	setBool(EnvHealthProbes, f.HealthProbes)
+	setBool(EnvSlowMode, f.SlowMode)
+	setBool(EnvEagerCordoning, f.EagerCordoning)
+	setBool(EnvExcludeFromExternalLoadBalancers, f.ExcludeFromExternalLoadBalancers)
+	setBool(EnvDryRun, f.DryRun)
+	setBool(EnvPaused, f.Paused)
+	setBool(EnvLeaderElection, f.LeaderElection)
+	setString(EnvLeaderElectionNamespace, f.LeaderElectionNamespace)
+	setString(EnvLeaderElectionLeaseName, f.LeaderElectionLeaseName)
+	setIntOrString(EnvMaxUnavailable, f.MaxUnavailable)
+	setIntOrString(EnvMaxSurge, f.MaxSurge)
+	return values
+}
+
+// WatchConfigFile polls the configuration file every interval until the context is cancelled.
+//
+// Every time the content of the configuration file changes, the configuration is reloaded and validated. If it is
+// valid, it is staged until ApplyPendingChanges is called, which is expected to happen between executions.
+// Otherwise, the change is ignored and the current configuration is kept.
+func WatchConfigFile(ctx context.Context, interval time.Duration) {
+	path := Get().ConfigFile
+	lastData, _ := os.ReadFile(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Unable to read configuration file '%s': %v", path, err)
+			continue
+		}
+		if bytes.Equal(data, lastData) {
+			continue
+		}
+		lastData = data
+		reloadedConfig, err := load()
+		if err != nil {
+			log.Printf("Ignoring change to configuration file '%s' because it is invalid: %v", path, err)
+			continue
+		}
+		log.Printf("Configuration file '%s' has changed, the new configuration will be applied before the next execution", path)
+		pendingCfg.Store(reloadedConfig)
+	}
+}
+
+// HasPendingChanges returns whether the configuration has been reloaded by WatchConfigFile since ApplyPendingChanges
+// was last called
+func HasPendingChanges() bool {
+	return pendingCfg.Load() != nil
+}
+
+// ApplyPendingChanges replaces the current configuration by the configuration that was last reloaded by
+// WatchConfigFile, if any.
+//
+// Note that settings that are only used at startup (e.g. MetricsPort, AdminAPI, HealthProbes, AwsRegion or
+// LeaderElection) require a restart to take effect.
+//
+// Returns whether the configuration was replaced
+func ApplyPendingChanges() bool {
+	reloadedConfig := pendingCfg.Swap(nil)
+	if reloadedConfig == nil {
+		return false
+	}
+	cfg.Store(reloadedConfig)
+	log.Println("Applied configuration changes")
+	return true
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return path
+}
+
+func TestInitialize_withConfigFile(t *testing.T) {
+	_ = os.Setenv(EnvConfigFile, writeConfigFile(t, `
+autoScalingGroupNames:
+  - asg-a
+  - asg-b
+ignoreDaemonSets: false
+executionInterval: 60
+podTerminationGracePeriod: 120
+maxUnavailable: 25%
+maxSurge: 2
+autoScalingGroups:
+  asg-b:
+    podTerminationGracePeriod: 3600
+    slowMode: true
+    paused: true
+    blackoutPeriods:
+      - 2024-12-20/2025-01-05
+    maxUnavailable: 1
+    maxUpdatedNonReadyNodesRatio: 0.5
+    updatedNodeMinReadyDuration: 600
+    healthGateStartupTaints: []
+    healthGateDaemonSets:
+      - kube-system/nvidia-device-plugin
+healthGateStartupTaints:
+  - node.example.com/csi-not-ready
+healthGateDaemonSets:
+  - kube-system/aws-node
+`))
+	defer os.Clearenv()
+	if err := Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	config := Get()
+	if len(config.AutoScalingGroupNames) != 2 {
+		t.Error("expected 2 ASGs from the configuration file, got", len(config.AutoScalingGroupNames))
+	}
+	if config.IgnoreDaemonSets {
+		t.Error("IgnoreDaemonSets should be false")
+	}
+	if !config.DeleteEmptyDirData {
+		t.Error("DeleteEmptyDirData should've defaulted to true")
+	}
+	if config.ExecutionInterval != time.Minute {
+		t.Error("ExecutionInterval should be 60 seconds, got", config.ExecutionInterval)
+	}
+	if config.MaxUnavailable != intstr.FromString("25%") || config.MaxSurge != intstr.FromInt32(2) {
+		t.Error("MaxUnavailable and MaxSurge should've been set from the configuration file")
+	}
+	asgAConfig := config.ForAutoScalingGroup("asg-a", nil)
+	if asgAConfig.PodTerminationGracePeriod != 120 || asgAConfig.SlowMode || asgAConfig.Paused {
+		t.Error("asg-a has no overrides, so it should've inherited the global configuration")
+	}
+	asgBConfig := config.ForAutoScalingGroup("asg-b", nil)
+	if asgBConfig.PodTerminationGracePeriod != 3600 || !asgBConfig.SlowMode || !asgBConfig.Paused || asgBConfig.MaxUnavailable != intstr.FromInt32(1) || len(asgBConfig.RolloutSchedule.BlackoutPeriods) != 1 {
+		t.Error("asg-b should've had its overrides from the configuration file applied")
+	}
+	if asgBConfig.MaxUpdatedNonReadyNodesRatio != 0.5 || asgBConfig.UpdatedNodeMinReadyDuration != 10*time.Minute {
+		t.Error("asg-b should've had its readiness thresholds overridden by the configuration file")
+	}
+	if len(asgAConfig.HealthGates.StartupTaints) != 1 || len(asgAConfig.HealthGates.DaemonSets) != 1 || asgAConfig.HealthGates.DaemonSets[0] != "kube-system/aws-node" {
+		t.Error("asg-a has no overrides, so it should've inherited the global health gates, got", asgAConfig.HealthGates)
+	}
+	if len(asgBConfig.HealthGates.StartupTaints) != 0 || len(asgBConfig.HealthGates.DaemonSets) != 1 || asgBConfig.HealthGates.DaemonSets[0] != "kube-system/nvidia-device-plugin" {
+		t.Error("asg-b's health gates should've replaced the global health gates, got", asgBConfig.HealthGates)
+	}
+	if asgBConfig.MaxSurge != intstr.FromInt32(2) {
+		t.Error("asg-b doesn't override maxSurge, so it should've been inherited from the global configuration")
+	}
+	asgBConfig = config.ForAutoScalingGroup("asg-b", map[string]string{TagPodTerminationGracePeriod: "900"})
+	if asgBConfig.PodTerminationGracePeriod != 900 {
+		t.Error("the ASG's tags should take precedence over the configuration file, got", asgBConfig.PodTerminationGracePeriod)
+	}
+}
+
+func TestInitialize_withConfigFileAndEnvironmentVariables(t *testing.T) {
+	_ = os.Setenv(EnvConfigFile, writeConfigFile(t, `{"clusterName": "from-file", "slowMode": true, "podTerminationGracePeriod": 120}`))
+	_ = os.Setenv(EnvAutoScalingGroupNames, "asg-a")
+	_ = os.Setenv(EnvPodTerminationGracePeriod, "30")
+	defer os.Clearenv()
+	if err := Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	config := Get()
+	if len(config.AutodiscoveryTags) != 0 || len(config.AutoScalingGroupNames) != 1 {
+		t.Error("the ASGs from the environment variables should've taken precedence over the configuration file")
+	}
+	if config.PodTerminationGracePeriod != 30 {
+		t.Error("environment variables should take precedence over the configuration file, got", config.PodTerminationGracePeriod)
+	}
+	if !config.SlowMode {
+		t.Error("SlowMode isn't set through environment variables, so it should've been set from the configuration file")
+	}
+}
+
+func TestInitialize_withInvalidConfigFile(t *testing.T) {
+	scenarios := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown-field", content: "autoScalingGroupNames: [asg-a]\nunknownField: true"},
+		{name: "wrong-type", content: "autoScalingGroupNames: [asg-a]\nexecutionInterval: soon"},
+		{name: "invalid-max-unavailable", content: "autoScalingGroupNames: [asg-a]\nmaxUnavailable: 150%"},
+		{name: "invalid-asg-max-surge", content: "autoScalingGroupNames: [asg-a]\nautoScalingGroups:\n  asg-a:\n    maxSurge: 0"},
+		{name: "invalid-asg-drain-escalation-policy", content: "autoScalingGroupNames: [asg-a]\nautoScalingGroups:\n  asg-a:\n    drainEscalationPolicy: retry"},
+		{name: "invalid-asg-max-updated-non-ready-nodes-ratio", content: "autoScalingGroupNames: [asg-a]\nautoScalingGroups:\n  asg-a:\n    maxUpdatedNonReadyNodesRatio: -1"},
+		{name: "invalid-asg-health-gate-daemon-sets", content: "autoScalingGroupNames: [asg-a]\nautoScalingGroups:\n  asg-a:\n    healthGateDaemonSets: [aws-node]"},
+		{name: "invalid-asg-maintenance-windows", content: "autoScalingGroupNames: [asg-a]\nautoScalingGroups:\n  asg-a:\n    maintenanceWindows: [Mon-Fri 22:00]"},
+		{name: "missing-asgs", content: "slowMode: true"},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			_ = os.Setenv(EnvConfigFile, writeConfigFile(t, scenario.content))
+			defer os.Clearenv()
+			if err := Initialize(); err == nil {
+				t.Error("expected error because the configuration file is invalid")
+			}
+		})
+	}
+	_ = os.Setenv(EnvConfigFile, filepath.Join(t.TempDir(), "does-not-exist.yaml"))
+	defer os.Clearenv()
+	if err := Initialize(); err == nil {
+		t.Error("expected error because the configuration file doesn't exist")
+	}
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "autoScalingGroupNames: [asg-a]\npodTerminationGracePeriod: 120")
+	_ = os.Setenv(EnvConfigFile, path)
+	defer os.Clearenv()
+	if err := Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	defer Set(nil, true, true, false, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchConfigFile(ctx, 10*time.Millisecond)
+	// An invalid configuration should be ignored
+	_ = os.WriteFile(path, []byte("autoScalingGroupNames: [asg-a]\npodTerminationGracePeriod: forever"), 0644)
+	time.Sleep(100 * time.Millisecond)
+	if ApplyPendingChanges() {
+		t.Error("the configuration file is invalid, so there shouldn't have been any changes to apply")
+	}
+	_ = os.WriteFile(path, []byte("autoScalingGroupNames: [asg-a]\npodTerminationGracePeriod: 600"), 0644)
+	time.Sleep(100 * time.Millisecond)
+	if Get().PodTerminationGracePeriod != 120 {
+		t.Error("changes shouldn't be applied until ApplyPendingChanges is called")
+	}
+	if !HasPendingChanges() {
+		t.Error("the new configuration should be pending")
+	}
+	if !ApplyPendingChanges() {
+		t.Fatal("expected the new configuration to be applied")
+	}
+	if Get().PodTerminationGracePeriod != 600 {
+		t.Error("PodTerminationGracePeriod should've been reloaded from the configuration file, got", Get().PodTerminationGracePeriod)
+	}
+	if ApplyPendingChanges() {
+		t.Error("there shouldn't be any changes left to apply")
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HealthGates are the checks that an updated node must pass, on top of being ready, before it is considered as
+// ready
+type HealthGates struct {
+	// NodeConditions are the node conditions that must have a given status, indexed by condition type
+	NodeConditions map[string]string
+	// NodeLabels are the labels that the node must have, indexed by key. An empty value matches any value.
+	NodeLabels map[string]string
+	// StartupTaints are the keys of the taints that the node must no longer have
+	StartupTaints []string
+	// DaemonSets are the DaemonSets, formatted as <namespace>/<name>, that must have a running and ready pod on the
+	// node
+	DaemonSets []string
+}
+
+// parseNodeConditions parses node conditions formatted as <type>=<status>, where the status is either True, False
+// or Unknown and defaults to True if omitted
+func parseNodeConditions(values []string) (map[string]string, error) {
+	nodeConditions := make(map[string]string)
+	for _, value := range splitList(values) {
+		conditionType, status, hasStatus := strings.Cut(value, "=")
+		conditionType, status = strings.TrimSpace(conditionType), strings.TrimSpace(status)
+		if !hasStatus {
+			status = "True"
+		}
+		if len(conditionType) == 0 {
+			return nil, fmt.Errorf("invalid node condition '%s': type must not be empty", value)
+		}
+		switch strings.ToLower(status) {
+		case "true":
+			status = "True"
+		case "false":
+			status = "False"
+		case "unknown":
+			status = "Unknown"
+		default:
+			return nil, fmt.Errorf("invalid node condition '%s': status must be True, False or Unknown", value)
+		}
+		nodeConditions[conditionType] = status
+	}
+	return nodeConditions, nil
+}
+
+// parseNodeLabels parses node labels formatted as <key>=<value>, or as <key> if any value is acceptable
+func parseNodeLabels(values []string) (map[string]string, error) {
+	nodeLabels := make(map[string]string)
+	for _, value := range splitList(values) {
+		key, labelValue, _ := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if len(key) == 0 {
+			return nil, fmt.Errorf("invalid node label '%s': key must not be empty", value)
+		}
+		nodeLabels[key] = strings.TrimSpace(labelValue)
+	}
+	return nodeLabels, nil
+}
+
+// parseDaemonSets parses DaemonSets formatted as <namespace>/<name>
+func parseDaemonSets(values []string) ([]string, error) {
+	daemonSets := splitList(values)
+	for _, daemonSet := range daemonSets {
+		namespace, name, ok := strings.Cut(daemonSet, "/")
+		if !ok || len(namespace) == 0 || len(name) == 0 || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid DaemonSet '%s': must be formatted as <namespace>/<name>", daemonSet)
+		}
+	}
+	return daemonSets, nil
+}
+
+// splitList splits every value by comma and returns the non-empty elements
+func splitList(values []string) []string {
+	var elements []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.TrimSpace(element); len(element) > 0 {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements
+}
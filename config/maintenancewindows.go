@@ -0,0 +1,231 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// The application runs in a scratch container, which doesn't have the time zone database that time zones of
+	// maintenance windows are loaded from
+	_ "time/tzdata"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// MaintenanceWindow is a recurring period during which the rollout of new nodes can be started, formatted as
+// <days> <start>-<end> [<time zone>] (e.g. Mon-Fri 22:00-06:00 Europe/Paris)
+type MaintenanceWindow struct {
+	// Days are the days of the week on which the window starts
+	Days [7]bool
+	// Start is the time of the day at which the window starts, as a duration since midnight
+	Start time.Duration
+	// End is the time of the day at which the window ends, as a duration since midnight. If End isn't after Start,
+	// the window ends on the next day.
+	End time.Duration
+	// Location is the time zone in which Days, Start and End are expressed
+	Location *time.Location
+
+	value string
+}
+
+// Contains checks whether the given time is part of the maintenance window
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	t = t.In(w.Location)
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start < w.End {
+		return w.Days[t.Weekday()] && timeOfDay >= w.Start && timeOfDay < w.End
+	}
+	// The window ends on the next day, so it may have started on the previous day
+	return (w.Days[t.Weekday()] && timeOfDay >= w.Start) || (w.Days[(t.Weekday()+6)%7] && timeOfDay < w.End)
+}
+
+func (w MaintenanceWindow) String() string {
+	return w.value
+}
+
+// BlackoutPeriod is a period during which the rollout of new nodes cannot be started, formatted as <start>/<end>,
+// where start and end are either RFC3339 timestamps or dates formatted as YYYY-MM-DD in UTC, in which case the end
+// date is inclusive (e.g. 2024-12-20/2025-01-05)
+type BlackoutPeriod struct {
+	Start time.Time
+	End   time.Time
+
+	value string
+}
+
+// Contains checks whether the given time is part of the blackout period
+func (p BlackoutPeriod) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+func (p BlackoutPeriod) String() string {
+	return p.value
+}
+
+// RolloutSchedule restricts when the rollout of new nodes can be started
+type RolloutSchedule struct {
+	// MaintenanceWindows are the periods during which the rollout of new nodes can be started. If empty, the rollout
+	// of new nodes can be started at any time.
+	MaintenanceWindows []MaintenanceWindow
+	// BlackoutPeriods are the periods during which the rollout of new nodes cannot be started, even during a
+	// maintenance window
+	BlackoutPeriods []BlackoutPeriod
+}
+
+// Check checks whether the rollout of a new node can be started at the given time, that is, whether the given time is
+// part of one of the maintenance windows, if any, and of none of the blackout periods.
+//
+// Returns an error describing why the rollout of a new node cannot be started, if applicable
+func (s RolloutSchedule) Check(now time.Time) error {
+	for _, blackoutPeriod := range s.BlackoutPeriods {
+		if blackoutPeriod.Contains(now) {
+			return fmt.Errorf("blackout period '%s' is in effect", blackoutPeriod)
+		}
+	}
+	if len(s.MaintenanceWindows) == 0 {
+		return nil
+	}
+	for _, maintenanceWindow := range s.MaintenanceWindows {
+		if maintenanceWindow.Contains(now) {
+			return nil
+		}
+	}
+	return fmt.Errorf("outside of maintenance windows")
+}
+
+// parseMaintenanceWindows parses maintenance windows formatted as <days> <start>-<end> [<time zone>], where days is
+// either *, a day of the week (e.g. Mon) or a range of days of the week (e.g. Mon-Fri), start and end are formatted
+// as HH:MM, and the time zone is an IANA time zone that defaults to UTC
+func parseMaintenanceWindows(values []string) ([]MaintenanceWindow, error) {
+	var maintenanceWindows []MaintenanceWindow
+	for _, value := range splitList(values) {
+		fields := strings.Fields(value)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("invalid maintenance window '%s': must be formatted as <days> <start>-<end> [<time zone>]", value)
+		}
+		maintenanceWindow := MaintenanceWindow{Location: time.UTC, value: value}
+		var err error
+		if maintenanceWindow.Days, err = parseDays(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window '%s': %w", value, err)
+		}
+		start, end, ok := strings.Cut(fields[1], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid maintenance window '%s': time range must be formatted as <start>-<end>", value)
+		}
+		if maintenanceWindow.Start, err = parseTimeOfDay(start); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window '%s': %w", value, err)
+		}
+		if maintenanceWindow.End, err = parseTimeOfDay(end); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window '%s': %w", value, err)
+		}
+		if len(fields) == 3 {
+			if maintenanceWindow.Location, err = time.LoadLocation(fields[2]); err != nil {
+				return nil, fmt.Errorf("invalid maintenance window '%s': unknown time zone '%s'", value, fields[2])
+			}
+		}
+		maintenanceWindows = append(maintenanceWindows, maintenanceWindow)
+	}
+	return maintenanceWindows, nil
+}
+
+// parseDays parses either *, a day of the week or a range of days of the week, which may wrap around the end of the
+// week (e.g. Fri-Mon)
+func parseDays(value string) ([7]bool, error) {
+	var days [7]bool
+	if value == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	first, last, isRange := strings.Cut(value, "-")
+	if !isRange {
+		last = first
+	}
+	firstDay, ok := weekdays[strings.ToLower(first)]
+	if !ok {
+		return days, fmt.Errorf("unknown day of the week '%s'", first)
+	}
+	lastDay, ok := weekdays[strings.ToLower(last)]
+	if !ok {
+		return days, fmt.Errorf("unknown day of the week '%s'", last)
+	}
+	for day := firstDay; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == lastDay {
+			break
+		}
+	}
+	return days, nil
+}
+
+// parseTimeOfDay parses a time of the day formatted as HH:MM into a duration since midnight. 24:00 is accepted as
+// the end of the day.
+func parseTimeOfDay(value string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time '%s': must be formatted as HH:MM", value)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s': must be formatted as HH:MM", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || len(minutes) != 2 {
+		return 0, fmt.Errorf("invalid time '%s': must be formatted as HH:MM", value)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time '%s': must be between 00:00 and 24:00", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// parseBlackoutPeriods parses blackout periods formatted as <start>/<end>, where start and end are either RFC3339
+// timestamps or dates formatted as YYYY-MM-DD in UTC
+func parseBlackoutPeriods(values []string) ([]BlackoutPeriod, error) {
+	var blackoutPeriods []BlackoutPeriod
+	for _, value := range splitList(values) {
+		start, end, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid blackout period '%s': must be formatted as <start>/<end>", value)
+		}
+		blackoutPeriod := BlackoutPeriod{value: value}
+		var err error
+		if blackoutPeriod.Start, err = parseTimestampOrDate(strings.TrimSpace(start), false); err != nil {
+			return nil, fmt.Errorf("invalid blackout period '%s': %w", value, err)
+		}
+		if blackoutPeriod.End, err = parseTimestampOrDate(strings.TrimSpace(end), true); err != nil {
+			return nil, fmt.Errorf("invalid blackout period '%s': %w", value, err)
+		}
+		if !blackoutPeriod.End.After(blackoutPeriod.Start) {
+			return nil, fmt.Errorf("invalid blackout period '%s': end must be after start", value)
+		}
+		blackoutPeriods = append(blackoutPeriods, blackoutPeriod)
+	}
+	return blackoutPeriods, nil
+}
+
+// parseTimestampOrDate parses either an RFC3339 timestamp or a date formatted as YYYY-MM-DD in UTC. If isEnd is true,
+// a date is parsed as the end of that day rather than its start.
+func parseTimestampOrDate(value string, isEnd bool) (time.Time, error) {
+	if timestamp, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamp, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is neither an RFC3339 timestamp nor a date formatted as YYYY-MM-DD", value)
+	}
+	if isEnd {
+		return date.AddDate(0, 0, 1), nil
+	}
+	return date, nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRolloutSchedule_Check(t *testing.T) {
+	maintenanceWindows, err := parseMaintenanceWindows([]string{"Mon-Fri 22:00-06:00 Europe/Paris, sat-sun 00:00-24:00"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	blackoutPeriods, err := parseBlackoutPeriods([]string{"2024-12-20/2025-01-05", "2024-06-05T23:00:00Z/2024-06-06T01:00:00Z"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	scenarios := []struct {
+		name            string
+		rolloutSchedule RolloutSchedule
+		now             string
+		expectedError   bool
+	}{
+		{name: "no-schedule", now: "2024-06-03T12:00:00Z"},
+		{name: "during-business-hours", rolloutSchedule: RolloutSchedule{MaintenanceWindows: maintenanceWindows}, now: "2024-06-03T12:00:00Z", expectedError: true},
+		{name: "monday-night-in-time-zone", rolloutSchedule: RolloutSchedule{MaintenanceWindows: maintenanceWindows}, now: "2024-06-03T20:30:00Z"},
+		{name: "before-monday-night-in-time-zone", rolloutSchedule: RolloutSchedule{MaintenanceWindows: maintenanceWindows}, now: "2024-06-03T19:30:00Z", expectedError: true},
+		{name: "tuesday-morning-in-time-zone", rolloutSchedule: RolloutSchedule{MaintenanceWindows: maintenanceWindows}, now: "2024-06-04T03:30:00Z"},
+		{name: "monday-morning-after-friday-night", rolloutSchedule: RolloutSchedule{MaintenanceWindows: maintenanceWindows}, now: "2024-06-03T03:30:00Z", expectedError: true},
+		{name: "weekend", rolloutSchedule: RolloutSchedule{MaintenanceWindows: maintenanceWindows}, now: "2024-06-08T12:00:00Z"},
+		{name: "during-blackout-period", rolloutSchedule: RolloutSchedule{BlackoutPeriods: blackoutPeriods}, now: "2024-12-24T12:00:00Z", expectedError: true},
+		{name: "last-day-of-blackout-period", rolloutSchedule: RolloutSchedule{BlackoutPeriods: blackoutPeriods}, now: "2025-01-05T23:59:00Z", expectedError: true},
+		{name: "after-blackout-period", rolloutSchedule: RolloutSchedule{BlackoutPeriods: blackoutPeriods}, now: "2025-01-06T00:00:00Z"},
+		{name: "blackout-period-during-maintenance-window", rolloutSchedule: RolloutSchedule{MaintenanceWindows: maintenanceWindows, BlackoutPeriods: blackoutPeriods}, now: "2024-06-05T23:30:00Z", expectedError: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, scenario.now)
+			err := scenario.rolloutSchedule.Check(now)
+			if scenario.expectedError && err == nil {
+				t.Error("expected an error, got none")
+			}
+			if !scenario.expectedError && err != nil {
+				t.Error("unexpected error:", err)
+			}
+		})
+	}
+}
+
+func TestParseMaintenanceWindowsAndBlackoutPeriods_withInvalidValues(t *testing.T) {
+	for _, value := range []string{"Mon-Fri", "Mon-Fry 22:00-06:00", "Mon 22:00", "Mon 22:00-25:00", "Mon 22:0-06:00", "Mon 22:00-06:00 Mars/Olympus_Mons", "Mon 22:00-06:00 UTC extra"} {
+		if _, err := parseMaintenanceWindows([]string{value}); err == nil {
+			t.Errorf("expected error for maintenance window '%s'", value)
+		}
+	}
+	for _, value := range []string{"2024-12-20", "2024-12-20/tomorrow", "2025-01-05/2024-12-20", "2024-12-20T10:00:00Z/2024-12-20T10:00:00Z"} {
+		if _, err := parseBlackoutPeriods([]string{value}); err == nil {
+			t.Errorf("expected error for blackout period '%s'", value)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloud"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8s"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Controller reconciles AutoScalingGroups from a work queue, with up to Workers ASGs being reconciled at the same
+// time. The work queue guarantees that a given ASG is never reconciled by more than one worker at once.
+//
+// An ASG is enqueued:
+//   - every ResyncInterval, when the ASGs are (re)discovered
+//   - ExecutionInterval after being reconciled, for as long as it has outdated instances
+//   - after a backoff based on ExecutionInterval, if its reconciliation failed
+//   - whenever one of its nodes is added or deleted, becomes ready or not ready, is cordoned or uncordoned, or has
+//     its rolling update annotations modified
+//
+// Once a node has been terminated while SlowMode was enabled, no ASG is reconciled until ExecutionInterval has
+// elapsed, as ASGs that are enqueued in the meantime are put back in the work queue until then. Reconciliations
+// that are already in progress are completed.
+type Controller struct {
+	client             k8s.ClientAPI
+	ec2Service         ec2iface.EC2API
+	autoScalingService autoscalingiface.AutoScalingAPI
+
+	queue workqueue.TypedRateLimitingInterface[string]
+
+	mutex sync.RWMutex
+	// autoScalingGroupNames are the names of the ASGs discovered during the last resync
+	autoScalingGroupNames map[string]bool
+	// autoScalingGroupNameByInstanceID is used to find the ASG that a node belongs to
+	autoScalingGroupNameByInstanceID map[string]string
+	// slowedDownUntil is when ASGs can be reconciled again after a node was terminated while SlowMode was enabled.
+	// Until then, no ASG is reconciled.
+	slowedDownUntil time.Time
+	// failedReconciliationCounters is the number of consecutive failed reconciliations of each ASG
+	failedReconciliationCounters map[string]int
+	// paused is whether the rolling update of every ASG has been paused through the admin API
+	paused bool
+	// pausedAutoScalingGroups are the ASGs whose rolling update has been paused through the admin API
+	pausedAutoScalingGroups map[string]bool
+	// resyncedAt is when the last resync completed, or when the controller started running if no resync has
+	// completed yet. It is zero if the controller isn't running.
+	resyncedAt time.Time
+	// reconcilingSince is when the reconciliation currently in progress of each ASG started
+	reconcilingSince map[string]time.Time
+	// reconciliationDone is signaled every time a reconciliation completes, so that the workers waiting for pending
+	// configuration changes to be applied can start reconciling again
+	reconciliationDone *sync.Cond
+
+	resyncFailedCounter int
+}
+
+// ErrUnrecoverable is returned when the resync kept failing with errors that retrying cannot resolve, in which case
+// the application should exit
+var ErrUnrecoverable = errors.New("unrecoverable error")
+
+// NewController creates a new Controller
+func NewController(client k8s.ClientAPI, ec2Service ec2iface.EC2API, autoScalingService autoscalingiface.AutoScalingAPI) *Controller {
+	c := &Controller{
+		client:             client,
+		ec2Service:         ec2Service,
+		autoScalingService: autoScalingService,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(workqueue.DefaultTypedControllerRateLimiter[string](), workqueue.TypedRateLimitingQueueConfig[string]{
+			Name: "autoscalinggroups",
+		}),
+		autoScalingGroupNames:            make(map[string]bool),
+		autoScalingGroupNameByInstanceID: make(map[string]string),
+		failedReconciliationCounters:     make(map[string]int),
+		pausedAutoScalingGroups:          make(map[string]bool),
+		reconcilingSince:                 make(map[string]time.Time),
+	}
+	c.reconciliationDone = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Run resyncs the ASGs every ResyncInterval and reconciles the ASGs from the work queue with Workers workers until
+// the context is cancelled
+//
+// Note that the number of workers is only read when Run is called.
+func (c *Controller) Run(ctx context.Context) {
+	c.mutex.Lock()
+	c.resyncedAt = time.Now()
+	c.mutex.Unlock()
+	go func() {
+		<-ctx.Done()
+		c.queue.ShutDown()
+	}()
+	go c.resyncPeriodically(ctx)
+	wg := sync.WaitGroup{}
+	for i := 0; i < config.Get().Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c.processNextWorkItem(ctx) {
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// resyncPeriodically resyncs the ASGs immediately, and then every ResyncInterval until the context is cancelled
+//
+// A failed resync is retried after a backoff instead, and the application exits if the resync kept failing with
+// unrecoverable errors.
+func (c *Controller) resyncPeriodically(ctx context.Context) {
+	for {
+		err := c.resync(ctx)
+		if err != nil {
+			log.Printf("Error during resync (%s): %s", cloud.ClassifyError(err), err.Error())
+		}
+		if err := c.handleResyncResult(err); err != nil {
+			log.Fatalf("Exiting: %s", err.Error())
+		}
+		c.mutex.Lock()
+		c.resyncedAt = time.Now()
+		interval := config.Get().ResyncInterval
+		if c.resyncFailedCounter > 0 {
+			interval = getBackoff(c.resyncFailedCounter)
+			log.Printf("Resync failed %d consecutive times, retrying in %s", c.resyncFailedCounter, interval.Round(time.Second))
+		}
+		c.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// resync discovers the ASGs to manage and enqueues every one of them
+func (c *Controller) resync(ctx context.Context) error {
+	autoScalingGroups, err := describeAutoScalingGroups(ctx, c.autoScalingService)
+	if err != nil {
+		return err
+	}
+	metrics.Server.NodeGroups.WithLabelValues().Set(float64(len(autoScalingGroups)))
+	autoScalingGroupNames := make(map[string]bool, len(autoScalingGroups))
+	autoScalingGroupNameByInstanceID := make(map[string]string)
+	for _, autoScalingGroup := range autoScalingGroups {
+		autoScalingGroupName := aws.StringValue(autoScalingGroup.AutoScalingGroupName)
+		autoScalingGroupNames[autoScalingGroupName] = true
+		for _, instance := range autoScalingGroup.Instances {
+			autoScalingGroupNameByInstanceID[aws.StringValue(instance.InstanceId)] = autoScalingGroupName
+		}
+	}
+	c.mutex.Lock()
+	c.autoScalingGroupNames = autoScalingGroupNames
+	c.autoScalingGroupNameByInstanceID = autoScalingGroupNameByInstanceID
+	c.mutex.Unlock()
+	for autoScalingGroupName := range autoScalingGroupNames {
+		c.queue.Add(autoScalingGroupName)
+	}
+	return nil
+}
+
+// processNextWorkItem reconciles the next ASG from the work queue
+//
+// Returns false if the work queue has been shut down
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	autoScalingGroupName, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(autoScalingGroupName)
+	c.mutex.RLock()
+	slowedDownFor := time.Until(c.slowedDownUntil)
+	c.mutex.RUnlock()
+	if slowedDownFor > 0 {
+		// A node was terminated while SlowMode was enabled, so no ASG is reconciled until the next execution
+		c.queue.AddAfter(autoScalingGroupName, slowedDownFor)
+		return true
+	}
+	c.mutex.Lock()
+	// Changes to the configuration file are only applied while no worker is reconciling an ASG, so that the
+	// configuration never changes in the middle of a reconciliation. While changes are pending, no new reconciliation
+	// is started until those in progress have completed, so that changes are applied even if ASGs are always being
+	// reconciled.
+	for config.HasPendingChanges() && len(c.reconcilingSince) > 0 {
+		c.reconciliationDone.Wait()
+	}
+	config.ApplyPendingChanges()
+	start := time.Now()
+	c.reconcilingSince[autoScalingGroupName] = start
+	c.mutex.Unlock()
+	result, err := c.reconcile(ctx, autoScalingGroupName)
+	c.mutex.Lock()
+	delete(c.reconcilingSince, autoScalingGroupName)
+	c.mutex.Unlock()
+	c.reconciliationDone.Broadcast()
+	if result.SlowedDown {
+		// Even if rolling out another node failed, a node was terminated
+		c.mutex.Lock()
+		c.slowedDownUntil = time.Now().Add(config.Get().ExecutionInterval)
+		c.mutex.Unlock()
+	}
+	if failures := c.handleReconciliationResult(autoScalingGroupName, err); err != nil {
+		backoff := getBackoff(failures)
+		log.Printf("[%s] Error during execution (%s), retrying in %s: %s", autoScalingGroupName, cloud.ClassifyError(err), backoff.Round(time.Second), err.Error())
+		c.queue.AddAfter(autoScalingGroupName, backoff)
+		return true
+	}
+	c.queue.Forget(autoScalingGroupName)
+	if result.Requeue {
+		log.Printf("[%s] Execution took %dms, reconciling again in %s", autoScalingGroupName, time.Since(start).Milliseconds(), config.Get().ExecutionInterval)
+		c.queue.AddAfter(autoScalingGroupName, config.Get().ExecutionInterval)
+	}
+	return true
+}
+
+// reconcile describes the given ASG and rolls out its outdated instances
+//
+// Returns ErrTimedOut if the reconciliation lasts for longer than ExecutionTimeout, in which case every in-flight step
+// is cancelled. Otherwise, returns the errors that prevented the rollout of the ASG from progressing, if any.
+func (c *Controller) reconcile(ctx context.Context, autoScalingGroupName string) (ReconcileResult, error) {
+	c.mutex.RLock()
+	isManaged := c.autoScalingGroupNames[autoScalingGroupName]
+	c.mutex.RUnlock()
+	if !isManaged {
+		// The ASG is no longer part of the ASGs discovered during the last resync
+		return ReconcileResult{}, nil
+	}
+	if c.IsPaused(autoScalingGroupName) {
+		log.Printf("[%s] Skipping because rolling update is paused through the admin API", autoScalingGroupName)
+		metrics.Server.PausedNodeGroups.WithLabelValues(autoScalingGroupName).Set(1)
+		return ReconcileResult{Requeue: true}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.Get().ExecutionTimeout)
+	defer cancel()
+	autoScalingGroups, err := cloud.DescribeAutoScalingGroupsByNames(ctx, c.autoScalingService, []string{autoScalingGroupName})
+	if err != nil {
+		return ReconcileResult{}, fmt.Errorf("unable to describe AutoScalingGroup: %w", err)
+	}
+	if len(autoScalingGroups) == 0 {
+		log.Printf("[%s] Skipping because AutoScalingGroup no longer exists", autoScalingGroupName)
+		return ReconcileResult{}, nil
+	}
+	autoScalingGroup := autoScalingGroups[0]
+	c.mutex.Lock()
+	for _, instance := range autoScalingGroup.Instances {
+		c.autoScalingGroupNameByInstanceID[aws.StringValue(instance.InstanceId)] = autoScalingGroupName
+	}
+	c.mutex.Unlock()
+	plan := NewPlan(config.Get().DryRun)
+	result, err := ReconcileAutoScalingGroup(ctx, c.client, c.ec2Service, c.autoScalingService, autoScalingGroup, plan)
+	if plan.DryRun {
+		plan.Log()
+	}
+	if err := contextError(ctx); err != nil {
+		return ReconcileResult{}, err
+	}
+	return result, err
+}
+
+// Pause pauses the rolling update of the given ASG, or of every ASG if the given ASG name is empty
+//
+// Executions that are already in progress are not interrupted, but no new execution is started until the rolling
+// update is resumed.
+func (c *Controller) Pause(autoScalingGroupName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(autoScalingGroupName) == 0 {
+		c.paused = true
+	} else {
+		c.pausedAutoScalingGroups[autoScalingGroupName] = true
+	}
+}
+
+// Resume resumes the rolling update of the given ASG, or of every ASG if the given ASG name is empty, and enqueues
+// the ASGs that are no longer paused
+//
+// Note that resuming every ASG doesn't resume the ASGs that were paused individually, and vice versa.
+func (c *Controller) Resume(autoScalingGroupName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(autoScalingGroupName) == 0 {
+		c.paused = false
+		for name := range c.autoScalingGroupNames {
+			if !c.pausedAutoScalingGroups[name] {
+				c.queue.Add(name)
+			}
+		}
+	} else {
+		delete(c.pausedAutoScalingGroups, autoScalingGroupName)
+		if !c.paused && c.autoScalingGroupNames[autoScalingGroupName] {
+			c.queue.Add(autoScalingGroupName)
+		}
+	}
+}
+
+// IsPaused checks whether the rolling update of the given ASG is paused, either individually or because the rolling
+// update of every ASG is paused
+func (c *Controller) IsPaused(autoScalingGroupName string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.paused || c.pausedAutoScalingGroups[autoScalingGroupName]
+}
+
+// AutoScalingGroupNames returns the sorted names of the ASGs discovered during the last resync
+func (c *Controller) AutoScalingGroupNames() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	autoScalingGroupNames := make([]string, 0, len(c.autoScalingGroupNames))
+	for autoScalingGroupName := range c.autoScalingGroupNames {
+		autoScalingGroupNames = append(autoScalingGroupNames, autoScalingGroupName)
+	}
+	sort.Strings(autoScalingGroupNames)
+	return autoScalingGroupNames
+}
+
+// CheckLiveness checks whether the controller is making progress, that is, whether the ASGs have been resynced within
+// the last LivenessMaxMissedIntervals resync intervals, and whether no reconciliation has been in progress for longer
+// than ExecutionTimeout plus LivenessMaxMissedIntervals execution intervals, which would mean that it is wedged
+// (e.g. in a drain that ignores cancellation).
+//
+// A controller that isn't running (e.g. because this replica isn't the leader) is always considered as live.
+func (c *Controller) CheckLiveness() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.resyncedAt.IsZero() {
+		return nil
+	}
+	maxMissedIntervals := time.Duration(config.Get().LivenessMaxMissedIntervals)
+	if sinceResync, threshold := time.Since(c.resyncedAt), maxMissedIntervals*config.Get().ResyncInterval; sinceResync > threshold {
+		return fmt.Errorf("no resync has completed for %s, which is longer than %s", sinceResync.Round(time.Second), threshold)
+	}
+	threshold := config.Get().ExecutionTimeout + maxMissedIntervals*config.Get().ExecutionInterval
+	for autoScalingGroupName, since := range c.reconcilingSince {
+		if duration := time.Since(since); duration > threshold {
+			return fmt.Errorf("reconciliation of %s has been in progress for %s, which is longer than %s", autoScalingGroupName, duration.Round(time.Second), threshold)
+		}
+	}
+	return nil
+}
+
+// handleResyncResult keeps track of the number of consecutive failed resyncs, past MaxFailedExecutions of which the
+// controller is degraded
+//
+// Returns ErrUnrecoverable if there have been more than MaxFailedExecutions consecutive failed resyncs, and the
+// last one failed because of an unrecoverable error (e.g. missing permissions), since retrying cannot resolve it.
+// Other errors, such as throttling, are retried indefinitely, because they are expected to be transient.
+func (c *Controller) handleResyncResult(err error) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.updateDegradedMetric()
+	if err != nil {
+		metrics.Server.Errors.Inc()
+		c.resyncFailedCounter++
+		if c.resyncFailedCounter > config.Get().MaxFailedExecutions && cloud.IsUnrecoverableError(err) {
+			return fmt.Errorf("%w: resync failed %d times: %v", ErrUnrecoverable, c.resyncFailedCounter, err)
+		}
+		if c.resyncFailedCounter == config.Get().MaxFailedExecutions {
+			log.Printf("Resync failed %d consecutive times, handler is now degraded", c.resyncFailedCounter)
+		}
+	} else if c.resyncFailedCounter > 0 {
+		log.Printf("Resync was successful after %d failed attempts, resetting counter to 0", c.resyncFailedCounter)
+		c.resyncFailedCounter = 0
+	}
+	return nil
+}
+
+// handleReconciliationResult keeps track of the number of consecutive failed reconciliations of an ASG, and returns
+// it
+//
+// Unlike failed resyncs, failed reconciliations never cause the application to exit, because a single ASG failing to
+// be reconciled shouldn't prevent the other ASGs from being reconciled. Instead, the ASG is retried with a backoff.
+func (c *Controller) handleReconciliationResult(autoScalingGroupName string, err error) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.updateDegradedMetric()
+	if err != nil {
+		metrics.Server.Errors.Inc()
+		c.failedReconciliationCounters[autoScalingGroupName]++
+		if c.failedReconciliationCounters[autoScalingGroupName] == config.Get().MaxFailedExecutions {
+			log.Printf("[%s] Execution failed %d consecutive times, handler is now degraded", autoScalingGroupName, c.failedReconciliationCounters[autoScalingGroupName])
+		}
+	} else if c.failedReconciliationCounters[autoScalingGroupName] > 0 {
+		log.Printf("[%s] Execution was successful after %d failed attempts, resetting counter to 0", autoScalingGroupName, c.failedReconciliationCounters[autoScalingGroupName])
+		delete(c.failedReconciliationCounters, autoScalingGroupName)
+	}
+	return c.failedReconciliationCounters[autoScalingGroupName]
+}
+
+// Degraded returns why the controller is degraded, that is, why the resync or the reconciliation of an ASG has failed
+// at least MaxFailedExecutions consecutive times, or an empty string if it isn't degraded
+//
+// Unlike a controller that isn't live, a degraded controller is still making progress: it keeps retrying with a
+// backoff, which restarting the application wouldn't improve.
+func (c *Controller) Degraded() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.getDegradedReason()
+}
+
+// getDegradedReason returns why the controller is degraded, or an empty string if it isn't
+//
+// Must be called with the mutex held.
+func (c *Controller) getDegradedReason() string {
+	maxFailedExecutions := config.Get().MaxFailedExecutions
+	if c.resyncFailedCounter >= maxFailedExecutions {
+		return fmt.Sprintf("resync failed %d consecutive times", c.resyncFailedCounter)
+	}
+	autoScalingGroupNames := make([]string, 0, len(c.failedReconciliationCounters))
+	for autoScalingGroupName, failures := range c.failedReconciliationCounters {
+		if failures >= maxFailedExecutions {
+			autoScalingGroupNames = append(autoScalingGroupNames, autoScalingGroupName)
+		}
+	}
+	if len(autoScalingGroupNames) == 0 {
+		return ""
+	}
+	sort.Strings(autoScalingGroupNames)
+	return fmt.Sprintf("execution failed at least %d consecutive times for %s", maxFailedExecutions, strings.Join(autoScalingGroupNames, ", "))
+}
+
+// updateDegradedMetric sets the degraded metric based on the state of the controller
+//
+// Must be called with the mutex held.
+func (c *Controller) updateDegradedMetric() {
+	if len(c.getDegradedReason()) > 0 {
+		metrics.Server.Degraded.Set(1)
+	} else {
+		metrics.Server.Degraded.Set(0)
+	}
+}
+
+// getBackoff returns how long to wait before retrying after the given number of consecutive failures, which is
+// ExecutionInterval doubled after every failure but the first, capped at ResyncInterval
+//
+// Up to 20% of the backoff is randomly removed, so that ASGs failing at the same time, e.g. because of throttling,
+// aren't retried at the same time.
+func getBackoff(failures int) time.Duration {
+	backoff, maxBackoff := config.Get().ExecutionInterval, config.Get().ResyncInterval
+	for i := 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxBackoff)
+	return backoff - time.Duration(rand.Int63n(int64(backoff)/5+1))
+}
+
+// NodeEventHandler returns the handler that enqueues the ASG of a node whenever a relevant change is made to it
+func (c *Controller) NodeEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(object interface{}) {
+			if node, ok := object.(*v1.Node); ok {
+				c.enqueueNode(node)
+			}
+		},
+		UpdateFunc: func(oldObject, newObject interface{}) {
+			oldNode, ok := oldObject.(*v1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObject.(*v1.Node)
+			if !ok {
+				return
+			}
+			if hasRelevantChanges(oldNode, newNode) {
+				c.enqueueNode(newNode)
+			}
+		},
+		DeleteFunc: func(object interface{}) {
+			if tombstone, ok := object.(cache.DeletedFinalStateUnknown); ok {
+				object = tombstone.Obj
+			}
+			if node, ok := object.(*v1.Node); ok {
+				c.enqueueNode(node)
+			}
+		},
+	}
+}
+
+// enqueueNode enqueues the ASG of the given node
+//
+// If the ASG of the node is unknown, which is the case for nodes of instances launched after the last resync, every
+// ASG is enqueued instead.
+func (c *Controller) enqueueNode(node *v1.Node) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var autoScalingGroupNames []string
+	if autoScalingGroupName, ok := c.autoScalingGroupNameByInstanceID[getInstanceIDFromProviderID(node.Spec.ProviderID)]; ok {
+		autoScalingGroupNames = append(autoScalingGroupNames, autoScalingGroupName)
+	} else {
+		for autoScalingGroupName := range c.autoScalingGroupNames {
+			autoScalingGroupNames = append(autoScalingGroupNames, autoScalingGroupName)
+		}
+	}
+	for _, autoScalingGroupName := range autoScalingGroupNames {
+		c.queue.Add(autoScalingGroupName)
+	}
+}
+
+// hasRelevantChanges checks whether a change to a node could affect the rollout of its ASG
+func hasRelevantChanges(oldNode, newNode *v1.Node) bool {
+	if isNodeReady(oldNode) != isNodeReady(newNode) || oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable {
+		return true
+	}
+	for _, annotation := range []string{k8s.AnnotationRollingUpdateStartedTimestamp, k8s.AnnotationRollingUpdateDrainedTimestamp, k8s.AnnotationRollingUpdateTerminatedTimestamp, k8s.AnnotationPaused} {
+		if oldNode.Annotations[annotation] != newNode.Annotations[annotation] {
+			return true
+		}
+	}
+	return false
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getInstanceIDFromProviderID extracts the instance ID from a provider ID (e.g. aws:///us-west-2a/i-034fa1dfbfd35f8bb)
+func getInstanceIDFromProviderID(providerID string) string {
+	return providerID[strings.LastIndex(providerID, "/")+1:]
+}
+
+// describeAutoScalingGroups describes the ASGs to manage, which are either discovered through their tags or
+// specified by name
+func describeAutoScalingGroups(ctx context.Context, autoScalingService autoscalingiface.AutoScalingAPI) ([]*autoscaling.Group, error) {
+	cfg := config.Get()
+	var (
+		autoScalingGroups []*autoscaling.Group
+		err               error
+	)
+	if len(cfg.AutodiscoveryTags) > 0 {
+		autoScalingGroups, err = cloud.DescribeEnabledAutoScalingGroupsByTags(ctx, autoScalingService, cfg.AutodiscoveryTags)
+	} else {
+		autoScalingGroups, err = cloud.DescribeAutoScalingGroupsByNames(ctx, autoScalingService, cfg.AutoScalingGroupNames)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe AutoScalingGroups: %w", err)
+	}
+	if cfg.Debug {
+		log.Println("Described AutoScalingGroups successfully")
+	}
+	return autoScalingGroups, nil
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloudtest"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8s"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8stest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestHasRelevantChanges(t *testing.T) {
+	readyNode := k8stest.CreateTestNode("node", "us-west-2a", "i-1", "1000m", "1000Mi")
+	readyNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+	notReadyNode := *readyNode.DeepCopy()
+	notReadyNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
+	cordonedNode := *readyNode.DeepCopy()
+	cordonedNode.Spec.Unschedulable = true
+	drainedNode := *readyNode.DeepCopy()
+	drainedNode.Annotations[k8s.AnnotationRollingUpdateDrainedTimestamp] = time.Now().Format(time.RFC3339)
+	pausedNode := *readyNode.DeepCopy()
+	pausedNode.Annotations[k8s.AnnotationPaused] = "true"
+	labeledNode := *readyNode.DeepCopy()
+	labeledNode.Labels["some-label"] = "some-value"
+	scenarios := []struct {
+		name     string
+		newNode  v1.Node
+		expected bool
+	}{
+		{name: "unchanged", newNode: readyNode, expected: false},
+		{name: "not-ready", newNode: notReadyNode, expected: true},
+		{name: "cordoned", newNode: cordonedNode, expected: true},
+		{name: "drained", newNode: drainedNode, expected: true},
+		{name: "paused", newNode: pausedNode, expected: true},
+		{name: "labeled", newNode: labeledNode, expected: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			if actual := hasRelevantChanges(&readyNode, &scenario.newNode); actual != scenario.expected {
+				t.Errorf("expected %v, got %v", scenario.expected, actual)
+			}
+		})
+	}
+}
+
+func TestController_enqueueNode(t *testing.T) {
+	controller := NewController(nil, nil, nil)
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg-a": true, "asg-b": true}
+	controller.autoScalingGroupNameByInstanceID = map[string]string{"i-1": "asg-a"}
+
+	node := k8stest.CreateTestNode("node-1", "us-west-2a", "i-1", "1000m", "1000Mi")
+	controller.enqueueNode(&node)
+	if keys := drainQueue(controller); len(keys) != 1 || keys[0] != "asg-a" {
+		t.Errorf("expected only asg-a to be enqueued, got %v", keys)
+	}
+
+	unknownNode := k8stest.CreateTestNode("node-2", "us-west-2a", "i-2", "1000m", "1000Mi")
+	controller.enqueueNode(&unknownNode)
+	if keys := drainQueue(controller); len(keys) != 2 || keys[0] != "asg-a" || keys[1] != "asg-b" {
+		t.Errorf("expected every ASG to be enqueued for a node of an unknown instance, got %v", keys)
+	}
+}
+
+func TestController_processNextWorkItem_whenSlowedDown(t *testing.T) {
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService(nil)
+	controller := NewController(k8stest.NewMockClient(nil, nil), cloudtest.NewMockEC2Service(nil), mockAutoScalingService)
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg-a": true, "asg-b": true}
+	// A node of asg-a was terminated while SlowMode was enabled, which must also prevent asg-b from being reconciled
+	controller.slowedDownUntil = time.Now().Add(time.Minute)
+	controller.queue.Add("asg-b")
+	controller.processNextWorkItem(context.Background())
+	if mockAutoScalingService.Counter["DescribeAutoScalingGroups"] != 0 {
+		t.Error("no ASG should've been reconciled while slowed down by SlowMode")
+	}
+	if controller.queue.Len() != 0 {
+		t.Error("asg-b should've been put back in the work queue only once SlowMode allows it")
+	}
+
+	controller.slowedDownUntil = time.Time{}
+	controller.queue.Add("asg-b")
+	controller.processNextWorkItem(context.Background())
+	if mockAutoScalingService.Counter["DescribeAutoScalingGroups"] != 1 {
+		t.Error("asg-b should've been reconciled once no longer slowed down by SlowMode")
+	}
+}
+
+func TestController_processNextWorkItem_withPendingConfigurationChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	_ = os.WriteFile(path, []byte("autoScalingGroupNames: [asg-a, asg-b]\npodTerminationGracePeriod: 120"), 0644)
+	t.Setenv(config.EnvConfigFile, path)
+	if err := config.Initialize(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	defer config.Set(nil, true, true, false, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go config.WatchConfigFile(ctx, 10*time.Millisecond)
+	// Wait for the watcher to have read the configuration file before changing it
+	time.Sleep(50 * time.Millisecond)
+	_ = os.WriteFile(path, []byte("autoScalingGroupNames: [asg-a, asg-b]\npodTerminationGracePeriod: 600"), 0644)
+	for start := time.Now(); !config.HasPendingChanges(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the configuration file should've been reloaded")
+		}
+	}
+	controller := NewController(k8stest.NewMockClient(nil, nil), cloudtest.NewMockEC2Service(nil), cloudtest.NewMockAutoScalingService(nil))
+	defer controller.queue.ShutDown()
+	// asg-a is being reconciled by another worker, which must not prevent the changes from being applied forever
+	controller.mutex.Lock()
+	controller.reconcilingSince["asg-a"] = time.Now()
+	controller.mutex.Unlock()
+	controller.queue.Add("asg-b")
+	processed := make(chan struct{})
+	go func() {
+		controller.processNextWorkItem(ctx)
+		close(processed)
+	}()
+	select {
+	case <-processed:
+		t.Fatal("asg-b shouldn't have been reconciled until the reconciliation of asg-a had completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if config.Get().PodTerminationGracePeriod != 120 {
+		t.Error("changes shouldn't have been applied in the middle of the reconciliation of asg-a")
+	}
+	controller.mutex.Lock()
+	delete(controller.reconcilingSince, "asg-a")
+	controller.mutex.Unlock()
+	controller.reconciliationDone.Broadcast()
+	select {
+	case <-processed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("asg-b should've been reconciled once the reconciliation of asg-a had completed")
+	}
+	if config.Get().PodTerminationGracePeriod != 600 {
+		t.Error("changes should've been applied before asg-b was reconciled, got PodTerminationGracePeriod", config.Get().PodTerminationGracePeriod)
+	}
+}
+
+func TestController_processNextWorkItem_withMaxConcurrentDrains(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	config.Get().MaxConcurrentDrains = 1
+	config.Get().ExecutionInterval = 10 * time.Millisecond
+	defer config.Set(nil, true, true, false, false)
+
+	var (
+		autoScalingGroups []*autoscaling.Group
+		nodes             []v1.Node
+		pods              []v1.Pod
+	)
+	autoScalingGroupNames := []string{"asg-a", "asg-b", "asg-c"}
+	for _, name := range autoScalingGroupNames {
+		oldInstance := cloudtest.CreateTestAutoScalingInstance(name+"-old-1", "v1", nil, "InService")
+		newInstance := cloudtest.CreateTestAutoScalingInstance(name+"-new-1", "v2", nil, "InService")
+		autoScalingGroups = append(autoScalingGroups, cloudtest.CreateTestAutoScalingGroup(name, "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false))
+		oldNode := k8stest.CreateTestNode("node-"+aws.StringValue(oldInstance.InstanceId), aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+		oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+		newNode := k8stest.CreateTestNode("node-"+aws.StringValue(newInstance.InstanceId), aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+		newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+		nodes = append(nodes, oldNode, newNode)
+		pods = append(pods, k8stest.CreateTestPod("pod-"+aws.StringValue(oldInstance.InstanceId), oldNode.Name, "100m", "100Mi", false, v1.PodRunning))
+	}
+
+	mockClient := k8stest.NewMockClient(nodes, pods)
+	mockClient.DrainDuration = 50 * time.Millisecond
+	controller := NewController(mockClient, cloudtest.NewMockEC2Service(nil), cloudtest.NewMockAutoScalingService(autoScalingGroups))
+	controller.autoScalingGroupNames = make(map[string]bool)
+	for _, name := range autoScalingGroupNames {
+		controller.autoScalingGroupNames[name] = true
+		controller.queue.Add(name)
+	}
+	// Every ASG is reconciled by its own worker, so each of them tries to drain its old node at the same time
+	wg := sync.WaitGroup{}
+	for range autoScalingGroupNames {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for controller.processNextWorkItem(context.Background()) {
+			}
+		}()
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		drainedNodes := 0
+		currentNodes, _ := mockClient.GetNodes()
+		for _, node := range currentNodes {
+			if _, ok := node.Annotations[k8s.AnnotationRollingUpdateDrainedTimestamp]; ok {
+				drainedNodes++
+			}
+		}
+		if drainedNodes == len(autoScalingGroupNames) {
+			break
+		}
+	}
+	controller.queue.ShutDownWithDrain()
+	wg.Wait()
+
+	if mockClient.Counter["Drain"] != int64(len(autoScalingGroupNames)) {
+		t.Error("Each ASG should've drained one node, but", mockClient.Counter["Drain"], "were drained instead")
+	}
+	if mockClient.MaxConcurrentDrains != 1 {
+		t.Error("No more than MaxConcurrentDrains nodes should've been drained at the same time, but", mockClient.MaxConcurrentDrains, "were drained at the same time instead")
+	}
+	if drainingNodes.draining != 0 {
+		t.Error("Every drain slot should've been released, but", drainingNodes.draining, "are still in use")
+	}
+}
+
+func TestController_processNextWorkItem_whenTerminationFails(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	config.Get().MaxFailedExecutions = 1
+	defer config.Set(nil, true, true, false, false)
+
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	oldNode.SetAnnotations(map[string]string{
+		k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339),
+		k8s.AnnotationRollingUpdateDrainedTimestamp: time.Now().Format(time.RFC3339),
+	})
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+	mockAutoScalingService.TerminateInstanceInAutoScalingGroupError = awserr.New("ScalingActivityInProgress", "scaling activity in progress", nil)
+	controller := NewController(k8stest.NewMockClient([]v1.Node{oldNode, newNode}, nil), cloudtest.NewMockEC2Service(nil), mockAutoScalingService)
+	defer controller.queue.ShutDown()
+	controller.autoScalingGroupNames = map[string]bool{"asg": true}
+	controller.queue.Add("asg")
+	controller.processNextWorkItem(context.Background())
+	if mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"] != 1 {
+		t.Fatal("the drained node should've been terminated, but TerminateInstanceInAutoScalingGroup was called", mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"], "times")
+	}
+	if controller.failedReconciliationCounters["asg"] != 1 {
+		t.Error("the failed termination should've been counted as a failed reconciliation, got", controller.failedReconciliationCounters["asg"])
+	}
+	if degraded := controller.Degraded(); !strings.Contains(degraded, "asg") {
+		t.Errorf("expected controller to be degraded because of asg, got %q", degraded)
+	}
+}
+
+func TestController_CheckLiveness(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	controller := NewController(nil, nil, nil)
+	defer controller.queue.ShutDown()
+	if err := controller.CheckLiveness(); err != nil {
+		t.Error("a controller that isn't running should be live, got", err)
+	}
+	controller.resyncedAt = time.Now()
+	if err := controller.CheckLiveness(); err != nil {
+		t.Error("a controller that has just resynced should be live, got", err)
+	}
+	controller.reconcilingSince["asg"] = time.Now().Add(-config.Get().ExecutionTimeout)
+	if err := controller.CheckLiveness(); err != nil {
+		t.Error("a reconciliation that hasn't timed out for long should be tolerated, got", err)
+	}
+	controller.reconcilingSince["asg"] = time.Now().Add(-2 * config.Get().ExecutionTimeout)
+	if err := controller.CheckLiveness(); err == nil {
+		t.Error("a reconciliation that is wedged should've made the controller not live")
+	}
+	delete(controller.reconcilingSince, "asg")
+	controller.resyncedAt = time.Now().Add(-time.Duration(config.Get().LivenessMaxMissedIntervals+1) * config.Get().ResyncInterval)
+	if err := controller.CheckLiveness(); err == nil {
+		t.Error("a controller that hasn't resynced for too long should've been not live")
+	}
+}
+
+func TestController_handleResyncResult(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	controller := NewController(nil, nil, nil)
+	defer controller.queue.ShutDown()
+	throttlingErr := awserr.New("Throttling", "Rate exceeded", nil)
+	for i := 0; i < 2*config.Get().MaxFailedExecutions; i++ {
+		if err := controller.handleResyncResult(throttlingErr); err != nil {
+			t.Fatal("a recoverable error should never have caused the application to exit, got", err)
+		}
+	}
+	if len(controller.Degraded()) == 0 {
+		t.Error("the controller should've been degraded")
+	}
+	if err := controller.handleResyncResult(awserr.New("AccessDenied", "User is not authorized", nil)); !errors.Is(err, ErrUnrecoverable) {
+		t.Error("an unrecoverable error should've caused the application to exit after MaxFailedExecutions failures, got", err)
+	}
+	if err := controller.handleResyncResult(nil); err != nil || len(controller.Degraded()) > 0 {
+		t.Error("a successful resync should've reset the controller, got", err, controller.Degraded())
+	}
+	if err := controller.handleResyncResult(awserr.New("AccessDenied", "User is not authorized", nil)); err != nil {
+		t.Error("an unrecoverable error shouldn't have caused the application to exit before MaxFailedExecutions failures, got", err)
+	}
+}
+
+func TestController_handleReconciliationResult(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	controller := NewController(nil, nil, nil)
+	defer controller.queue.ShutDown()
+	for i := 1; i <= config.Get().MaxFailedExecutions; i++ {
+		if len(controller.Degraded()) > 0 {
+			t.Fatalf("the controller shouldn't have been degraded after %d failures", i-1)
+		}
+		if failures := controller.handleReconciliationResult("asg", ErrTimedOut); failures != i {
+			t.Fatalf("expected %d failures, got %d", i, failures)
+		}
+	}
+	if degraded := controller.Degraded(); !strings.Contains(degraded, "asg") {
+		t.Error("the controller should've been degraded because of asg, got", degraded)
+	}
+	if failures := controller.handleReconciliationResult("asg", nil); failures != 0 || len(controller.Degraded()) > 0 {
+		t.Error("a successful reconciliation should've reset the controller")
+	}
+}
+
+func TestGetBackoff(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	scenarios := []struct {
+		failures        int
+		expectedBackoff time.Duration
+	}{
+		{failures: 1, expectedBackoff: 20 * time.Second},
+		{failures: 2, expectedBackoff: 40 * time.Second},
+		{failures: 4, expectedBackoff: 160 * time.Second},
+		{failures: 5, expectedBackoff: 300 * time.Second},
+		{failures: 100, expectedBackoff: 300 * time.Second},
+	}
+	for _, scenario := range scenarios {
+		backoff := getBackoff(scenario.failures)
+		if backoff > scenario.expectedBackoff || backoff < scenario.expectedBackoff*4/5 {
+			t.Errorf("expected backoff after %d failures to be between %s and %s, got %s", scenario.failures, scenario.expectedBackoff*4/5, scenario.expectedBackoff, backoff)
+		}
+	}
+}
+
+// drainQueue removes every key currently in the controller's work queue and returns them sorted
+func drainQueue(controller *Controller) []string {
+	var keys []string
+	for controller.queue.Len() > 0 {
+		key, _ := controller.queue.Get()
+		controller.queue.Done(key)
+		controller.queue.Forget(key)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
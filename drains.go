@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+)
+
+// drainingNodes keeps track of the number of nodes being drained across every ASG, so that no more than
+// MaxConcurrentDrains nodes are drained at the same time cluster-wide
+var drainingNodes = &DrainLimiter{}
+
+// DrainLimiter limits the number of nodes that can be drained at the same time
+type DrainLimiter struct {
+	mutex    sync.Mutex
+	draining int
+}
+
+// TryAcquire reserves a slot for draining a node, and returns false if MaxConcurrentDrains nodes are already
+// being drained
+//
+// The limit is read from the configuration on every call, so that changes to the configuration file are applied
+// without having to wait for the ongoing drains to complete.
+func (l *DrainLimiter) TryAcquire() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.draining >= config.Get().MaxConcurrentDrains {
+		return false
+	}
+	l.draining++
+	return true
+}
+
+// Release frees a slot previously reserved with TryAcquire
+func (l *DrainLimiter) Release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.draining > 0 {
+		l.draining--
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8s"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+)
+
+// getFailedDrainAttemptsFromNode returns the number of failed attempts at draining the given node, as well as when
+// the first of these attempts failed
+func getFailedDrainAttemptsFromNode(node *v1.Node) (attempts int, firstFailedAt time.Time) {
+	attempts, _ = strconv.Atoi(node.Annotations[k8s.AnnotationRollingUpdateFailedDrainAttempts])
+	if attempts <= 0 {
+		return 0, time.Time{}
+	}
+	firstFailedAt, _ = time.Parse(time.RFC3339, node.Annotations[k8s.AnnotationRollingUpdateFirstFailedDrainTimestamp])
+	return attempts, firstFailedAt
+}
+
+// shouldEscalateDrain checks whether a node has failed to drain enough times, or for long enough, for the drain
+// escalation policy of its ASG to apply
+func shouldEscalateDrain(attempts int, firstFailedAt time.Time) bool {
+	if attempts == 0 {
+		return false
+	}
+	if attempts >= config.Get().DrainEscalationAttempts {
+		return true
+	}
+	timeout := config.Get().DrainEscalationTimeout
+	return timeout > 0 && !firstFailedAt.IsZero() && time.Since(firstFailedAt) >= timeout
+}
+
+// isDrainEscalated checks whether the drain escalation policy of the ASG applies to the given node
+func isDrainEscalated(node *v1.Node) bool {
+	return shouldEscalateDrain(getFailedDrainAttemptsFromNode(node))
+}
+
+// recordFailedDrainAttempt persists a failed attempt at draining the given outdated node on the node itself, and
+// escalates the drain according to the ASG's drain escalation policy as soon as the node has failed to drain
+// DrainEscalationAttempts times or for longer than DrainEscalationTimeout
+func recordFailedDrainAttempt(ctx context.Context, client k8s.ClientAPI, autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig, outdatedNode *outdatedNode) {
+	outdatedInstance, node := outdatedNode.instance, outdatedNode.node
+	metrics.Server.DrainFailures.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
+	attempts, firstFailedAt := getFailedDrainAttemptsFromNode(node)
+	alreadyEscalated := shouldEscalateDrain(attempts, firstFailedAt)
+	attempts++
+	// The attempt is recorded even if the execution has been cancelled in the meantime
+	ctx, cancel := withoutCancel(ctx)
+	defer cancel()
+	if firstFailedAt.IsZero() {
+		firstFailedAt = time.Now()
+	}
+	// Both annotations are written at once, so that they can't end up out of sync with each other
+	patch := k8s.NodePatch{Annotations: map[string]*string{
+		k8s.AnnotationRollingUpdateFailedDrainAttempts:       aws.String(strconv.Itoa(attempts)),
+		k8s.AnnotationRollingUpdateFirstFailedDrainTimestamp: aws.String(firstFailedAt.Format(time.RFC3339)),
+	}}
+	if err := k8s.PatchNodeByAutoScalingInstance(ctx, client, outdatedInstance, patch); err != nil {
+		log.Printf("[%s][%s] Unable to annotate node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
+	}
+	log.Printf("[%s][%s] Node has failed to drain %d time(s) since %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), attempts, firstFailedAt.Format(time.RFC3339))
+	if alreadyEscalated || !shouldEscalateDrain(attempts, firstFailedAt) {
+		return
+	}
+	var consequence string
+	switch asgConfig.DrainEscalationPolicy {
+	case config.DrainEscalationPolicySkip:
+		revertDrain(ctx, client, autoScalingGroup, asgConfig, outdatedNode)
+		consequence = "node will be skipped"
+	case config.DrainEscalationPolicyForce:
+		consequence = "node will be drained by deleting its pods without eviction, bypassing PodDisruptionBudgets"
+	case config.DrainEscalationPolicyBlock:
+		consequence = fmt.Sprintf("rolling update of ASG %s is blocked", aws.StringValue(autoScalingGroup.AutoScalingGroupName))
+	}
+	message := fmt.Sprintf("Node failed to drain %d time(s) since %s", attempts, firstFailedAt.Format(time.RFC3339))
+	if len(consequence) > 0 {
+		message += fmt.Sprintf(", %s until the %s annotation is removed", consequence, k8s.AnnotationRollingUpdateFailedDrainAttempts)
+	}
+	log.Printf("[%s][%s] %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), message)
+	client.RecordEvent(node, v1.EventTypeWarning, k8s.EventReasonDrainEscalated, message)
+}
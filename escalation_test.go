@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloudtest"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8s"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8stest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestShouldEscalateDrain(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	config.Get().DrainEscalationAttempts = 3
+	scenarios := []struct {
+		name                   string
+		drainEscalationTimeout time.Duration
+		attempts               int
+		firstFailedAt          time.Time
+		expected               bool
+	}{
+		{name: "no-failed-attempts", attempts: 0, expected: false},
+		{name: "below-attempts", attempts: 2, firstFailedAt: time.Now().Add(-24 * time.Hour), expected: false},
+		{name: "reached-attempts", attempts: 3, firstFailedAt: time.Now(), expected: true},
+		{name: "below-timeout", drainEscalationTimeout: time.Hour, attempts: 1, firstFailedAt: time.Now().Add(-time.Minute), expected: false},
+		{name: "reached-timeout", drainEscalationTimeout: time.Hour, attempts: 1, firstFailedAt: time.Now().Add(-2 * time.Hour), expected: true},
+		{name: "reached-timeout-without-failed-attempts", drainEscalationTimeout: time.Hour, attempts: 0, firstFailedAt: time.Now().Add(-2 * time.Hour), expected: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			config.Get().DrainEscalationTimeout = scenario.drainEscalationTimeout
+			if actual := shouldEscalateDrain(scenario.attempts, scenario.firstFailedAt); actual != scenario.expected {
+				t.Errorf("expected %v, got %v", scenario.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRecordFailedDrainAttempt(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	config.Get().DrainEscalationAttempts = 1
+	config.Get().DrainEscalationPolicy = config.DrainEscalationPolicySkip
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance}, false)
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	oldNode.SetLabels(map[string]string{k8s.LabelExcludeFromExternalLoadBalancers: "true"})
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode}, []v1.Pod{})
+	// Like the informer cache of the real client, the node retrieved doesn't reflect the changes made to it
+	mockClient.StaleReads = true
+	asgConfig := config.Get().ForAutoScalingGroup(aws.StringValue(asg.AutoScalingGroupName), nil)
+	asgConfig.ExcludeFromExternalLoadBalancers = true
+	recordFailedDrainAttempt(context.Background(), mockClient, asg, asgConfig, &outdatedNode{instance: oldInstance, node: &oldNode})
+	oldNode = mockClient.Nodes[oldNode.Name]
+	if attempts, firstFailedAt := getFailedDrainAttemptsFromNode(&oldNode); attempts != 1 || firstFailedAt.IsZero() {
+		t.Errorf("expected 1 failed attempt with a timestamp, got %d failed attempts since %s", attempts, firstFailedAt)
+	}
+	if _, ok := oldNode.Labels[k8s.LabelExcludeFromExternalLoadBalancers]; ok {
+		t.Error("expected the drain to have been reverted, since the drain was escalated with the skip policy")
+	}
+	if events := mockClient.Events[oldNode.Name]; len(events) != 1 || events[0] != k8s.EventReasonDrainEscalated {
+		t.Errorf("expected a %s event to have been recorded, got %v", k8s.EventReasonDrainEscalated, events)
+	}
+}
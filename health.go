@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/metrics"
+)
+
+// Health exposes the liveness and readiness of the application, so that Kubernetes can restart a handler that is no
+// longer making progress
+type Health struct {
+	// awsClientsCreated is whether the AWS services have been created
+	awsClientsCreated atomic.Bool
+	// kubernetesClientStarted is whether the Kubernetes client has been created and its informers have synced
+	kubernetesClientStarted atomic.Bool
+	// leading is whether this replica is the leader, which is only relevant if leader election is enabled
+	leading atomic.Bool
+	// leader is the identity of the current leader, which is only relevant if leader election is enabled
+	leader atomic.Pointer[string]
+	// controller is the controller whose liveness is checked, once it has been created
+	controller atomic.Pointer[Controller]
+}
+
+// NewHealth creates a new Health
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// Register registers the liveness and readiness endpoints on the metrics server
+func (h *Health) Register() {
+	metrics.Server.Handle("/healthz", http.HandlerFunc(h.handleLiveness))
+	metrics.Server.Handle("/readyz", http.HandlerFunc(h.handleReadiness))
+}
+
+// HealthStatus is the response of the liveness and readiness endpoints
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+	// Leader is whether this replica is the leader. Omitted if leader election is disabled.
+	Leader *bool `json:"leader,omitempty"`
+	// Degraded is why the controller is degraded, if it is. A degraded controller keeps retrying with a backoff, so
+	// it doesn't cause the checks to fail.
+	Degraded string `json:"degraded,omitempty"`
+}
+
+// handleLiveness returns a 503 if the controller is no longer making progress
+func (h *Health) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	status := HealthStatus{Checks: map[string]string{"controller": "ok"}}
+	if controller := h.controller.Load(); controller != nil {
+		if err := controller.CheckLiveness(); err != nil {
+			status.Checks["controller"] = err.Error()
+		}
+		status.Degraded = controller.Degraded()
+	}
+	writeHealthStatus(w, status)
+}
+
+// handleReadiness returns a 503 until the AWS and Kubernetes clients are ready to be used
+//
+// With leader election enabled, replicas that aren't the leader are still considered as ready, because they are ready
+// to take over, but whether a replica is the leader is part of the response.
+func (h *Health) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	status := HealthStatus{Checks: map[string]string{"aws": "ok", "kubernetes": "ok"}}
+	if !h.awsClientsCreated.Load() {
+		status.Checks["aws"] = "AWS services have not been created yet"
+	}
+	if !h.kubernetesClientStarted.Load() {
+		status.Checks["kubernetes"] = "Kubernetes client has not been created or its informers have not synced yet"
+	}
+	if config.Get().LeaderElection {
+		leading := h.leading.Load()
+		status.Leader = &leading
+	}
+	if controller := h.controller.Load(); controller != nil {
+		status.Degraded = controller.Degraded()
+	}
+	writeHealthStatus(w, status)
+}
+
+// writeHealthStatus writes the given status with a 200 if every check passed, or with a 503 otherwise
+func writeHealthStatus(w http.ResponseWriter, status HealthStatus) {
+	status.Status = "ok"
+	for _, result := range status.Checks {
+		if result != "ok" {
+			status.Status = "failed"
+		}
+	}
+	if status.Status != "ok" {
+		writeJSON(w, http.StatusServiceUnavailable, status)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+)
+
+func TestHealth(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	health := NewHealth()
+	if code := serveAdminAPI(health.handleReadiness, http.MethodGet, "/readyz", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d before the clients are created, got %d", http.StatusServiceUnavailable, code)
+	}
+	health.awsClientsCreated.Store(true)
+	health.kubernetesClientStarted.Store(true)
+	if code := serveAdminAPI(health.handleReadiness, http.MethodGet, "/readyz", nil); code != http.StatusOK {
+		t.Errorf("expected %d once the clients are created, got %d", http.StatusOK, code)
+	}
+
+	if code := serveAdminAPI(health.handleLiveness, http.MethodGet, "/healthz", nil); code != http.StatusOK {
+		t.Errorf("expected %d before the controller is created, got %d", http.StatusOK, code)
+	}
+	controller := NewController(nil, nil, nil)
+	defer controller.queue.ShutDown()
+	controller.resyncedAt = time.Now()
+	health.controller.Store(controller)
+	var status HealthStatus
+	if code := serveAdminAPI(health.handleLiveness, http.MethodGet, "/healthz", &status); code != http.StatusOK || status.Status != "ok" {
+		t.Errorf("expected %d and status ok, got %d and %+v", http.StatusOK, code, status)
+	}
+	controller.resyncFailedCounter = config.Get().MaxFailedExecutions
+	if code := serveAdminAPI(health.handleLiveness, http.MethodGet, "/healthz", &status); code != http.StatusOK || len(status.Degraded) == 0 {
+		t.Errorf("expected %d and a degraded reason, got %d and %+v", http.StatusOK, code, status)
+	}
+	controller.reconcilingSince["asg"] = time.Now().Add(-24 * time.Hour)
+	if code := serveAdminAPI(health.handleLiveness, http.MethodGet, "/healthz", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d when a reconciliation is wedged, got %d", http.StatusServiceUnavailable, code)
+	}
+}
@@ -6,12 +6,18 @@ import (
 	"log"
 	"time"
 
-	"github.com/TwiN/gocache/v2"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubectl/pkg/drain"
 )
 
@@ -20,82 +26,136 @@ const (
 	AnnotationRollingUpdateDrainedTimestamp    = "aws-eks-asg-rolling-update-handler.twin.sh/drained-at"
 	AnnotationRollingUpdateTerminatedTimestamp = "aws-eks-asg-rolling-update-handler.twin.sh/terminated-at"
 
-	LabelExcludeFromExternalLoadBalancers = "node.kubernetes.io/exclude-from-external-load-balancers"
+	AnnotationRollingUpdateFailedDrainAttempts       = "aws-eks-asg-rolling-update-handler.twin.sh/failed-drain-attempts"
+	AnnotationRollingUpdateFirstFailedDrainTimestamp = "aws-eks-asg-rolling-update-handler.twin.sh/first-failed-drain-at"
 
-	nodesCacheKey = "nodes"
-)
+	// AnnotationPaused can be set to true on an outdated node to exempt it from being drained
+	AnnotationPaused = "aws-eks-asg-rolling-update-handler.twin.sh/paused"
+
+	LabelExcludeFromExternalLoadBalancers = "node.kubernetes.io/exclude-from-external-load-balancers"
 
-var (
-	cache = gocache.NewCache().WithMaxSize(1000).WithEvictionPolicy(gocache.LeastRecentlyUsed)
+	nodeProviderIDIndex = "spec.providerID"
+	podNodeNameIndex    = "spec.nodeName"
 )
 
 type ClientAPI interface {
 	GetNodes() ([]v1.Node, error)
 	GetPodsInNode(nodeName string) ([]v1.Pod, error)
+	GetPodDisruptionBudgets(ctx context.Context, namespace string) ([]policyv1.PodDisruptionBudget, error)
 	GetNodeByAutoScalingInstance(instance *autoscaling.Instance) (*v1.Node, error)
 	FilterNodeByAutoScalingInstance(nodes []v1.Node, instance *autoscaling.Instance) (*v1.Node, error)
-	UpdateNode(node *v1.Node) error
-	Cordon(nodeName string) error
-	Drain(nodeName string, ignoreDaemonSets, deleteEmptyDirData bool, podTerminationGracePeriod int) error
+	UpdateNode(ctx context.Context, node *v1.Node) error
+	PatchNode(ctx context.Context, nodeName string, patch []byte) error
+	Cordon(ctx context.Context, nodeName string) error
+	Uncordon(ctx context.Context, nodeName string) error
+	Drain(ctx context.Context, nodeName string, ignoreDaemonSets, deleteEmptyDirData bool, podTerminationGracePeriod int, disableEviction bool) error
+	RecordEvent(object runtime.Object, eventType, reason, message string)
 }
 
 type Client struct {
-	client kubernetes.Interface
+	client          kubernetes.Interface
+	recorder        record.EventRecorder
+	informerFactory informers.SharedInformerFactory
+	nodeInformer    cache.SharedIndexInformer
+	podInformer     cache.SharedIndexInformer
 }
 
 // NewClient creates a new Client
-func NewClient(client kubernetes.Interface) *Client {
+//
+// Nodes and pods are retrieved from shared informers, which must be started with Start before the Client is used.
+//
+// If recorder is nil, no events will be recorded
+func NewClient(client kubernetes.Interface, recorder record.EventRecorder) *Client {
+	// managedFields can be quite large and are never used, so there's no point in keeping them in memory
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTransform(stripManagedFields))
+	nodeInformer := informerFactory.Core().V1().Nodes().Informer()
+	podInformer := informerFactory.Core().V1().Pods().Informer()
+	// Errors can only be returned if the informers have already been started, which isn't possible at this point
+	_ = nodeInformer.AddIndexers(cache.Indexers{nodeProviderIDIndex: indexNodeByProviderID})
+	_ = podInformer.AddIndexers(cache.Indexers{podNodeNameIndex: indexPodByNodeName})
 	return &Client{
-		client: client,
+		client:          client,
+		recorder:        recorder,
+		informerFactory: informerFactory,
+		nodeInformer:    nodeInformer,
+		podInformer:     podInformer,
 	}
 }
 
+// Start starts the node and pod informers and blocks until their caches are synced.
+// The informers stop when the given context is cancelled.
+func (k *Client) Start(ctx context.Context) error {
+	k.informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), k.nodeInformer.HasSynced, k.podInformer.HasSynced) {
+		return fmt.Errorf("failed to sync node and pod informers")
+	}
+	return nil
+}
+
+// AddNodeEventHandler registers a handler that is notified whenever a node is added, updated or deleted
+func (k *Client) AddNodeEventHandler(handler cache.ResourceEventHandler) error {
+	_, err := k.nodeInformer.AddEventHandler(handler)
+	return err
+}
+
 // GetNodes retrieves all nodes from the cluster
 func (k *Client) GetNodes() ([]v1.Node, error) {
-	nodes, exists := cache.Get(nodesCacheKey)
-	if exists {
-		if v1Nodes, ok := nodes.([]v1.Node); ok {
-			// Return cached nodes
-			return v1Nodes, nil
-		} else {
-			log.Println("[k8s.GetNodes] Failed to cast cached nodes to []v1.Node; retrieving nodes from API instead")
-			cache.Delete(nodesCacheKey)
-		}
+	if !k.nodeInformer.HasSynced() {
+		return nil, fmt.Errorf("node informer has not synced")
 	}
-	nodeList, err := k.client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		return nil, err
+	objects := k.nodeInformer.GetIndexer().List()
+	nodes := make([]v1.Node, 0, len(objects))
+	for _, object := range objects {
+		// Objects from the informer's cache are shared, so they must be copied before being returned
+		nodes = append(nodes, *object.(*v1.Node).DeepCopy())
 	}
-	cache.SetWithTTL(nodesCacheKey, nodeList.Items, 10*time.Second)
-	return nodeList.Items, nil
+	return nodes, nil
 }
 
 // GetPodsInNode retrieves all pods from a given node
 func (k *Client) GetPodsInNode(node string) ([]v1.Pod, error) {
-	podList, err := k.client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-		FieldSelector:   "spec.nodeName=" + node,
-		ResourceVersion: "0",
-	})
+	if !k.podInformer.HasSynced() {
+		return nil, fmt.Errorf("pod informer has not synced")
+	}
+	objects, err := k.podInformer.GetIndexer().ByIndex(podNodeNameIndex, node)
 	if err != nil {
 		return nil, err
 	}
-	return podList.Items, nil
+	pods := make([]v1.Pod, 0, len(objects))
+	for _, object := range objects {
+		pods = append(pods, *object.(*v1.Pod).DeepCopy())
+	}
+	return pods, nil
+}
+
+// GetPodDisruptionBudgets retrieves all PodDisruptionBudgets from a given namespace
+func (k *Client) GetPodDisruptionBudgets(ctx context.Context, namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	podDisruptionBudgetList, err := k.client.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return podDisruptionBudgetList.Items, nil
 }
 
 // GetNodeByAutoScalingInstance gets the Kubernetes node matching an AWS AutoScaling instance
-// Because we cannot filter by spec.providerID, the entire list of nodes is fetched every time
-// this function is called
 func (k *Client) GetNodeByAutoScalingInstance(instance *autoscaling.Instance) (*v1.Node, error) {
-	nodes, err := k.GetNodes()
+	if !k.nodeInformer.HasSynced() {
+		return nil, fmt.Errorf("node informer has not synced")
+	}
+	providerId := getProviderID(instance)
+	objects, err := k.nodeInformer.GetIndexer().ByIndex(nodeProviderIDIndex, providerId)
 	if err != nil {
 		return nil, err
 	}
-	return k.FilterNodeByAutoScalingInstance(nodes, instance)
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("node with providerID \"%s\" not found", providerId)
+	}
+	return objects[0].(*v1.Node).DeepCopy(), nil
 }
 
 // FilterNodeByAutoScalingInstance extracts the Kubernetes node belonging to a given AWS instance from a list of nodes
 func (k *Client) FilterNodeByAutoScalingInstance(nodes []v1.Node, instance *autoscaling.Instance) (*v1.Node, error) {
-	providerId := fmt.Sprintf("aws:///%s/%s", aws.StringValue(instance.AvailabilityZone), aws.StringValue(instance.InstanceId))
+	providerId := getProviderID(instance)
 	for _, node := range nodes {
 		if node.Spec.ProviderID == providerId {
 			return &node, nil
@@ -105,21 +165,30 @@ func (k *Client) FilterNodeByAutoScalingInstance(nodes []v1.Node, instance *auto
 }
 
 // UpdateNode updates a node
-func (k *Client) UpdateNode(node *v1.Node) error {
+func (k *Client) UpdateNode(ctx context.Context, node *v1.Node) error {
 	api := k.client.CoreV1().Nodes()
-	_, err := api.Update(context.TODO(), node, metav1.UpdateOptions{})
+	_, err := api.Update(ctx, node, metav1.UpdateOptions{})
+	return err
+}
+
+// PatchNode applies a strategic merge patch to a node
+//
+// Unlike UpdateNode, PatchNode doesn't require the node to be up to date, which makes it suitable for changing a node
+// retrieved from the informer cache. See NodePatch.
+func (k *Client) PatchNode(ctx context.Context, nodeName string, patch []byte) error {
+	_, err := k.client.CoreV1().Nodes().Patch(ctx, nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
 	return err
 }
 
 // Cordon disables scheduling new pods onto the given node
-func (k *Client) Cordon(nodeName string) error {
-	node, err := k.client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+func (k *Client) Cordon(ctx context.Context, nodeName string) error {
+	node, err := k.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 	drainer := &drain.Helper{
 		Client: k.client,
-		Ctx:    context.TODO(),
+		Ctx:    ctx,
 	}
 	if err := drain.RunCordonOrUncordon(drainer, node, true); err != nil {
 		log.Printf("[%s][CORDONER] Failed to cordon node: %v", node.Name, err)
@@ -128,9 +197,30 @@ func (k *Client) Cordon(nodeName string) error {
 	return nil
 }
 
+// Uncordon enables scheduling new pods onto the given node
+func (k *Client) Uncordon(ctx context.Context, nodeName string) error {
+	node, err := k.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	drainer := &drain.Helper{
+		Client: k.client,
+		Ctx:    ctx,
+	}
+	if err := drain.RunCordonOrUncordon(drainer, node, false); err != nil {
+		log.Printf("[%s][CORDONER] Failed to uncordon node: %v", node.Name, err)
+		return err
+	}
+	return nil
+}
+
 // Drain gracefully deletes all pods from a given node
-func (k *Client) Drain(nodeName string, ignoreDaemonSets, deleteEmptyDirData bool, podTerminationGracePeriod int) error {
-	node, err := k.client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+//
+// If the context is cancelled, the drain stops waiting for the pods to be evicted and returns an error.
+//
+// If disableEviction is true, pods are deleted instead of being evicted, which bypasses PodDisruptionBudgets.
+func (k *Client) Drain(ctx context.Context, nodeName string, ignoreDaemonSets, deleteEmptyDirData bool, podTerminationGracePeriod int, disableEviction bool) error {
+	node, err := k.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -140,12 +230,18 @@ func (k *Client) Drain(nodeName string, ignoreDaemonSets, deleteEmptyDirData boo
 		IgnoreAllDaemonSets: ignoreDaemonSets,
 		DeleteEmptyDirData:  deleteEmptyDirData,
 		GracePeriodSeconds:  podTerminationGracePeriod,
+		DisableEviction:     disableEviction,
 		Timeout:             5 * time.Minute,
-		Ctx:                 context.TODO(),
+		Ctx:                 ctx,
 		Out:                 drainLogger{NodeName: nodeName},
 		ErrOut:              drainLogger{NodeName: nodeName},
 		OnPodDeletedOrEvicted: func(pod *v1.Pod, usingEviction bool) {
 			log.Printf("[%s][DRAINER] evicted pod %s/%s", nodeName, pod.Namespace, pod.Name)
+			if usingEviction {
+				k.RecordEvent(pod, v1.EventTypeNormal, EventReasonEvicted, fmt.Sprintf("Evicted from node %s because the node is being rolled out", nodeName))
+			} else {
+				k.RecordEvent(pod, v1.EventTypeNormal, EventReasonEvicted, fmt.Sprintf("Deleted from node %s because the node is being rolled out", nodeName))
+			}
 		},
 	}
 	if !node.Spec.Unschedulable {
@@ -162,6 +258,41 @@ func (k *Client) Drain(nodeName string, ignoreDaemonSets, deleteEmptyDirData boo
 	return nil
 }
 
+// RecordEvent records an event for the given object
+func (k *Client) RecordEvent(object runtime.Object, eventType, reason, message string) {
+	if k.recorder == nil {
+		return
+	}
+	k.recorder.Event(object, eventType, reason, message)
+}
+
+func getProviderID(instance *autoscaling.Instance) string {
+	return fmt.Sprintf("aws:///%s/%s", aws.StringValue(instance.AvailabilityZone), aws.StringValue(instance.InstanceId))
+}
+
+func stripManagedFields(object interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(object); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return object, nil
+}
+
+func indexNodeByProviderID(object interface{}) ([]string, error) {
+	node, ok := object.(*v1.Node)
+	if !ok || len(node.Spec.ProviderID) == 0 {
+		return nil, nil
+	}
+	return []string{node.Spec.ProviderID}, nil
+}
+
+func indexPodByNodeName(object interface{}) ([]string, error) {
+	pod, ok := object.(*v1.Pod)
+	if !ok || len(pod.Spec.NodeName) == 0 {
+		return nil, nil
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
 type drainLogger struct {
 	NodeName string
 }
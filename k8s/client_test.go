@@ -1,20 +1,129 @@
 package k8s
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestClient_Drain(t *testing.T) {
 	fakeKubernetesClient := fakekubernetes.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
-	kc := NewClient(fakeKubernetesClient)
-	if err := kc.Cordon("default"); err != nil {
+	kc := NewClient(fakeKubernetesClient, nil)
+	if err := kc.Cordon(context.Background(), "default"); err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-	if err := kc.Drain("default", true, true, -1); err != nil {
+	if err := kc.Drain(context.Background(), "default", true, true, -1, false); err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
 }
+
+func TestClient_DrainRecordsEvents(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "rs"}}},
+		Spec:       v1.PodSpec{NodeName: "default"},
+	}
+	fakeKubernetesClient := fakekubernetes.NewSimpleClientset(node, pod)
+	fakeKubernetesClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "policy/v1"},
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods/eviction", Kind: "Eviction", Group: "policy", Version: "v1"}}},
+	}
+	// The fake client doesn't support evictions, so we delete the pod ourselves instead
+	fakeKubernetesClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		return true, nil, fakeKubernetesClient.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), pod.Namespace, pod.Name)
+	})
+	recorder := record.NewFakeRecorder(10)
+	kc := NewClient(fakeKubernetesClient, recorder)
+	if err := kc.Drain(context.Background(), "default", true, true, -1, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, EventReasonEvicted) {
+			t.Errorf("expected an event with reason %s, got %s", EventReasonEvicted, event)
+		}
+	default:
+		t.Error("expected an event to have been recorded for the evicted pod")
+	}
+}
+
+func TestClient_Start(t *testing.T) {
+	fakeKubernetesClient := fakekubernetes.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-034fa1dfbfd35f8bb"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}, Spec: v1.NodeSpec{ProviderID: "aws:///us-west-2b/i-07550830aef9e4179"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "node-1"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "kube-system"}, Spec: v1.PodSpec{NodeName: "node-1"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-3", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "node-2"}},
+	)
+	kc := NewClient(fakeKubernetesClient, nil)
+	if _, err := kc.GetNodes(); err == nil {
+		t.Error("expected an error, because the informers haven't been started yet")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := kc.Start(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	nodes, err := kc.GetNodes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Errorf("expected 2 nodes, got %d", len(nodes))
+	}
+	pods, err := kc.GetPodsInNode("node-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pods) != 2 {
+		t.Errorf("expected 2 pods in node-1, got %d", len(pods))
+	}
+	node, err := kc.GetNodeByAutoScalingInstance(&autoscaling.Instance{AvailabilityZone: aws.String("us-west-2b"), InstanceId: aws.String("i-07550830aef9e4179")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if node.Name != "node-2" {
+		t.Errorf("expected node-2, got %s", node.Name)
+	}
+	// Modifying the node returned must not modify the informer's cache
+	node.SetAnnotations(map[string]string{"modified": "true"})
+	if node, _ = kc.GetNodeByAutoScalingInstance(&autoscaling.Instance{AvailabilityZone: aws.String("us-west-2b"), InstanceId: aws.String("i-07550830aef9e4179")}); len(node.Annotations) != 0 {
+		t.Error("the node from the informer's cache shouldn't have been modified")
+	}
+	if _, err := kc.GetNodeByAutoScalingInstance(&autoscaling.Instance{AvailabilityZone: aws.String("us-west-2c"), InstanceId: aws.String("i-0147ad0816c210dae")}); err == nil {
+		t.Error("expected an error, because there's no node for that instance")
+	}
+}
+
+func TestClient_PatchNode(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{"kept": "true", "removed": "true"}}}
+	fakeKubernetesClient := fakekubernetes.NewSimpleClientset(node)
+	kc := NewClient(fakeKubernetesClient, nil)
+	value := "value"
+	patch, err := NodePatch{Annotations: map[string]*string{"added": &value, "removed": nil}}.Marshal()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := kc.PatchNode(context.Background(), "default", patch); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	patchedNode, err := fakeKubernetesClient.CoreV1().Nodes().Get(context.Background(), "default", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(patchedNode.Annotations) != 2 || patchedNode.Annotations["kept"] != "true" || patchedNode.Annotations["added"] != "value" {
+		t.Errorf("expected annotations kept=true and added=value, got %v", patchedNode.Annotations)
+	}
+}
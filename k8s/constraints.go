@@ -0,0 +1,183 @@
+package k8s
+
+import (
+	"fmt"
+	"math"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"k8s.io/klog/v2"
+)
+
+// Reasons why a pod cannot be scheduled on a node, worded like the kube-scheduler's
+const (
+	ReasonNodeUnschedulable              = "node(s) were unschedulable"
+	ReasonNodeAffinityMismatch           = "node(s) didn't match Pod's node affinity/selector"
+	ReasonUntoleratedTaint               = "node(s) had untolerated taint {%s: %s}"
+	ReasonPodAntiAffinityMismatch        = "node(s) didn't match pod anti-affinity rules"
+	ReasonTopologySpreadConstraintsUnmet = "node(s) didn't match pod topology spread constraints"
+	ReasonInsufficientResource           = "Insufficient %s"
+)
+
+// checkSchedulingConstraints checks whether the given pod can be scheduled on the given node based on the pod's
+// node selector, node affinity, tolerations, required pod anti-affinity and topology spread constraints, given the
+// pods that are (or would be) on each target node.
+//
+// Returns the reason why the pod cannot be scheduled on the node, or an empty string if it can.
+//
+// This is a simplified version of the kube-scheduler's filters: only the target nodes are taken into account when
+// evaluating pod anti-affinity and topology spread constraints, and non-empty namespace selectors are ignored.
+func checkSchedulingConstraints(pod *v1.Pod, node *targetNode, nodes []*targetNode) string {
+	if node.node.Spec.Unschedulable {
+		return ReasonNodeUnschedulable
+	}
+	requiredNodeAffinity := nodeaffinity.GetRequiredNodeAffinity(pod)
+	if matches, err := requiredNodeAffinity.Match(node.node); err != nil || !matches {
+		return ReasonNodeAffinityMismatch
+	}
+	if taint, isUntolerated := findUntoleratedTaint(pod, node.node); isUntolerated {
+		return fmt.Sprintf(ReasonUntoleratedTaint, taint.Key, taint.Value)
+	}
+	if violatesPodAntiAffinity(pod, node, nodes) {
+		return ReasonPodAntiAffinityMismatch
+	}
+	if violatesTopologySpreadConstraints(pod, node, nodes, requiredNodeAffinity) {
+		return ReasonTopologySpreadConstraintsUnmet
+	}
+	return ""
+}
+
+// findUntoleratedTaint returns the first NoSchedule or NoExecute taint of the node that the pod doesn't tolerate
+func findUntoleratedTaint(pod *v1.Pod, node *v1.Node) (v1.Taint, bool) {
+	return corev1helpers.FindMatchingUntoleratedTaint(klog.Background(), node.Spec.Taints, pod.Spec.Tolerations, func(taint *v1.Taint) bool {
+		return taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute
+	}, false)
+}
+
+// violatesPodAntiAffinity checks whether scheduling the pod on the node would violate the required pod
+// anti-affinity of either the pod itself or of the pods in the same topology domain
+func violatesPodAntiAffinity(pod *v1.Pod, node *targetNode, nodes []*targetNode) bool {
+	for _, term := range getRequiredPodAntiAffinityTerms(pod) {
+		for _, otherNode := range nodes {
+			if !inSameTopologyDomain(node.node, otherNode.node, term.TopologyKey) {
+				continue
+			}
+			for _, otherPod := range otherNode.pods {
+				if podMatchesAffinityTerm(otherPod, pod, term) {
+					return true
+				}
+			}
+		}
+	}
+	for _, otherNode := range nodes {
+		for _, otherPod := range otherNode.pods {
+			for _, term := range getRequiredPodAntiAffinityTerms(otherPod) {
+				if inSameTopologyDomain(node.node, otherNode.node, term.TopologyKey) && podMatchesAffinityTerm(pod, otherPod, term) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func getRequiredPodAntiAffinityTerms(pod *v1.Pod) []v1.PodAffinityTerm {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAntiAffinity == nil {
+		return nil
+	}
+	return pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+}
+
+// inSameTopologyDomain checks whether both nodes have the same value for the given topology key
+func inSameTopologyDomain(node, otherNode *v1.Node, topologyKey string) bool {
+	value, ok := node.Labels[topologyKey]
+	if !ok {
+		return false
+	}
+	otherValue, ok := otherNode.Labels[topologyKey]
+	return ok && value == otherValue
+}
+
+// podMatchesAffinityTerm checks whether a pod is matched by an affinity term belonging to termOwner
+func podMatchesAffinityTerm(pod, termOwner *v1.Pod, term v1.PodAffinityTerm) bool {
+	if len(term.Namespaces) == 0 && term.NamespaceSelector == nil {
+		if pod.Namespace != termOwner.Namespace {
+			return false
+		}
+	} else if term.NamespaceSelector == nil || len(term.NamespaceSelector.MatchLabels) > 0 || len(term.NamespaceSelector.MatchExpressions) > 0 {
+		// An empty namespace selector matches every namespace, but we can't evaluate non-empty ones without the
+		// namespaces' labels, so only the namespaces that are explicitly listed are taken into account
+		matchesNamespace := false
+		for _, namespace := range term.Namespaces {
+			if namespace == pod.Namespace {
+				matchesNamespace = true
+				break
+			}
+		}
+		if !matchesNamespace {
+			return false
+		}
+	}
+	return labelSelectorMatches(term.LabelSelector, pod)
+}
+
+// violatesTopologySpreadConstraints checks whether scheduling the pod on the node would violate one of the pod's
+// topology spread constraints with whenUnsatisfiable set to DoNotSchedule
+func violatesTopologySpreadConstraints(pod *v1.Pod, node *targetNode, nodes []*targetNode, requiredNodeAffinity nodeaffinity.RequiredNodeAffinity) bool {
+	for _, constraint := range pod.Spec.TopologySpreadConstraints {
+		if constraint.WhenUnsatisfiable != v1.DoNotSchedule {
+			continue
+		}
+		domain, ok := node.node.Labels[constraint.TopologyKey]
+		if !ok {
+			return true
+		}
+		// Count the number of matching pods in each domain, only taking into account the nodes that the pod could
+		// be scheduled on according to its node affinity
+		matchingPodsByDomain := make(map[string]int)
+		for _, otherNode := range nodes {
+			otherDomain, ok := otherNode.node.Labels[constraint.TopologyKey]
+			if !ok {
+				continue
+			}
+			if matches, err := requiredNodeAffinity.Match(otherNode.node); err != nil || !matches {
+				continue
+			}
+			if _, exists := matchingPodsByDomain[otherDomain]; !exists {
+				matchingPodsByDomain[otherDomain] = 0
+			}
+			for _, otherPod := range otherNode.pods {
+				if otherPod.Namespace == pod.Namespace && labelSelectorMatches(constraint.LabelSelector, otherPod) {
+					matchingPodsByDomain[otherDomain]++
+				}
+			}
+		}
+		minimumMatchingPods := math.MaxInt
+		for _, matchingPods := range matchingPodsByDomain {
+			minimumMatchingPods = min(minimumMatchingPods, matchingPods)
+		}
+		selfMatch := 0
+		if labelSelectorMatches(constraint.LabelSelector, pod) {
+			selfMatch = 1
+		}
+		if matchingPodsByDomain[domain]+selfMatch-minimumMatchingPods > int(constraint.MaxSkew) {
+			return true
+		}
+	}
+	return false
+}
+
+// labelSelectorMatches checks whether a label selector matches the labels of a pod. A nil selector matches nothing.
+func labelSelectorMatches(labelSelector *metav1.LabelSelector, pod *v1.Pod) bool {
+	if labelSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(pod.Labels))
+}
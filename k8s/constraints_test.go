@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8stest"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSimulateSchedulingOfPodsFromOldNodes_withNodeSelector(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "1000m", "1000Mi")
+	secondNewNode.Labels["workload"] = "batch"
+	pod := k8stest.CreateTestPod("pod", oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+	pod.Spec.NodeSelector = map[string]string{"workload": "batch"}
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, []v1.Pod{pod})
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if simulation.Placements["/pod"] != secondNewNode.Name {
+		t.Error("the pod should've been placed on the only node matching its node selector, got", simulation.Placements["/pod"])
+	}
+
+	simulation, err = SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if simulation.Fits() {
+		t.Fatal("the pod shouldn't have fit, because no target node matches its node selector")
+	}
+	if reason := simulation.UnschedulablePods[0].Reason; reason != "0/1 updated nodes are available: 1 node(s) didn't match Pod's node affinity/selector" {
+		t.Error("unexpected reason:", reason)
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_withTaints(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	newNode := k8stest.CreateTestNode("new-node", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	newNode.Spec.Taints = []v1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule},
+		{Key: "preferred", Value: "true", Effect: v1.TaintEffectPreferNoSchedule},
+	}
+	pod := k8stest.CreateTestPod("pod", oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{pod})
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&newNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if simulation.Fits() {
+		t.Fatal("the pod shouldn't have fit, because it doesn't tolerate the target node's taint")
+	}
+	if reason := simulation.UnschedulablePods[0].Reason; reason != "0/1 updated nodes are available: 1 node(s) had untolerated taint {dedicated: gpu}" {
+		t.Error("unexpected reason:", reason)
+	}
+
+	// PreferNoSchedule taints don't need to be tolerated
+	pod.Spec.Tolerations = []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+	mockClient.Pods[pod.Name] = pod
+	simulation, err = SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&newNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !simulation.Fits() {
+		t.Error("the pod should've fit, because it tolerates the target node's taint, but", simulation.String())
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_withPodAntiAffinity(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	firstNewNode.Labels[v1.LabelHostname] = firstNewNode.Name
+	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "1000m", "1000Mi")
+	secondNewNode.Labels[v1.LabelHostname] = secondNewNode.Name
+	antiAffinity := &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			TopologyKey:   v1.LabelHostname,
+		}},
+	}}
+	var pods []v1.Pod
+	for _, name := range []string{"web-1", "web-2", "web-3"} {
+		pod := k8stest.CreateTestPod(name, oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+		pod.Labels = map[string]string{"app": "web"}
+		pod.Spec.Affinity = antiAffinity
+		pods = append(pods, pod)
+	}
+	// web-1 is already running on new-node-1, so only one more replica can be placed, on new-node-2
+	pods[0].Spec.NodeName = firstNewNode.Name
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, pods)
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(simulation.Placements) != 1 || simulation.Placements["/web-2"] != secondNewNode.Name {
+		t.Error("web-2 should've been placed on new-node-2, got", simulation.Placements)
+	}
+	if len(simulation.UnschedulablePods) != 1 {
+		t.Fatal("web-3 shouldn't have fit, got", simulation.String())
+	}
+	if reason := simulation.UnschedulablePods[0].Reason; reason != "0/2 updated nodes are available: 2 node(s) didn't match pod anti-affinity rules" {
+		t.Error("unexpected reason:", reason)
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_withTopologySpreadConstraints(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	firstNewNode.Labels[v1.LabelTopologyZone] = "us-west-2a"
+	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2b", "i-0147ad0816c210dae", "1000m", "1000Mi")
+	secondNewNode.Labels[v1.LabelTopologyZone] = "us-west-2b"
+	var pods []v1.Pod
+	for _, name := range []string{"web-1", "web-2", "web-3", "web-4"} {
+		pod := k8stest.CreateTestPod(name, oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+		pod.Labels = map[string]string{"app": "web"}
+		pod.Spec.TopologySpreadConstraints = []v1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       v1.LabelTopologyZone,
+			WhenUnsatisfiable: v1.DoNotSchedule,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		}}
+		pods = append(pods, pod)
+	}
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, pods)
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !simulation.Fits() {
+		t.Fatal("every pod should've fit, but", simulation.String())
+	}
+	podsByNode := make(map[string]int)
+	for _, nodeName := range simulation.Placements {
+		podsByNode[nodeName]++
+	}
+	if podsByNode[firstNewNode.Name] != 2 || podsByNode[secondNewNode.Name] != 2 {
+		t.Error("the pods should've been spread evenly across both zones, got", podsByNode)
+	}
+
+	// Nodes without the topology key are not eligible, and a single domain can never be skewed
+	secondNewNode.Labels[v1.LabelTopologyZone] = "us-west-2a"
+	delete(firstNewNode.Labels, v1.LabelTopologyZone)
+	simulation, err = SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !simulation.Fits() {
+		t.Fatal("every pod should've fit on new-node-2, since its zone is the only domain, but", simulation.String())
+	}
+	for podName, nodeName := range simulation.Placements {
+		if nodeName != secondNewNode.Name {
+			t.Errorf("%s shouldn't have been placed on %s, because it doesn't have the topology key", podName, nodeName)
+		}
+	}
+}
+
+// shuffledPodsClient returns the pods of a node in a random order
+type shuffledPodsClient struct {
+	*k8stest.MockClient
+}
+
+func (client shuffledPodsClient) GetPodsInNode(node string) ([]v1.Pod, error) {
+	pods, err := client.MockClient.GetPodsInNode(node)
+	rand.Shuffle(len(pods), func(i, j int) {
+		pods[i], pods[j] = pods[j], pods[i]
+	})
+	return pods, err
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_isDeterministic(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "1000m", "1000Mi")
+	var pods []v1.Pod
+	for i := 1; i <= 7; i++ {
+		pods = append(pods, k8stest.CreateTestPod(fmt.Sprintf("pod-%d", i), oldNode.Name, "300m", "100Mi", false, v1.PodRunning))
+	}
+	client := shuffledPodsClient{MockClient: k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, pods)}
+
+	expected, err := SimulateSchedulingOfPodsFromOldNodes(client, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(expected.Placements) != 6 || len(expected.UnschedulablePods) != 1 {
+		t.Fatal("6 pods should've fit, but", expected.String())
+	}
+	for i := 0; i < 20; i++ {
+		simulation, err := SimulateSchedulingOfPodsFromOldNodes(client, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if !reflect.DeepEqual(simulation.Placements, expected.Placements) {
+			t.Fatalf("placements should've been the same regardless of the order of the pods, expected %v, got %v", expected.Placements, simulation.Placements)
+		}
+		if simulation.UnschedulablePods[0].Pod.Name != expected.UnschedulablePods[0].Pod.Name {
+			t.Fatalf("the same pod should've been unschedulable, expected %s, got %s", expected.UnschedulablePods[0].Pod.Name, simulation.UnschedulablePods[0].Pod.Name)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package k8s
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// EventComponent is the component reported as the source of every event
+const EventComponent = "aws-eks-asg-rolling-update-handler"
+
+const (
+	EventReasonRollingUpdateStarted         = "RollingUpdateStarted"
+	EventReasonScaledUp                     = "RollingUpdateScaledUp"
+	EventReasonDrainStarted                 = "RollingUpdateDrainStarted"
+	EventReasonDrainFailed                  = "RollingUpdateDrainFailed"
+	EventReasonDrainInterrupted             = "RollingUpdateDrainInterrupted"
+	EventReasonDrainEscalated               = "RollingUpdateDrainEscalated"
+	EventReasonBlockedByPodDisruptionBudget = "RollingUpdateBlockedByPodDisruptionBudget"
+	EventReasonTerminated                   = "RollingUpdateTerminated"
+	EventReasonTerminationStuck             = "RollingUpdateTerminationStuck"
+	EventReasonRolloutAborted               = "RollingUpdateAborted"
+	EventReasonTaintRemoved                 = "RollingUpdateTaintRemoved"
+	EventReasonEvicted                      = "RollingUpdateEvicted"
+)
+
+// NewEventRecorder creates an EventRecorder that publishes events to the cluster
+func NewEventRecorder(client kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: EventComponent})
+}
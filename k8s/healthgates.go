@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// CheckNodeHealthGates checks whether the given node passes every health gate, that is, whether it has the required
+// node conditions and labels, none of the startup taints, and a running and ready pod for every required DaemonSet.
+//
+// Returns an error describing the first health gate that the node doesn't pass, if any
+func CheckNodeHealthGates(client ClientAPI, node *v1.Node, healthGates config.HealthGates) error {
+	for conditionType, status := range healthGates.NodeConditions {
+		condition := GetNodeCondition(node, v1.NodeConditionType(conditionType))
+		if condition == nil {
+			return fmt.Errorf("node doesn't have condition %s", conditionType)
+		}
+		if string(condition.Status) != status {
+			return fmt.Errorf("node condition %s is %s instead of %s", conditionType, condition.Status, status)
+		}
+	}
+	for key, value := range healthGates.NodeLabels {
+		actualValue, ok := node.Labels[key]
+		if !ok {
+			return fmt.Errorf("node doesn't have label %s", key)
+		}
+		if len(value) > 0 && actualValue != value {
+			return fmt.Errorf("node label %s is '%s' instead of '%s'", key, actualValue, value)
+		}
+	}
+	for _, taintKey := range healthGates.StartupTaints {
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == taintKey {
+				return fmt.Errorf("node still has startup taint %s", taintKey)
+			}
+		}
+	}
+	if len(healthGates.DaemonSets) == 0 {
+		return nil
+	}
+	podsInNode, err := client.GetPodsInNode(node.Name)
+	if err != nil {
+		return fmt.Errorf("unable to get pods in node: %w", err)
+	}
+	for _, daemonSet := range healthGates.DaemonSets {
+		if !hasRunningAndReadyDaemonSetPod(podsInNode, daemonSet) {
+			return fmt.Errorf("node doesn't have a running and ready pod for DaemonSet %s", daemonSet)
+		}
+	}
+	return nil
+}
+
+// GetNodeCondition returns the condition of the given type of a node, or nil if the node doesn't have it
+func GetNodeCondition(node *v1.Node, conditionType v1.NodeConditionType) *v1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == conditionType {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// hasRunningAndReadyDaemonSetPod checks whether one of the given pods is a running and ready pod of the given
+// DaemonSet, formatted as <namespace>/<name>
+func hasRunningAndReadyDaemonSetPod(pods []v1.Pod, daemonSet string) bool {
+	namespace, name, _ := strings.Cut(daemonSet, "/")
+	for _, pod := range pods {
+		if pod.Namespace != namespace || pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		ownedByDaemonSet := false
+		for _, owner := range pod.OwnerReferences {
+			if owner.Kind == "DaemonSet" && owner.Name == name {
+				ownedByDaemonSet = true
+				break
+			}
+		}
+		if !ownedByDaemonSet {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+				return true
+			}
+		}
+	}
+	return false
+}
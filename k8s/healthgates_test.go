@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
+	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8stest"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckNodeHealthGates(t *testing.T) {
+	node := k8stest.CreateTestNode("node", "us-west-2a", "i-034fa1dfbfd35f8bb", "1000m", "1000Mi")
+	node.Status.Conditions = []v1.NodeCondition{
+		{Type: "KernelDeadlock", Status: v1.ConditionFalse},
+		{Type: v1.NodeReady, Status: v1.ConditionTrue},
+		{Type: "NetworkingReady", Status: v1.ConditionTrue},
+	}
+	node.Labels["node.example.com/cni-ready"] = "true"
+	node.Spec.Taints = []v1.Taint{{Key: "node.example.com/csi-not-ready", Effect: v1.TaintEffectNoSchedule}}
+	readyPod := k8stest.CreateTestPod("aws-node-abcde", node.Name, "10m", "10Mi", true, v1.PodRunning)
+	readyPod.Namespace = "kube-system"
+	readyPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "aws-node"}}
+	readyPod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+	nonReadyPod := k8stest.CreateTestPod("node-local-dns-abcde", node.Name, "10m", "10Mi", true, v1.PodRunning)
+	nonReadyPod.Namespace = "kube-system"
+	nonReadyPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-local-dns"}}
+	nonReadyPod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}}
+	mockClient := k8stest.NewMockClient([]v1.Node{node}, []v1.Pod{readyPod, nonReadyPod})
+	scenarios := []struct {
+		name          string
+		healthGates   config.HealthGates
+		expectedError bool
+	}{
+		{name: "no-health-gates", healthGates: config.HealthGates{}},
+		{name: "node-conditions", healthGates: config.HealthGates{NodeConditions: map[string]string{"NetworkingReady": "True", "KernelDeadlock": "False"}}},
+		{name: "node-condition-with-wrong-status", healthGates: config.HealthGates{NodeConditions: map[string]string{"KernelDeadlock": "True"}}, expectedError: true},
+		{name: "missing-node-condition", healthGates: config.HealthGates{NodeConditions: map[string]string{"StorageReady": "True"}}, expectedError: true},
+		{name: "node-label", healthGates: config.HealthGates{NodeLabels: map[string]string{"node.example.com/cni-ready": "true"}}},
+		{name: "node-label-with-any-value", healthGates: config.HealthGates{NodeLabels: map[string]string{"node.example.com/cni-ready": ""}}},
+		{name: "node-label-with-wrong-value", healthGates: config.HealthGates{NodeLabels: map[string]string{"node.example.com/cni-ready": "false"}}, expectedError: true},
+		{name: "missing-node-label", healthGates: config.HealthGates{NodeLabels: map[string]string{"node.example.com/dns-ready": ""}}, expectedError: true},
+		{name: "startup-taint-removed", healthGates: config.HealthGates{StartupTaints: []string{"node.example.com/cni-not-ready"}}},
+		{name: "startup-taint-still-present", healthGates: config.HealthGates{StartupTaints: []string{"node.example.com/csi-not-ready"}}, expectedError: true},
+		{name: "daemon-set-pod-ready", healthGates: config.HealthGates{DaemonSets: []string{"kube-system/aws-node"}}},
+		{name: "daemon-set-pod-not-ready", healthGates: config.HealthGates{DaemonSets: []string{"kube-system/node-local-dns"}}, expectedError: true},
+		{name: "daemon-set-pod-in-other-namespace", healthGates: config.HealthGates{DaemonSets: []string{"default/aws-node"}}, expectedError: true},
+		{name: "missing-daemon-set-pod", healthGates: config.HealthGates{DaemonSets: []string{"kube-system/ebs-csi-node"}}, expectedError: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			err := CheckNodeHealthGates(mockClient, &node, scenario.healthGates)
+			if (err != nil) != scenario.expectedError {
+				t.Errorf("expected error to be %v, got %v", scenario.expectedError, err)
+			}
+		})
+	}
+}
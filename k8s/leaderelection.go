@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"context"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	LeaderElectionLeaseDuration = 15 * time.Second
+	LeaderElectionRenewDeadline = 10 * time.Second
+	LeaderElectionRetryPeriod   = 2 * time.Second
+)
+
+// RunLeaderElection blocks until the given context is cancelled, competing with every other replica for the
+// Lease with the given name in the given namespace.
+//
+// onStartedLeading is called once the Lease has been acquired, and onStoppedLeading is called once it has been lost
+// or released. onNewLeader is called with the identity of the leader every time it changes, including when this
+// replica becomes the leader. The Lease is released as soon as the given context is cancelled, so that another replica can take over
+// without waiting for it to expire, which means that the context must only be cancelled once every step started
+// by onStartedLeading has completed.
+func RunLeaderElection(ctx context.Context, client kubernetes.Interface, namespace, leaseName, identity string, onStartedLeading func(ctx context.Context), onStoppedLeading func(), onNewLeader func(currentLeader string)) error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: namespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: LeaderElectionLeaseDuration,
+		RenewDeadline: LeaderElectionRenewDeadline,
+		RetryPeriod:   LeaderElectionRetryPeriod,
+		// Releasing the Lease on cancel saves the other replicas from waiting for it to expire on every shutdown
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Printf("[%s/%s] Acquired lease as %s", namespace, leaseName, identity)
+				onStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				log.Printf("[%s/%s] Lost lease as %s", namespace, leaseName, identity)
+				onStoppedLeading()
+			},
+			OnNewLeader: func(currentLeader string) {
+				if currentLeader != identity {
+					log.Printf("[%s/%s] Current leader is %s, waiting until the lease can be acquired", namespace, leaseName, currentLeader)
+				}
+				onNewLeader(currentLeader)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	elector.Run(ctx)
+	return nil
+}
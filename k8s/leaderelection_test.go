@@ -0,0 +1,39 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunLeaderElection(t *testing.T) {
+	fakeKubernetesClient := fakekubernetes.NewSimpleClientset()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	startedLeading, stoppedLeading := false, false
+	err := RunLeaderElection(ctx, fakeKubernetesClient, "kube-system", "lease", "replica-1", func(_ context.Context) {
+		startedLeading = true
+		cancel()
+	}, func() {
+		stoppedLeading = true
+	}, func(_ string) {})
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if !startedLeading {
+		t.Error("should've started leading")
+	}
+	if !stoppedLeading {
+		t.Error("should've stopped leading once the context was cancelled")
+	}
+	lease, err := fakeKubernetesClient.CoordinationV1().Leases("kube-system").Get(context.TODO(), "lease", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Lease should've been created:", err)
+	}
+	if lease.Spec.HolderIdentity != nil && len(*lease.Spec.HolderIdentity) > 0 {
+		t.Error("Lease should've been released once the context was cancelled, but is held by", *lease.Spec.HolderIdentity)
+	}
+}
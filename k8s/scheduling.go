@@ -0,0 +1,247 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	resourcehelper "k8s.io/component-helpers/resource"
+)
+
+// SchedulingSimulation is the result of simulating the scheduling of the pods from one or more old nodes onto
+// target nodes
+type SchedulingSimulation struct {
+	// Placements maps each pod that could be placed (namespace/name) to the name of the target node it was placed on
+	Placements map[string]string
+	// UnschedulablePods are the pods that couldn't be placed on any target node
+	UnschedulablePods []UnschedulablePod
+}
+
+// UnschedulablePod is a pod that couldn't be placed on any target node, along with the reason why
+type UnschedulablePod struct {
+	Pod    *v1.Pod
+	Reason string
+}
+
+// Fits returns whether every pod could be placed on a target node
+func (s *SchedulingSimulation) Fits() bool {
+	return len(s.UnschedulablePods) == 0
+}
+
+// String returns a human-readable explanation of the simulation's result
+func (s *SchedulingSimulation) String() string {
+	if s.Fits() {
+		return fmt.Sprintf("all %d pod(s) fit on the updated nodes", len(s.Placements))
+	}
+	var explanations []string
+	for _, unschedulablePod := range s.UnschedulablePods {
+		explanations = append(explanations, fmt.Sprintf("%s/%s (%s)", unschedulablePod.Pod.Namespace, unschedulablePod.Pod.Name, unschedulablePod.Reason))
+	}
+	return fmt.Sprintf("%d pod(s) do not fit on the updated nodes: %s", len(s.UnschedulablePods), strings.Join(explanations, "; "))
+}
+
+// targetNode is a node on which pods can be placed during a scheduling simulation
+type targetNode struct {
+	node      *v1.Node
+	available resources
+	// pods are the pods running on the node, as well as the pods that have been placed on it by the simulation
+	pods []*v1.Pod
+}
+
+// SimulateSchedulingOfPodsFromOldNodes places every pod from the old nodes that would need to be rescheduled if the
+// old nodes were drained on a specific target node using first-fit-decreasing: pods are sorted by decreasing
+// resource requests, and each pod is placed on the first target node that satisfies the pod's scheduling constraints
+// (see checkSchedulingConstraints) and still has enough resources available.
+//
+// Unlike adding up the resources available across all target nodes, this doesn't let a pod "fit" in resources that
+// are spread across several nodes (e.g. a 2G pod in two nodes with 1G available each).
+func SimulateSchedulingOfPodsFromOldNodes(client ClientAPI, oldNodes []*v1.Node, targetNodes []*v1.Node) (*SchedulingSimulation, error) {
+	var podsToSchedule []*v1.Pod
+	for _, oldNode := range oldNodes {
+		podsInNode, err := client.GetPodsInNode(oldNode.Name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get pods in old node %s: %w", oldNode.Name, err)
+		}
+		for i := range podsInNode {
+			// DaemonSets in the old node will also be present in the target nodes, so they're ignored
+			if isPodEvictedOnDrain(podsInNode[i]) {
+				podsToSchedule = append(podsToSchedule, &podsInNode[i])
+			}
+		}
+	}
+	var nodes []*targetNode
+	largestAllocatable := make(resources)
+	for _, node := range targetNodes {
+		podsInNode, err := client.GetPodsInNode(node.Name)
+		if err != nil {
+			continue
+		}
+		target := &targetNode{node: node, available: getAllocatableResources(node)}
+		for resourceName, quantity := range target.available {
+			largestAllocatable[resourceName] = max(largestAllocatable[resourceName], quantity)
+		}
+		for i := range podsInNode {
+			// Skip pods that have terminated (e.g. "Evicted" pods that haven't been cleaned up)
+			if podsInNode[i].Status.Phase == v1.PodFailed || podsInNode[i].Status.Phase == v1.PodSucceeded {
+				continue
+			}
+			target.available.subtract(getPodRequests(&podsInNode[i]))
+			target.pods = append(target.pods, &podsInNode[i])
+		}
+		nodes = append(nodes, target)
+	}
+	// Pods with the same requests are sorted by name, so that the simulation doesn't depend on the order in which
+	// pods are retrieved
+	sort.SliceStable(podsToSchedule, func(i, j int) bool {
+		return podsToSchedule[i].Namespace+"/"+podsToSchedule[i].Name < podsToSchedule[j].Namespace+"/"+podsToSchedule[j].Name
+	})
+	sort.SliceStable(podsToSchedule, func(i, j int) bool {
+		return getPodRequests(podsToSchedule[i]).isLargerThan(getPodRequests(podsToSchedule[j]), largestAllocatable)
+	})
+	simulation := &SchedulingSimulation{Placements: make(map[string]string)}
+	for _, pod := range podsToSchedule {
+		requests := getPodRequests(pod)
+		placed := false
+		reasons := make(map[string]int)
+		for _, node := range nodes {
+			if reason := checkSchedulingConstraints(pod, node, nodes); len(reason) > 0 {
+				reasons[reason]++
+				continue
+			}
+			if missingResources := node.available.missing(requests); len(missingResources) > 0 {
+				for _, missingResource := range missingResources {
+					reasons[fmt.Sprintf(ReasonInsufficientResource, missingResource)]++
+				}
+				continue
+			}
+			node.available.subtract(requests)
+			node.pods = append(node.pods, pod)
+			simulation.Placements[pod.Namespace+"/"+pod.Name] = node.node.Name
+			placed = true
+			break
+		}
+		if !placed {
+			simulation.UnschedulablePods = append(simulation.UnschedulablePods, UnschedulablePod{
+				Pod:    pod,
+				Reason: explainUnschedulable(len(nodes), reasons),
+			})
+		}
+	}
+	return simulation, nil
+}
+
+// explainUnschedulable returns an explanation similar to the kube-scheduler's, e.g.
+// "0/3 updated nodes are available: 1 node(s) didn't match Pod's node affinity/selector, 2 Insufficient memory"
+func explainUnschedulable(numberOfTargetNodes int, numberOfNodesByReason map[string]int) string {
+	if numberOfTargetNodes == 0 {
+		return "no updated nodes are available"
+	}
+	var reasons []string
+	for reason, numberOfNodes := range numberOfNodesByReason {
+		reasons = append(reasons, fmt.Sprintf("%d %s", numberOfNodes, reason))
+	}
+	sort.Strings(reasons)
+	return fmt.Sprintf("0/%d updated nodes are available: %s", numberOfTargetNodes, strings.Join(reasons, ", "))
+}
+
+// isPodEvictedOnDrain returns whether a pod would be evicted if its node were drained, meaning that it would
+// need to be rescheduled somewhere else
+func isPodEvictedOnDrain(pod v1.Pod) bool {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return false
+	}
+	if _, isMirrorPod := pod.Annotations[v1.MirrorPodAnnotationKey]; isMirrorPod {
+		return false
+	}
+	return !isDaemonSetPod(pod)
+}
+
+// resources are amounts of resources in milli-units (e.g. millicores for CPU, millibytes for memory and
+// ephemeral-storage, and thousandths of a pod for the number of pods) indexed by resource name
+type resources map[v1.ResourceName]int64
+
+// getAllocatableResources returns the resources of a node that can be requested by pods, including the number of
+// pods and any extended resources (e.g. nvidia.com/gpu)
+func getAllocatableResources(node *v1.Node) resources {
+	allocatable := make(resources, len(node.Status.Allocatable))
+	for resourceName, quantity := range node.Status.Allocatable {
+		allocatable[resourceName] = quantity.MilliValue()
+	}
+	return allocatable
+}
+
+// getPodRequests returns the effective requests of a pod the way the kube-scheduler computes them: the sum of the
+// requests of its containers, or the highest request of its init containers if that's higher (taking sidecar
+// containers into account), plus the pod's overhead.
+//
+// Every pod also requests one pod, so that the node's maximum number of pods is taken into account.
+func getPodRequests(pod *v1.Pod) resources {
+	podRequests := resourcehelper.PodRequests(pod, resourcehelper.PodResourcesOptions{})
+	requests := make(resources, len(podRequests)+1)
+	for resourceName, quantity := range podRequests {
+		requests[resourceName] = quantity.MilliValue()
+	}
+	requests[v1.ResourcePods] = 1000
+	return requests
+}
+
+func (r resources) subtract(other resources) {
+	for resourceName, quantity := range other {
+		r[resourceName] -= quantity
+	}
+}
+
+// missing returns the name of every resource for which there isn't enough available to satisfy the requests.
+// Resources that aren't allocatable on the node at all (e.g. nvidia.com/gpu on a node without GPUs) are considered
+// to have nothing available.
+func (r resources) missing(requests resources) []string {
+	var missingResources []string
+	for resourceName, quantity := range requests {
+		if quantity > 0 && quantity > r[resourceName] {
+			missingResources = append(missingResources, string(resourceName))
+		}
+	}
+	sort.Strings(missingResources)
+	return missingResources
+}
+
+// size returns the sum of the requests for every resource relative to the largest amount of that resource allocatable
+// on a single target node, so that every requested resource, including extended resources (e.g. nvidia.com/gpu),
+// weighs in proportion to how scarce it is. A resource that isn't allocatable on any target node counts as a whole
+// node.
+//
+// Resources are summed up in the same order every time, otherwise rounding errors could break ties between pods with
+// the same requests differently from one simulation to the next.
+func (r resources) size(largestAllocatable resources) float64 {
+	resourceNames := make([]string, 0, len(r))
+	for resourceName := range r {
+		resourceNames = append(resourceNames, string(resourceName))
+	}
+	sort.Strings(resourceNames)
+	var size float64
+	for _, name := range resourceNames {
+		resourceName, quantity := v1.ResourceName(name), r[v1.ResourceName(name)]
+		if quantity <= 0 {
+			continue
+		}
+		if largestAllocatable[resourceName] <= 0 {
+			size++
+			continue
+		}
+		size += float64(quantity) / float64(largestAllocatable[resourceName])
+	}
+	return size
+}
+
+// isLargerThan is used to sort pods by decreasing requests; the size of the requests relative to the largest amount
+// allocatable on a single target node is compared first (see size), followed by CPU and then memory
+func (r resources) isLargerThan(other resources, largestAllocatable resources) bool {
+	if size, otherSize := r.size(largestAllocatable), other.size(largestAllocatable); size != otherSize {
+		return size > otherSize
+	}
+	if r[v1.ResourceCPU] != other[v1.ResourceCPU] {
+		return r[v1.ResourceCPU] > other[v1.ResourceCPU]
+	}
+	return r[v1.ResourceMemory] > other[v1.ResourceMemory]
+}
@@ -1,123 +1,132 @@
 package k8s
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-// CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode calculates the resources available in the target nodes
-// and compares them with the resources that would be required if the old node were to be drained
+// GetPodDisruptionBudgetsBlockingDrain returns the PodDisruptionBudgets that would prevent at least one of the pods
+// on the given node from being evicted, that is, the PodDisruptionBudgets matching a pod on the node while allowing
+// no disruptions.
 //
-// This is not foolproof: 2 targetNodes with 1G available in each would cause the assumption that you can fit
-// a 2G pod in the targetNodes when you obviously can't (you'd need 1 node with 2G available, not 2 with 1G)
-// That's alright, because the purpose is to provide a smooth rolling upgrade, not a flawless experience,  and
-// while the latter is definitely possible, it would slow down the process by quite a bit. In a way, this is
-// the beauty of co-existing with the cluster autoscaler; an extra node will be spun up to handle the leftovers,
-// if any.
-func CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(client ClientAPI, oldNode *v1.Node, targetNodes []*v1.Node) bool {
-	totalAvailableTargetCPU := int64(0)
-	totalAvailableTargetMemory := int64(0)
-	// Get resources available in target nodes
-	for _, targetNode := range targetNodes {
-		availableTargetCPU := targetNode.Status.Allocatable.Cpu().MilliValue()
-		availableTargetMemory := targetNode.Status.Allocatable.Memory().MilliValue()
-		podsInNode, err := client.GetPodsInNode(targetNode.Name)
-		if err != nil {
-			continue
-		}
-		for _, podInNode := range podsInNode {
-			// Skip pods that have terminated (e.g. "Evicted" pods that haven't been cleaned up)
-			if podInNode.Status.Phase == v1.PodFailed {
-				continue
-			}
-			for _, container := range podInNode.Spec.Containers {
-				if container.Resources.Requests.Cpu() != nil {
-					// Subtract the cpu request of the pod from the node's total allocatable cpu
-					availableTargetCPU -= container.Resources.Requests.Cpu().MilliValue()
-				}
-				if container.Resources.Requests.Memory() != nil {
-					// Subtract the memory request of the pod from the node's total allocatable memory
-					totalAvailableTargetMemory -= container.Resources.Requests.Memory().MilliValue()
-				}
-			}
-		}
-		totalAvailableTargetCPU += availableTargetCPU
-		totalAvailableTargetMemory += availableTargetMemory
-	}
-	cpuNeeded := int64(0)
-	memoryNeeded := int64(0)
-	// Get resources requested in old node
-	podsInNode, err := client.GetPodsInNode(oldNode.Name)
+// Pods that are not evicted when draining a node (pods from DaemonSets, mirror pods and pods that have already
+// terminated) are ignored.
+func GetPodDisruptionBudgetsBlockingDrain(ctx context.Context, client ClientAPI, node *v1.Node) ([]*policyv1.PodDisruptionBudget, error) {
+	podsInNode, err := client.GetPodsInNode(node.Name)
 	if err != nil {
-		log.Printf("Unable to determine resources needed for old node, assuming that enough resources are available")
-		return true
+		return nil, err
 	}
-	for _, podInNode := range podsInNode {
-		// Skip pods that have terminated (e.g. "Evicted" pods that haven't been cleaned up)
-		if podInNode.Status.Phase == v1.PodFailed {
+	var blockingPodDisruptionBudgets []*policyv1.PodDisruptionBudget
+	podDisruptionBudgetsByNamespace := make(map[string][]policyv1.PodDisruptionBudget)
+	alreadyBlocking := make(map[string]bool)
+	for _, pod := range podsInNode {
+		if !isPodEvictedOnDrain(pod) {
 			continue
 		}
-		// Ignore DaemonSets in the old node, because these pods will also be present in the target nodes
-		hasDaemonSetOwnerReference := false
-		for _, owner := range podInNode.GetOwnerReferences() {
-			if owner.Kind == "DaemonSet" {
-				hasDaemonSetOwnerReference = true
-				break
+		podDisruptionBudgets, ok := podDisruptionBudgetsByNamespace[pod.Namespace]
+		if !ok {
+			if podDisruptionBudgets, err = client.GetPodDisruptionBudgets(ctx, pod.Namespace); err != nil {
+				return nil, err
 			}
+			podDisruptionBudgetsByNamespace[pod.Namespace] = podDisruptionBudgets
 		}
-		if hasDaemonSetOwnerReference {
-			continue
-		}
-		for _, container := range podInNode.Spec.Containers {
-			if container.Resources.Requests.Cpu() != nil {
-				// Subtract the cpu request of the pod from the node's total allocatable
-				cpuNeeded += container.Resources.Requests.Cpu().MilliValue()
+		for i := range podDisruptionBudgets {
+			podDisruptionBudget := &podDisruptionBudgets[i]
+			if podDisruptionBudget.Status.DisruptionsAllowed > 0 || alreadyBlocking[podDisruptionBudget.Namespace+"/"+podDisruptionBudget.Name] {
+				continue
 			}
-			if container.Resources.Requests.Memory() != nil {
-				// Subtract the cpu request of the pod from the node's total allocatable
-				memoryNeeded += container.Resources.Requests.Memory().MilliValue()
+			// A nil selector matches no pods, while an empty selector matches every pod in the namespace
+			if podDisruptionBudget.Spec.Selector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(podDisruptionBudget.Spec.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector for PodDisruptionBudget %s/%s: %w", podDisruptionBudget.Namespace, podDisruptionBudget.Name, err)
+			}
+			if selector.Matches(labels.Set(pod.Labels)) {
+				alreadyBlocking[podDisruptionBudget.Namespace+"/"+podDisruptionBudget.Name] = true
+				blockingPodDisruptionBudgets = append(blockingPodDisruptionBudgets, podDisruptionBudget)
 			}
 		}
 	}
-	leftOverCPU := totalAvailableTargetCPU - cpuNeeded
-	leftOverMemory := totalAvailableTargetMemory - memoryNeeded
-	return leftOverCPU >= 0 && leftOverMemory >= 0
+	return blockingPodDisruptionBudgets, nil
 }
 
-// AnnotateNodeByAutoScalingInstance adds an annotation to the Kubernetes node represented by a given AWS instance
-func AnnotateNodeByAutoScalingInstance(client ClientAPI, instance *autoscaling.Instance, key, value string) error {
-	node, err := client.GetNodeByAutoScalingInstance(instance)
-	if err != nil {
-		return err
-	}
-	annotations := node.GetAnnotations()
-	if currentValue := annotations[key]; currentValue != value {
-		annotations[key] = value
-		node.SetAnnotations(annotations)
-		err = client.UpdateNode(node)
-		if err != nil {
-			return err
+func isDaemonSetPod(pod v1.Pod) bool {
+	for _, owner := range pod.GetOwnerReferences() {
+		if owner.Kind == "DaemonSet" {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
-// LabelNodeByAutoScalingInstance adds a Label to the Kubernetes node represented by a given AWS instance
-func LabelNodeByAutoScalingInstance(client ClientAPI, instance *autoscaling.Instance, key, value string) error {
+// NodePatch is a set of changes to make to a node's annotations, labels and schedulability in a single request
+//
+// A nil value removes the annotation or label with the given key, and a nil Unschedulable leaves the node's
+// schedulability unchanged.
+type NodePatch struct {
+	Annotations   map[string]*string
+	Labels        map[string]*string
+	Unschedulable *bool
+}
+
+// Marshal returns the NodePatch as a strategic merge patch
+func (patch NodePatch) Marshal() ([]byte, error) {
+	metadata := make(map[string]interface{})
+	if len(patch.Annotations) > 0 {
+		metadata["annotations"] = patch.Annotations
+	}
+	if len(patch.Labels) > 0 {
+		metadata["labels"] = patch.Labels
+	}
+	data := map[string]interface{}{"metadata": metadata}
+	if patch.Unschedulable != nil {
+		data["spec"] = map[string]bool{"unschedulable": *patch.Unschedulable}
+	}
+	return json.Marshal(data)
+}
+
+// PatchNodeByAutoScalingInstance applies a NodePatch to the Kubernetes node represented by a given AWS instance
+//
+// Since the node is patched rather than updated, the changes don't conflict with changes made since the node was
+// last retrieved from the informer cache, which lags behind the changes made by the handler itself.
+func PatchNodeByAutoScalingInstance(ctx context.Context, client ClientAPI, instance *autoscaling.Instance, patch NodePatch) error {
 	node, err := client.GetNodeByAutoScalingInstance(instance)
 	if err != nil {
 		return err
 	}
-	labels := node.GetLabels()
-	if currentValue := labels[key]; currentValue != value {
-		labels[key] = value
-		node.SetLabels(labels)
-		err = client.UpdateNode(node)
-		if err != nil {
-			return err
-		}
+	data, err := patch.Marshal()
+	if err != nil {
+		return err
 	}
-	return nil
+	return client.PatchNode(ctx, node.Name, data)
+}
+
+// AnnotateNodeByAutoScalingInstance adds an annotation to the Kubernetes node represented by a given AWS instance
+func AnnotateNodeByAutoScalingInstance(ctx context.Context, client ClientAPI, instance *autoscaling.Instance, key, value string) error {
+	return PatchNodeByAutoScalingInstance(ctx, client, instance, NodePatch{Annotations: map[string]*string{key: &value}})
+}
+
+// LabelNodeByAutoScalingInstance adds a Label to the Kubernetes node represented by a given AWS instance
+func LabelNodeByAutoScalingInstance(ctx context.Context, client ClientAPI, instance *autoscaling.Instance, key, value string) error {
+	return PatchNodeByAutoScalingInstance(ctx, client, instance, NodePatch{Labels: map[string]*string{key: &value}})
+}
+
+// RemoveAnnotationFromNodeByAutoScalingInstance removes an annotation from the Kubernetes node represented by a given
+// AWS instance
+func RemoveAnnotationFromNodeByAutoScalingInstance(ctx context.Context, client ClientAPI, instance *autoscaling.Instance, key string) error {
+	return PatchNodeByAutoScalingInstance(ctx, client, instance, NodePatch{Annotations: map[string]*string{key: nil}})
+}
+
+// RemoveLabelFromNodeByAutoScalingInstance removes a label from the Kubernetes node represented by a given AWS instance
+func RemoveLabelFromNodeByAutoScalingInstance(ctx context.Context, client ClientAPI, instance *autoscaling.Instance, key string) error {
+	return PatchNodeByAutoScalingInstance(ctx, client, instance, NodePatch{Labels: map[string]*string{key: nil}})
 }
@@ -1,13 +1,20 @@
 package k8s
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
 	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8stest"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(t *testing.T) {
+func TestSimulateSchedulingOfPodsFromOldNodes_withSinglePod(t *testing.T) {
 	// allocatable cpu & memory aren't used for the old node.
 	// They're only used by the target nodes (newNode, in this case) to calculate if the leftover resources from moving
 	// the pods from the old node to the new node are positive (if the leftover is negative, it means there's not enough
@@ -17,7 +24,7 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(t *t
 	oldNodePod := k8stest.CreateTestPod("old-pod-1", oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
 	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{oldNodePod})
 
-	hasEnoughResources := CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(mockClient, &oldNode, []*v1.Node{&newNode})
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{&newNode})
 	if !hasEnoughResources {
 		t.Error("should've had enough space in node")
 	}
@@ -26,14 +33,14 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(t *t
 	}
 }
 
-func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_whenNotEnoughSpaceInNewNodes(t *testing.T) {
+func TestSimulateSchedulingOfPodsFromOldNodes_whenNotEnoughSpaceInNewNodes(t *testing.T) {
 	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
 	newNode := k8stest.CreateTestNode("new-node-1", "us-west-2c", "i-0b22d79604221412c", "1000m", "1000Mi")
 	oldNodePod := k8stest.CreateTestPod("old-pod-1", oldNode.Name, "200m", "200Mi", false, v1.PodRunning)
 	newNodePod := k8stest.CreateTestPod("new-pod-1", newNode.Name, "900m", "200Mi", false, v1.PodRunning)
 	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{oldNodePod, newNodePod})
 
-	hasEnoughResources := CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(mockClient, &oldNode, []*v1.Node{&newNode})
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{&newNode})
 	if hasEnoughResources {
 		t.Error("shouldn't have had enough space in node")
 	}
@@ -42,7 +49,7 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_when
 	}
 }
 
-func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_withMultiplePods(t *testing.T) {
+func TestSimulateSchedulingOfPodsFromOldNodes_withMultiplePods(t *testing.T) {
 	oldNode := k8stest.CreateTestNode("old-node", "us-west-2c", "i-0b22d79604221412c", "0m", "0m")
 	newNode := k8stest.CreateTestNode("new-node-1", "us-west-2b", "i-07550830aef9e4179", "1000m", "1000Mi")
 	oldNodeFirstPod := k8stest.CreateTestPod("old-pod-1", oldNode.Name, "300m", "0", false, v1.PodRunning)
@@ -51,7 +58,7 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_with
 	newNodePod := k8stest.CreateTestPod("new-pod-1", newNode.Name, "200m", "200Mi", false, v1.PodRunning)
 	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{oldNodeFirstPod, oldNodeSecondPod, oldNodeThirdPod, newNodePod})
 
-	hasEnoughResources := CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(mockClient, &oldNode, []*v1.Node{&newNode})
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{&newNode})
 	if hasEnoughResources {
 		t.Error("shouldn't have had enough space in node")
 	}
@@ -60,7 +67,7 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_with
 	}
 }
 
-func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_withMultipleTargetNodes(t *testing.T) {
+func TestSimulateSchedulingOfPodsFromOldNodes_withMultipleTargetNodes(t *testing.T) {
 	oldNode := k8stest.CreateTestNode("old-node", "us-west-2b", "i-07550830aef9e4179", "0m", "0m")
 	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-034fa1dfbfd35f8bb", "1000m", "1000Mi")
 	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2b", "i-0918aff89347cef0c", "1000m", "1000Mi")
@@ -69,7 +76,7 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_with
 	oldNodeThirdPod := k8stest.CreateTestPod("old-node-pod-3", oldNode.Name, "500m", "0", false, v1.PodRunning)
 	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, []v1.Pod{oldNodeFirstPod, oldNodeSecondPod, oldNodeThirdPod})
 
-	hasEnoughResources := CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(mockClient, &oldNode, []*v1.Node{&firstNewNode, &secondNewNode})
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{&firstNewNode, &secondNewNode})
 	if !hasEnoughResources {
 		t.Error("should've had enough space in node")
 	}
@@ -78,7 +85,7 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_with
 	}
 }
 
-func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_withPodsSpreadAcrossMultipleTargetNodes(t *testing.T) {
+func TestSimulateSchedulingOfPodsFromOldNodes_withPodsSpreadAcrossMultipleTargetNodes(t *testing.T) {
 	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
 	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
 	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "1000m", "1000Mi")
@@ -89,7 +96,7 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_with
 	oldNodeThirdPod := k8stest.CreateTestPod("old-node-pod-3", oldNode.Name, "0", "500Mi", false, v1.PodRunning)
 	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, []v1.Pod{oldNodeFirstPod, oldNodeSecondPod, oldNodeThirdPod, firstNewNodePod, secondNewNodePod})
 
-	hasEnoughResources := CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(mockClient, &oldNode, []*v1.Node{&firstNewNode, &secondNewNode})
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{&firstNewNode, &secondNewNode})
 	if hasEnoughResources {
 		t.Error("shouldn't have had enough space in node")
 	}
@@ -98,24 +105,300 @@ func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_with
 	}
 }
 
-func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_withNoTargetNodes(t *testing.T) {
+func TestSimulateSchedulingOfPodsFromOldNodes_withNoTargetNodes(t *testing.T) {
 	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
 	oldNodePod := k8stest.CreateTestPod("old-node-pod-1", oldNode.Name, "500Mi", "500Mi", false, v1.PodRunning)
 	mockClient := k8stest.NewMockClient([]v1.Node{oldNode}, []v1.Pod{oldNodePod})
 
-	hasEnoughResources := CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(mockClient, &oldNode, []*v1.Node{})
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{})
 	if hasEnoughResources {
 		t.Error("there's no target nodes; there definitely shouldn't have been enough space")
 	}
 }
 
-func TestCheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode_withNoTargetNodesButOldNodeOnlyHasPodsFromDaemonSets(t *testing.T) {
+func TestSimulateSchedulingOfPodsFromOldNodes_withNoTargetNodesButOldNodeOnlyHasPodsFromDaemonSets(t *testing.T) {
 	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
 	oldNodePod := k8stest.CreateTestPod("old-node-pod-1", oldNode.Name, "500Mi", "500Mi", true, v1.PodRunning)
 	mockClient := k8stest.NewMockClient([]v1.Node{oldNode}, []v1.Pod{oldNodePod})
 
-	hasEnoughResources := CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(mockClient, &oldNode, []*v1.Node{})
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{})
 	if !hasEnoughResources {
 		t.Error("there's no target nodes, but the only pods in the old node are from daemon sets")
 	}
 }
+
+// podsFromOldNodeFit checks whether every pod from the old node that would need to be rescheduled fits on one of the
+// target nodes
+func podsFromOldNodeFit(t *testing.T, client ClientAPI, oldNode *v1.Node, targetNodes []*v1.Node) bool {
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(client, []*v1.Node{oldNode}, targetNodes)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return simulation.Fits()
+}
+
+func TestGetPodDisruptionBudgetsBlockingDrain(t *testing.T) {
+	node := k8stest.CreateTestNode("node", "us-west-2a", "i-034fa1dfbfd35f8bb", "1000m", "1000Mi")
+	pod := k8stest.CreateTestPod("pod", node.Name, "100m", "100Mi", false, v1.PodRunning)
+	pod.SetLabels(map[string]string{"app": "app"})
+	daemonSetPod := k8stest.CreateTestPod("daemon-set-pod", node.Name, "100m", "100Mi", true, v1.PodRunning)
+	daemonSetPod.SetLabels(map[string]string{"app": "daemon-set"})
+	completedPod := k8stest.CreateTestPod("completed-pod", node.Name, "100m", "100Mi", false, v1.PodSucceeded)
+	completedPod.SetLabels(map[string]string{"app": "completed"})
+	mockClient := k8stest.NewMockClient([]v1.Node{node}, []v1.Pod{pod, daemonSetPod, completedPod})
+
+	scenarios := []struct {
+		name                 string
+		podDisruptionBudget  policyv1.PodDisruptionBudget
+		expectedBlockingPDBs int
+	}{
+		{name: "no-disruptions-allowed", podDisruptionBudget: k8stest.CreateTestPodDisruptionBudget("pdb", map[string]string{"app": "app"}, 0), expectedBlockingPDBs: 1},
+		{name: "disruptions-allowed", podDisruptionBudget: k8stest.CreateTestPodDisruptionBudget("pdb", map[string]string{"app": "app"}, 1), expectedBlockingPDBs: 0},
+		{name: "no-matching-pod", podDisruptionBudget: k8stest.CreateTestPodDisruptionBudget("pdb", map[string]string{"app": "other"}, 0), expectedBlockingPDBs: 0},
+		{name: "empty-selector", podDisruptionBudget: k8stest.CreateTestPodDisruptionBudget("pdb", map[string]string{}, 0), expectedBlockingPDBs: 1},
+		{name: "daemon-set-pod", podDisruptionBudget: k8stest.CreateTestPodDisruptionBudget("pdb", map[string]string{"app": "daemon-set"}, 0), expectedBlockingPDBs: 0},
+		{name: "completed-pod", podDisruptionBudget: k8stest.CreateTestPodDisruptionBudget("pdb", map[string]string{"app": "completed"}, 0), expectedBlockingPDBs: 0},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			mockClient.PodDisruptionBudgets = []policyv1.PodDisruptionBudget{scenario.podDisruptionBudget}
+			blockingPodDisruptionBudgets, err := GetPodDisruptionBudgetsBlockingDrain(context.Background(), mockClient, &node)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if len(blockingPodDisruptionBudgets) != scenario.expectedBlockingPDBs {
+				t.Errorf("expected %d blocking PodDisruptionBudgets, got %d", scenario.expectedBlockingPDBs, len(blockingPodDisruptionBudgets))
+			}
+		})
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_whenResourcesAreSpreadAcrossMultipleTargetNodes(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "1000m", "1000Mi")
+	// Both new nodes have 1000Mi available, which adds up to 2000Mi, but the old node's pod needs 1500Mi on one node
+	oldNodePod := k8stest.CreateTestPod("old-node-pod-1", oldNode.Name, "100m", "1500Mi", false, v1.PodRunning)
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, []v1.Pod{oldNodePod})
+
+	hasEnoughResources := podsFromOldNodeFit(t, mockClient, &oldNode, []*v1.Node{&firstNewNode, &secondNewNode})
+	if hasEnoughResources {
+		t.Error("shouldn't have had enough space, because the pod doesn't fit on any single target node")
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	firstNewNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	secondNewNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "1000m", "1000Mi")
+	// With first-fit (not decreasing), the 300m pods would fill new-node-1 and new-node-2 would only have room for
+	// one 700m pod, but sorting by decreasing requests lets every pod fit
+	pods := []v1.Pod{
+		k8stest.CreateTestPod("small-pod-1", oldNode.Name, "300m", "0", false, v1.PodRunning),
+		k8stest.CreateTestPod("small-pod-2", oldNode.Name, "300m", "0", false, v1.PodRunning),
+		k8stest.CreateTestPod("large-pod-1", oldNode.Name, "700m", "0", false, v1.PodRunning),
+		k8stest.CreateTestPod("large-pod-2", oldNode.Name, "700m", "0", false, v1.PodRunning),
+		k8stest.CreateTestPod("daemon-set-pod", oldNode.Name, "900m", "0", true, v1.PodRunning),
+	}
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, firstNewNode, secondNewNode}, pods)
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !simulation.Fits() {
+		t.Error("every pod should've fit, but", simulation.String())
+	}
+	if len(simulation.Placements) != 4 {
+		t.Error("4 pods should've been placed, because pods from DaemonSets are ignored, got", len(simulation.Placements))
+	}
+	if simulation.Placements["/large-pod-1"] == simulation.Placements["/large-pod-2"] {
+		t.Error("both large pods shouldn't have been placed on the same node")
+	}
+
+	// Adding a third large pod means that one of the pods no longer fits
+	mockClient.Pods["large-pod-3"] = k8stest.CreateTestPod("large-pod-3", oldNode.Name, "700m", "100Mi", false, v1.PodRunning)
+	simulation, err = SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&firstNewNode, &secondNewNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if simulation.Fits() {
+		t.Fatal("one of the pods shouldn't have fit")
+	}
+	if len(simulation.UnschedulablePods) != 1 {
+		t.Fatal("exactly one pod shouldn't have fit, got", len(simulation.UnschedulablePods))
+	}
+	if reason := simulation.UnschedulablePods[0].Reason; reason != "0/2 updated nodes are available: 2 Insufficient cpu" {
+		t.Error("unexpected reason:", reason)
+	}
+}
+
+func TestGetPodRequests(t *testing.T) {
+	pod := k8stest.CreateTestPod("pod", "node", "100m", "100Mi", false, v1.PodRunning)
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+		Name: "sidecar",
+		Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+			v1.ResourceCPU:              resource.MustParse("100m"),
+			v1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+			"nvidia.com/gpu":            resource.MustParse("1"),
+		}},
+	})
+	// The init container runs before the other containers, so only its CPU request is higher than the containers'
+	pod.Spec.InitContainers = []v1.Container{{
+		Name: "init",
+		Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("50Mi"),
+		}},
+	}}
+	pod.Spec.Overhead = v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m"), v1.ResourceMemory: resource.MustParse("10Mi")}
+	requests := getPodRequests(&pod)
+	if requests[v1.ResourceCPU] != 510 {
+		t.Error("the CPU request should've been the init container's request plus the overhead, got", requests[v1.ResourceCPU])
+	}
+	if expected := resource.MustParse("110Mi"); requests[v1.ResourceMemory] != expected.MilliValue() {
+		t.Error("the memory request should've been the containers' requests plus the overhead, got", requests[v1.ResourceMemory])
+	}
+	if expected := resource.MustParse("1Gi"); requests[v1.ResourceEphemeralStorage] != expected.MilliValue() {
+		t.Error("the ephemeral-storage request should've been 1Gi, got", requests[v1.ResourceEphemeralStorage])
+	}
+	if requests["nvidia.com/gpu"] != 1000 {
+		t.Error("the pod should've requested 1 GPU, got", requests["nvidia.com/gpu"])
+	}
+	if requests[v1.ResourcePods] != 1000 {
+		t.Error("the pod should've requested 1 pod, got", requests[v1.ResourcePods])
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_withExtendedResources(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	cpuNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "4000m", "4000Mi")
+	gpuNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "4000m", "4000Mi")
+	gpuNode.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("1")
+	var pods []v1.Pod
+	for _, name := range []string{"gpu-pod-1", "gpu-pod-2"} {
+		pod := k8stest.CreateTestPod(name, oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+		pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+		pods = append(pods, pod)
+	}
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, cpuNode, gpuNode}, pods)
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&cpuNode, &gpuNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(simulation.Placements) != 1 || len(simulation.UnschedulablePods) != 1 {
+		t.Fatal("only one of the pods should've fit, because there's only one GPU available, but", simulation.String())
+	}
+	if reason := simulation.UnschedulablePods[0].Reason; reason != "0/2 updated nodes are available: 2 Insufficient nvidia.com/gpu" {
+		t.Error("unexpected reason:", reason)
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_withExtendedResourcesAndLargerCPURequests(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	gpuNode := k8stest.CreateTestNode("new-node-1", "us-west-2a", "i-07550830aef9e4179", "1000m", "1000Mi")
+	gpuNode.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("1")
+	cpuNode := k8stest.CreateTestNode("new-node-2", "us-west-2a", "i-0147ad0816c210dae", "1000m", "1000Mi")
+	// If pods were only sorted by CPU, cpu-pod would be placed on new-node-1 first, leaving too little CPU for
+	// gpu-pod, which can only be placed on new-node-1
+	gpuPod := k8stest.CreateTestPod("gpu-pod", oldNode.Name, "500m", "0", false, v1.PodRunning)
+	gpuPod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+	cpuPod := k8stest.CreateTestPod("cpu-pod", oldNode.Name, "600m", "0", false, v1.PodRunning)
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, gpuNode, cpuNode}, []v1.Pod{gpuPod, cpuPod})
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&gpuNode, &cpuNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if !simulation.Fits() {
+		t.Fatal("every pod should've fit, but", simulation.String())
+	}
+	if simulation.Placements["/gpu-pod"] != gpuNode.Name || simulation.Placements["/cpu-pod"] != cpuNode.Name {
+		t.Error("gpu-pod should've been placed on new-node-1 and cpu-pod on new-node-2, got", simulation.Placements)
+	}
+}
+
+func TestSimulateSchedulingOfPodsFromOldNodes_whenTargetNodeHasMaximumNumberOfPods(t *testing.T) {
+	oldNode := k8stest.CreateTestNode("old-node", "us-west-2a", "i-034fa1dfbfd35f8bb", "0m", "0m")
+	newNode := k8stest.CreateTestNode("new-node", "us-west-2a", "i-07550830aef9e4179", "4000m", "4000Mi")
+	newNode.Status.Allocatable[v1.ResourcePods] = resource.MustParse("2")
+	pods := []v1.Pod{
+		k8stest.CreateTestPod("pod-in-new-node", newNode.Name, "100m", "100Mi", false, v1.PodRunning),
+		k8stest.CreateTestPod("pod-1", oldNode.Name, "100m", "100Mi", false, v1.PodRunning),
+		k8stest.CreateTestPod("pod-2", oldNode.Name, "100m", "100Mi", false, v1.PodRunning),
+	}
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, pods)
+
+	simulation, err := SimulateSchedulingOfPodsFromOldNodes(mockClient, []*v1.Node{&oldNode}, []*v1.Node{&newNode})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(simulation.UnschedulablePods) != 1 {
+		t.Fatal("one of the pods shouldn't have fit, because the new node can only have 2 pods, but", simulation.String())
+	}
+	if reason := simulation.UnschedulablePods[0].Reason; reason != "0/1 updated nodes are available: 1 Insufficient pods" {
+		t.Error("unexpected reason:", reason)
+	}
+}
+
+func TestPatchNodeByAutoScalingInstance_withStaleNode(t *testing.T) {
+	node := k8stest.CreateTestNode("node", "us-west-2a", "i-034fa1dfbfd35f8bb", "1000m", "1000Mi")
+	node.SetAnnotations(map[string]string{"to-remove": "true"})
+	node.SetLabels(map[string]string{"to-remove": "true"})
+	instance := &autoscaling.Instance{InstanceId: aws.String("i-034fa1dfbfd35f8bb"), AvailabilityZone: aws.String("us-west-2a")}
+	mockClient := k8stest.NewMockClient([]v1.Node{node}, nil)
+	// Every change is made while the node retrieved is the one from before the first change
+	mockClient.StaleReads = true
+	if err := AnnotateNodeByAutoScalingInstance(context.Background(), mockClient, instance, "first", "1"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := AnnotateNodeByAutoScalingInstance(context.Background(), mockClient, instance, "second", "2"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := LabelNodeByAutoScalingInstance(context.Background(), mockClient, instance, "label", "value"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := RemoveAnnotationFromNodeByAutoScalingInstance(context.Background(), mockClient, instance, "to-remove"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := RemoveLabelFromNodeByAutoScalingInstance(context.Background(), mockClient, instance, "to-remove"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	unschedulable := true
+	if err := PatchNodeByAutoScalingInstance(context.Background(), mockClient, instance, NodePatch{Unschedulable: &unschedulable}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	patchedNode := mockClient.Nodes[node.Name]
+	if expectedAnnotations := map[string]string{"first": "1", "second": "2"}; !reflect.DeepEqual(patchedNode.Annotations, expectedAnnotations) {
+		t.Errorf("expected annotations %v, got %v", expectedAnnotations, patchedNode.Annotations)
+	}
+	if expectedLabels := map[string]string{"label": "value"}; !reflect.DeepEqual(patchedNode.Labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, patchedNode.Labels)
+	}
+	if !patchedNode.Spec.Unschedulable {
+		t.Error("expected node to be unschedulable")
+	}
+	// Unlike patches, updates based on the stale node are rejected
+	staleNode, _ := mockClient.GetNodeByAutoScalingInstance(instance)
+	if err := mockClient.UpdateNode(context.Background(), staleNode); !apierrors.IsConflict(err) {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+}
+
+func TestNodePatch_Marshal(t *testing.T) {
+	value, unschedulable := "value", false
+	patch := NodePatch{
+		Annotations:   map[string]*string{"added": &value, "removed": nil},
+		Labels:        map[string]*string{"removed": nil},
+		Unschedulable: &unschedulable,
+	}
+	data, err := patch.Marshal()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	expected := `{"metadata":{"annotations":{"added":"value","removed":null},"labels":{"removed":null}},"spec":{"unschedulable":false}}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
@@ -1,14 +1,24 @@
 package k8stest
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 )
 
 // TODO: replace this by Kubernetes' official fake client (k8s.io/client-go/kubernetes/fake)
@@ -17,6 +27,26 @@ type MockClient struct {
 	Counter map[string]int64
 	Nodes   map[string]v1.Node
 	Pods    map[string]v1.Pod
+	Events  map[string][]string // Reasons of the events recorded, indexed by object name
+
+	PodDisruptionBudgets []policyv1.PodDisruptionBudget
+
+	// DrainDuration is how long Drain takes to complete, unless the context is cancelled first
+	DrainDuration time.Duration
+	// DrainError is the error returned by Drain, unless eviction is disabled
+	DrainError error
+	// MaxConcurrentDrains is the highest number of nodes that were being drained at the same time
+	MaxConcurrentDrains int
+
+	// StaleReads makes the nodes retrieved through GetNodes and GetNodeByAutoScalingInstance remain as they were
+	// before being written to through UpdateNode or PatchNode, like the nodes retrieved from the informer cache of
+	// the real client until the cache catches up
+	StaleReads bool
+
+	// staleNodes are the nodes returned instead of the stored nodes when StaleReads is true
+	staleNodes map[string]v1.Node
+	draining   int
+	mutex      sync.Mutex
 }
 
 func NewMockClient(nodes []v1.Node, pods []v1.Pod) *MockClient {
@@ -24,6 +54,9 @@ func NewMockClient(nodes []v1.Node, pods []v1.Pod) *MockClient {
 		Counter: make(map[string]int64),
 		Nodes:   make(map[string]v1.Node),
 		Pods:    make(map[string]v1.Pod),
+		Events:  make(map[string][]string),
+
+		staleNodes: make(map[string]v1.Node),
 	}
 	for _, node := range nodes {
 		client.Nodes[node.Name] = node
@@ -35,15 +68,23 @@ func NewMockClient(nodes []v1.Node, pods []v1.Pod) *MockClient {
 }
 
 func (mock *MockClient) GetNodes() ([]v1.Node, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.Counter["GetNodes"]++
 	var nodes []v1.Node
 	for _, node := range mock.Nodes {
-		nodes = append(nodes, node)
+		if staleNode, ok := mock.staleNodes[node.Name]; ok && mock.StaleReads {
+			node = staleNode
+		}
+		// Like the nodes returned by the real client, the nodes returned must not share anything with the stored nodes
+		nodes = append(nodes, *node.DeepCopy())
 	}
 	return nodes, nil
 }
 
 func (mock *MockClient) GetPodsInNode(node string) ([]v1.Pod, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.Counter["GetPodsInNode"]++
 	var pods []v1.Pod
 	for _, pod := range mock.Pods {
@@ -54,13 +95,30 @@ func (mock *MockClient) GetPodsInNode(node string) ([]v1.Pod, error) {
 	return pods, nil
 }
 
+func (mock *MockClient) GetPodDisruptionBudgets(_ context.Context, namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.Counter["GetPodDisruptionBudgets"]++
+	var podDisruptionBudgets []policyv1.PodDisruptionBudget
+	for _, podDisruptionBudget := range mock.PodDisruptionBudgets {
+		if podDisruptionBudget.Namespace == namespace {
+			podDisruptionBudgets = append(podDisruptionBudgets, podDisruptionBudget)
+		}
+	}
+	return podDisruptionBudgets, nil
+}
+
 func (mock *MockClient) GetNodeByAutoScalingInstance(instance *autoscaling.Instance) (*v1.Node, error) {
+	mock.mutex.Lock()
 	mock.Counter["GetNodeByAutoScalingInstance"]++
+	mock.mutex.Unlock()
 	nodes, _ := mock.GetNodes()
 	return mock.FilterNodeByAutoScalingInstance(nodes, instance)
 }
 
 func (mock *MockClient) FilterNodeByAutoScalingInstance(nodes []v1.Node, instance *autoscaling.Instance) (*v1.Node, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.Counter["FilterNodeByAutoScalingInstance"]++
 	for _, node := range nodes {
 		if node.Spec.ProviderID == fmt.Sprintf("aws:///%s/%s", aws.StringValue(instance.AvailabilityZone), aws.StringValue(instance.InstanceId)) {
@@ -70,22 +128,116 @@ func (mock *MockClient) FilterNodeByAutoScalingInstance(nodes []v1.Node, instanc
 	return nil, errors.New("not found")
 }
 
-func (mock *MockClient) UpdateNode(node *v1.Node) error {
+// UpdateNode replaces a stored node, unless the node has been written to since it was retrieved, in which case a
+// conflict error is returned, like the API server would
+func (mock *MockClient) UpdateNode(_ context.Context, node *v1.Node) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.Counter["UpdateNode"]++
-	mock.Nodes[node.Name] = *node
+	storedNode, ok := mock.Nodes[node.Name]
+	if ok && storedNode.ResourceVersion != node.ResourceVersion {
+		return apierrors.NewConflict(v1.Resource("nodes"), node.Name, errors.New("the object has been modified; please apply your changes to the latest version and try again"))
+	}
+	mock.write(storedNode, *node.DeepCopy())
 	return nil
 }
 
-func (mock *MockClient) Cordon(nodeName string) error {
+// PatchNode applies a strategic merge patch to a stored node
+func (mock *MockClient) PatchNode(_ context.Context, nodeName string, patch []byte) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.Counter["PatchNode"]++
+	storedNode, ok := mock.Nodes[nodeName]
+	if !ok {
+		return apierrors.NewNotFound(v1.Resource("nodes"), nodeName)
+	}
+	original, err := json.Marshal(storedNode)
+	if err != nil {
+		return err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, patch, v1.Node{})
+	if err != nil {
+		return err
+	}
+	var node v1.Node
+	if err := json.Unmarshal(patched, &node); err != nil {
+		return err
+	}
+	mock.write(storedNode, node)
+	return nil
+}
+
+// write replaces a stored node by its new version and bumps its resource version, keeping the previous version of
+// the node around for StaleReads
+func (mock *MockClient) write(storedNode, node v1.Node) {
+	if _, ok := mock.staleNodes[storedNode.Name]; !ok && len(storedNode.Name) > 0 {
+		mock.staleNodes[storedNode.Name] = storedNode
+	}
+	resourceVersion, _ := strconv.Atoi(storedNode.ResourceVersion)
+	node.ResourceVersion = strconv.Itoa(resourceVersion + 1)
+	mock.Nodes[node.Name] = node
+}
+
+func (mock *MockClient) Cordon(_ context.Context, nodeName string) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.Counter["Cordon"]++
+	mock.setUnschedulable(nodeName, true)
 	return nil
 }
 
-func (mock *MockClient) Drain(nodeName string, ignoreDaemonSets, deleteLocalData bool, podTerminationGracePeriod int) error {
-	mock.Counter["Drain"]++
+func (mock *MockClient) Uncordon(_ context.Context, nodeName string) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.Counter["Uncordon"]++
+	mock.setUnschedulable(nodeName, false)
 	return nil
 }
 
+func (mock *MockClient) setUnschedulable(nodeName string, unschedulable bool) {
+	if storedNode, ok := mock.Nodes[nodeName]; ok {
+		node := *storedNode.DeepCopy()
+		node.Spec.Unschedulable = unschedulable
+		mock.write(storedNode, node)
+	}
+}
+
+func (mock *MockClient) Drain(ctx context.Context, nodeName string, ignoreDaemonSets, deleteLocalData bool, podTerminationGracePeriod int, disableEviction bool) error {
+	mock.mutex.Lock()
+	mock.Counter["Drain"]++
+	if disableEviction {
+		mock.Counter["DrainWithoutEviction"]++
+	}
+	// Like the real client, the node is cordoned before being drained
+	mock.setUnschedulable(nodeName, true)
+	mock.draining++
+	mock.MaxConcurrentDrains = max(mock.MaxConcurrentDrains, mock.draining)
+	mock.mutex.Unlock()
+	defer func() {
+		mock.mutex.Lock()
+		mock.draining--
+		mock.mutex.Unlock()
+	}()
+	select {
+	case <-time.After(mock.DrainDuration):
+		if disableEviction {
+			return nil
+		}
+		return mock.DrainError
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (mock *MockClient) RecordEvent(object runtime.Object, eventType, reason, message string) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.Counter["RecordEvent"]++
+	if accessor, err := meta.Accessor(object); err == nil {
+		mock.Events[accessor.GetName()] = append(mock.Events[accessor.GetName()], reason)
+	}
+}
+
 func CreateTestNode(name, availabilityZone, instanceId, allocatableCpu, allocatableMemory string) v1.Node {
 	node := v1.Node{
 		Spec: v1.NodeSpec{
@@ -95,6 +247,7 @@ func CreateTestNode(name, availabilityZone, instanceId, allocatableCpu, allocata
 			Allocatable: map[v1.ResourceName]resource.Quantity{
 				v1.ResourceCPU:    resource.MustParse(allocatableCpu),
 				v1.ResourceMemory: resource.MustParse(allocatableMemory),
+				v1.ResourcePods:   resource.MustParse("110"),
 			},
 		},
 	}
@@ -104,6 +257,17 @@ func CreateTestNode(name, availabilityZone, instanceId, allocatableCpu, allocata
 	return node
 }
 
+func CreateTestPodDisruptionBudget(name string, matchLabels map[string]string, disruptionsAllowed int32) policyv1.PodDisruptionBudget {
+	podDisruptionBudget := policyv1.PodDisruptionBudget{
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: matchLabels},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+	}
+	podDisruptionBudget.SetName(name)
+	return podDisruptionBudget
+}
+
 func CreateTestPod(name, nodeName, cpuRequest, cpuMemory string, isDaemonSet bool, podPhase v1.PodPhase) v1.Pod {
 	pod := v1.Pod{
 		Spec: v1.PodSpec{
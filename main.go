@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloud"
@@ -17,19 +24,18 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
 )
 
 const (
-	MaximumFailedExecutionBeforePanic = 10 // Maximum number of allowed failed executions before panicking
+	ConfigFileWatchInterval = 10 * time.Second // How often the configuration file is checked for changes
 
-	MaximumAcceptableUpdatedNonReadyToUpdatedReadyNodesRatio = 0.11 // To help with larger clusters
-	MaximumNumberOfUpdatedNonReadyNodes                      = 5    // To prevent too many non-ready nodes from being taken into account when calculating resources available in one node
+	CleanupTimeout = 10 * time.Second // Maximum duration of the steps that must complete even if the execution is cancelled
 )
 
 var (
 	ErrTimedOut = errors.New("execution timed out")
-
-	executionFailedCounter = 0
 )
 
 func init() {
@@ -37,259 +43,705 @@ func init() {
 }
 
 func main() {
+	// Cancelling the context on SIGTERM stops every in-flight step rather than leaving them running until the
+	// process is killed
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 	err := config.Initialize()
 	if err != nil {
 		log.Fatalf("Unable to initialize configuration: %s", err.Error())
 	}
+	health := NewHealth()
+	if config.Get().HealthProbes {
+		health.Register()
+	}
 	if config.Get().Metrics {
+		metrics.Server.HandleMetrics()
+	}
+	// The server is started before the admin API is registered, so that Kubernetes can probe the readiness of the
+	// application while it is starting
+	if config.Get().HealthProbes || config.Get().Metrics || config.Get().AdminAPI {
 		go metrics.Server.Listen(config.Get().MetricsPort)
 	}
+	if len(config.Get().ConfigFile) > 0 {
+		go config.WatchConfigFile(ctx, ConfigFileWatchInterval)
+	}
 	ec2Service, autoScalingService, err := cloud.GetServices(config.Get().AwsRegion)
 	if err != nil {
 		log.Fatalf("Unable to create AWS services: %s", err.Error())
 	}
-	for {
-		start := time.Now()
-		if err := run(ec2Service, autoScalingService); err != nil {
-			log.Printf("Error during execution: %s", err.Error())
-			metrics.Server.Errors.Inc()
-			executionFailedCounter++
-			if executionFailedCounter > MaximumFailedExecutionBeforePanic {
-				panic(fmt.Errorf("execution failed %d times: %v", executionFailedCounter, err))
-			}
-		} else if executionFailedCounter > 0 {
-			log.Printf("Execution was successful after %d failed attempts, resetting counter to 0", executionFailedCounter)
-			executionFailedCounter = 0
-		}
-		log.Printf("Execution took %dms, sleeping for %s", time.Since(start).Milliseconds(), config.Get().ExecutionInterval)
-		time.Sleep(config.Get().ExecutionInterval)
-	}
-}
-
-func run(ec2Service ec2iface.EC2API, autoScalingService autoscalingiface.AutoScalingAPI) error {
-	log.Println("Starting execution")
-	cfg := config.Get()
+	health.awsClientsCreated.Store(true)
 	client, err := k8s.CreateClientSet()
 	if err != nil {
-		return errors.New("unable to create Kubernetes client: " + err.Error())
+		log.Fatalf("Unable to create Kubernetes client: %s", err.Error())
 	}
-	kubernetesClient := k8s.NewClient(client)
-	if cfg.Debug {
-		log.Println("Created Kubernetes Client successfully")
+	kubernetesClient := k8s.NewClient(client, k8s.NewEventRecorder(client))
+	controller := NewController(kubernetesClient, ec2Service, autoScalingService)
+	health.controller.Store(controller)
+	if config.Get().AdminAPI {
+		NewAdminAPI(controller, health).Register()
 	}
-
-	var autoScalingGroups []*autoscaling.Group
-	if len(cfg.AutodiscoveryTags) > 0 {
-		autoScalingGroups, err = cloud.DescribeEnabledAutoScalingGroupsByTags(autoScalingService, cfg.AutodiscoveryTags)
-	} else {
-		autoScalingGroups, err = cloud.DescribeAutoScalingGroupsByNames(autoScalingService, cfg.AutoScalingGroupNames)
+	if err := kubernetesClient.AddNodeEventHandler(controller.NodeEventHandler()); err != nil {
+		log.Fatalf("Unable to watch nodes: %s", err.Error())
 	}
-	if err != nil {
-		return errors.New("unable to describe AutoScalingGroups: " + err.Error())
+	if err := kubernetesClient.Start(ctx); err != nil {
+		log.Fatalf("Unable to start Kubernetes informers: %s", err.Error())
 	}
-	if cfg.Debug {
-		log.Println("Described AutoScalingGroups successfully")
+	health.kubernetesClientStarted.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx, client, controller, health)
+	}()
+	var serverShutdownDeadline time.Time
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// No new step is started once the context is cancelled, and drains that are interrupted are rolled back
+		log.Printf("Received termination signal, waiting up to %s for in-flight steps to complete", config.Get().ShutdownTimeout)
+		// The server must also be shut down within ShutdownTimeout, so that the application exits before being
+		// killed as long as ShutdownTimeout is lower than the pod's terminationGracePeriodSeconds
+		serverShutdownDeadline = time.Now().Add(config.Get().ShutdownTimeout)
+		select {
+		case <-done:
+			log.Println("In-flight steps completed, shutting down")
+		case <-time.After(config.Get().ShutdownTimeout):
+			log.Println("Timed out waiting for in-flight steps to complete, shutting down anyway")
+		}
+	}
+	if serverShutdownDeadline.IsZero() || time.Until(serverShutdownDeadline) > CleanupTimeout {
+		serverShutdownDeadline = time.Now().Add(CleanupTimeout)
+	}
+	// Give Prometheus a chance to finish scraping the latest values before exiting
+	metricsCtx, cancel := context.WithDeadline(context.Background(), serverShutdownDeadline)
+	defer cancel()
+	if err := metrics.Server.Shutdown(metricsCtx); err != nil {
+		log.Printf("Unable to shut down metrics server gracefully: %s", err.Error())
 	}
-	return HandleRollingUpgrade(kubernetesClient, ec2Service, autoScalingService, autoScalingGroups)
 }
 
-// HandleRollingUpgrade handles rolling upgrades.
+// run runs the controller until the context is cancelled, or, if leader election is enabled, for as long as this
+// replica is the leader, in which case the given health is notified when this replica becomes or stops being the
+// leader, and whenever the leader changes
 //
-// Returns an error if an execution lasts for longer than ExecutionTimeout
-func HandleRollingUpgrade(client k8s.ClientAPI, ec2Service ec2iface.EC2API, autoScalingService autoscalingiface.AutoScalingAPI, autoScalingGroups []*autoscaling.Group) error {
-	metrics.Server.NodeGroups.WithLabelValues().Set(float64(len(autoScalingGroups)))
-	timeout := make(chan bool, 1)
-	result := make(chan bool, 1)
-	go func() {
-		time.Sleep(config.Get().ExecutionTimeout)
-		timeout <- true
-	}()
+// Returns once the controller has stopped.
+func run(ctx context.Context, client kubernetes.Interface, controller *Controller, health *Health) {
+	if !config.Get().LeaderElection {
+		controller.Run(ctx)
+		return
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("Unable to determine leader election identity: %s", err.Error())
+	}
+	// The Lease is released when the leader election's context is cancelled, so it must only be cancelled once the
+	// controller has stopped, otherwise another replica could start executing while this one is still rolling back
+	// interrupted drains.
+	electionCtx, cancelElection := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelElection()
+	var started atomic.Bool
+	controllerStopped := make(chan struct{})
 	go func() {
-		result <- DoHandleRollingUpgrade(client, ec2Service, autoScalingService, autoScalingGroups)
+		<-ctx.Done()
+		if started.Load() {
+			<-controllerStopped
+		}
+		cancelElection()
 	}()
-	select {
-	case <-timeout:
+	err = k8s.RunLeaderElection(electionCtx, client, config.Get().LeaderElectionNamespace, config.Get().LeaderElectionLeaseName, identity, func(leaderCtx context.Context) {
+		started.Store(true)
+		health.leading.Store(true)
+		defer close(controllerStopped)
+		// The controller stops when the application is shutting down, or when the leadership is lost
+		controllerCtx, cancel := context.WithCancel(leaderCtx)
+		defer cancel()
+		defer context.AfterFunc(ctx, cancel)()
+		controller.Run(controllerCtx)
+	}, func() {
+		health.leading.Store(false)
+		if ctx.Err() != nil {
+			// The lease was released because the application is shutting down
+			return
+		}
+		// There's no way to know whether another replica has already taken over, so we exit to make sure that
+		// two replicas are never executing at the same time.
+		log.Fatalf("Lost leadership, exiting")
+	}, func(currentLeader string) {
+		health.leader.Store(&currentLeader)
+	})
+	if err != nil {
+		log.Fatalf("Unable to run leader election: %s", err.Error())
+	}
+	if started.Load() {
+		// The leader election doesn't wait for the controller to stop
+		<-controllerStopped
+	}
+}
+
+// contextError returns ErrTimedOut if the given context's deadline has been exceeded, or the context's error otherwise
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 		return ErrTimedOut
-	case <-result:
-		return nil
 	}
+	return ctx.Err()
 }
 
-// DoHandleRollingUpgrade handles rolling upgrades by iterating over every single AutoScalingGroups' outdated
-// instances
-func DoHandleRollingUpgrade(client k8s.ClientAPI, ec2Service ec2iface.EC2API, autoScalingService autoscalingiface.AutoScalingAPI, autoScalingGroups []*autoscaling.Group) bool {
-	for _, autoScalingGroup := range autoScalingGroups {
-		outdatedInstances, updatedInstances, err := SeparateOutdatedFromUpdatedInstances(autoScalingGroup, ec2Service)
-		if err != nil {
-			metrics.Server.Errors.Inc()
-			log.Printf("[%s] Skipping because unable to separate outdated instances from updated instances: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), err.Error())
-			continue
-		}
+// ReconcileResult is the result of reconciling a single AutoScalingGroup
+type ReconcileResult struct {
+	// Requeue is whether the ASG needs to be reconciled again, which is the case until all of its instances are
+	// up to date
+	Requeue bool
+	// SlowedDown is whether a node has been terminated while SlowMode was enabled, in which case no ASG should be
+	// reconciled until the next execution
+	SlowedDown bool
+}
+
+// ReconcileAutoScalingGroup handles the rolling upgrade of a single AutoScalingGroup by rolling out as many of its
+// outdated instances as its rollout budget allows
+//
+// Every action is recorded in the given plan. If plan.DryRun is true, no action is actually executed.
+// Once the context is cancelled, ongoing steps are interrupted and no new step is started.
+//
+// Returns the errors that prevented the rollout of the ASG from progressing (e.g. an instance that couldn't be
+// terminated), joined together. A failure to roll out an outdated instance doesn't prevent the other outdated instances
+// from being rolled out. Note that failed drains aren't returned, because they're handled by the drain escalation
+// policy instead.
+func ReconcileAutoScalingGroup(ctx context.Context, client k8s.ClientAPI, ec2Service ec2iface.EC2API, autoScalingService autoscalingiface.AutoScalingAPI, autoScalingGroup *autoscaling.Group, plan *Plan) (ReconcileResult, error) {
+	asgConfig := config.Get().ForAutoScalingGroup(aws.StringValue(autoScalingGroup.AutoScalingGroupName), cloud.GetAutoScalingGroupTags(autoScalingGroup))
+	outdatedInstances, updatedInstances, err := SeparateOutdatedFromUpdatedInstances(ctx, autoScalingGroup, ec2Service)
+	if err != nil {
+		return ReconcileResult{Requeue: true}, fmt.Errorf("unable to separate outdated instances from updated instances: %w", err)
+	}
+	if !plan.DryRun {
 		metrics.Server.UpdatedNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(float64(len(updatedInstances)))
 		metrics.Server.OutdatedNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(float64(len(outdatedInstances)))
-		if config.Get().Debug {
-			log.Printf("[%s] outdatedInstances: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), outdatedInstances)
-			log.Printf("[%s] updatedInstances: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), updatedInstances)
-		}
-		// Get the updated and ready nodes from the list of updated instances
-		// This will be used to determine if the desired number of updated instances need to scale up or not
-		// We also use this to clean up, if necessary
-		updatedReadyNodes, numberOfNonReadyUpdatedNodesOrInstances := getReadyNodesAndNumberOfNonReadyNodesOrInstances(client, updatedInstances, autoScalingGroup)
-		if len(outdatedInstances) == 0 {
-			log.Printf("[%s] All instances are up to date", aws.StringValue(autoScalingGroup.AutoScalingGroupName))
-			continue
+	}
+	if config.Get().Debug {
+		log.Printf("[%s] outdatedInstances: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), outdatedInstances)
+		log.Printf("[%s] updatedInstances: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), updatedInstances)
+	}
+	if asgConfig.Paused {
+		if config.IsPausedByTag(cloud.GetAutoScalingGroupTags(autoScalingGroup)) {
+			log.Printf("[%s] Skipping because rolling update is paused by tag '%s'", aws.StringValue(autoScalingGroup.AutoScalingGroupName), config.TagPaused)
+		} else {
+			log.Printf("[%s] Skipping because rolling update is paused by configuration", aws.StringValue(autoScalingGroup.AutoScalingGroupName))
+		}
+		if !plan.DryRun {
+			metrics.Server.PausedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(1)
+		}
+		return ReconcileResult{Requeue: true}, nil
+	}
+	if !plan.DryRun {
+		metrics.Server.PausedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(0)
+	}
+	// Get the updated and ready nodes from the list of updated instances
+	// This will be used to determine if the desired number of updated instances need to scale up or not
+	// We also use this to clean up, if necessary
+	updatedReadyNodes, nonReadyUpdatedInstances := getReadyNodesAndNonReadyInstances(ctx, client, updatedInstances, autoScalingGroup, asgConfig, plan)
+	if len(outdatedInstances) == 0 {
+		log.Printf("[%s] All instances are up to date", aws.StringValue(autoScalingGroup.AutoScalingGroupName))
+		if !plan.DryRun {
+			metrics.Server.BlockedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(0)
+			metrics.Server.AbortedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(0)
+		}
+		return ReconcileResult{}, nil
+	} else {
+		log.Printf("[%s] outdated=%d; updated=%d; updatedAndReady=%d; asgCurrent=%d; asgDesired=%d; asgMax=%d", aws.StringValue(autoScalingGroup.AutoScalingGroupName), len(outdatedInstances), len(updatedInstances), len(updatedReadyNodes), len(autoScalingGroup.Instances), aws.Int64Value(autoScalingGroup.DesiredCapacity), aws.Int64Value(autoScalingGroup.MaxSize))
+	}
+	if int64(len(autoScalingGroup.Instances)) < aws.Int64Value(autoScalingGroup.DesiredCapacity) {
+		log.Printf("[%s] Skipping because ASG has a desired capacity of %d, but only has %d instances", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.Int64Value(autoScalingGroup.DesiredCapacity), len(autoScalingGroup.Instances))
+		return ReconcileResult{Requeue: true}, nil
+	}
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(len(nonReadyUpdatedInstances), updatedReadyNodes, asgConfig) {
+		if asgConfig.UpdatedNodeMinReadyDuration > 0 {
+			log.Printf("[%s] ASG has too many non-ready updated nodes/instances (%d) or updated nodes that have been ready for less than %s, waiting until they become ready", aws.StringValue(autoScalingGroup.AutoScalingGroupName), len(nonReadyUpdatedInstances), asgConfig.UpdatedNodeMinReadyDuration)
 		} else {
-			log.Printf("[%s] outdated=%d; updated=%d; updatedAndReady=%d; asgCurrent=%d; asgDesired=%d; asgMax=%d", aws.StringValue(autoScalingGroup.AutoScalingGroupName), len(outdatedInstances), len(updatedInstances), len(updatedReadyNodes), len(autoScalingGroup.Instances), aws.Int64Value(autoScalingGroup.DesiredCapacity), aws.Int64Value(autoScalingGroup.MaxSize))
+			log.Printf("[%s] ASG has too many non-ready updated nodes/instances (%d), waiting until they become ready", aws.StringValue(autoScalingGroup.AutoScalingGroupName), len(nonReadyUpdatedInstances))
+		}
+		if plan.DryRun {
+			// Whether the rollout should be aborted requires describing the non-ready updated instances, so aborts
+			// are never planned
+			return ReconcileResult{Requeue: true}, nil
+		}
+		notReadySince, ok, err := shouldAbortRollout(ctx, ec2Service, autoScalingGroup, nonReadyUpdatedInstances)
+		if err != nil {
+			return ReconcileResult{Requeue: true}, err
+		}
+		if ok {
+			abortRollout(ctx, client, autoScalingGroup, asgConfig, outdatedInstances, len(nonReadyUpdatedInstances), notReadySince)
 		}
-		if int64(len(autoScalingGroup.Instances)) < aws.Int64Value(autoScalingGroup.DesiredCapacity) {
-			log.Printf("[%s] Skipping because ASG has a desired capacity of %d, but only has %d instances", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.Int64Value(autoScalingGroup.DesiredCapacity), len(autoScalingGroup.Instances))
+		return ReconcileResult{Requeue: true}, nil
+	}
+	if !plan.DryRun {
+		metrics.Server.AbortedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(0)
+	}
+	// Shuffle the outdated instances, so that we don't always try to terminate the same instance.
+	rand.Shuffle(len(outdatedInstances), func(i, j int) {
+		outdatedInstances[i], outdatedInstances[j] = outdatedInstances[j], outdatedInstances[i]
+	})
+	// Outside of maintenance windows and during blackout periods, the rollout of nodes that have already started is
+	// completed, but the rollout of new nodes isn't started
+	rolloutScheduleErr := asgConfig.RolloutSchedule.Check(time.Now())
+	var (
+		drainedOutdatedNodes   []*outdatedNode // Outdated nodes that have been drained, but not terminated
+		undrainedOutdatedNodes []*outdatedNode // Outdated nodes that have started their rollout, but haven't been drained
+		errs                   []error         // Errors that prevented outdated nodes from being rolled out
+	)
+	for _, outdatedInstance := range outdatedInstances {
+		if ctx.Err() != nil {
+			return ReconcileResult{Requeue: true}, errors.Join(errs...)
+		}
+		node, err := client.GetNodeByAutoScalingInstance(outdatedInstance)
+		if err != nil {
+			log.Printf("[%s][%s] Skipping because unable to get outdated node from Kubernetes: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
 			continue
 		}
-		if !HasAcceptableNumberOfUpdatedNonReadyNodes(numberOfNonReadyUpdatedNodesOrInstances, len(updatedReadyNodes)) {
-			log.Printf("[%s] ASG has too many non-ready updated nodes/instances (%d), waiting until they become ready", aws.StringValue(autoScalingGroup.AutoScalingGroupName), numberOfNonReadyUpdatedNodesOrInstances)
+		if isNodePaused(node) {
+			log.Printf("[%s][%s] Skipping because node has annotation '%s=true'", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), k8s.AnnotationPaused)
 			continue
 		}
-		// Shuffle the outdated instances, so that we don't always try to terminate the same instance.
-		// This is also useful if you want to have more than one aws-eks-asg-rolling-update-handler running
-		rand.Shuffle(len(outdatedInstances), func(i, j int) {
-			outdatedInstances[i], outdatedInstances[j] = outdatedInstances[j], outdatedInstances[i]
-		})
-		for _, outdatedInstance := range outdatedInstances {
-			node, err := client.GetNodeByAutoScalingInstance(outdatedInstance)
-			if err != nil {
-				log.Printf("[%s][%s] Skipping because unable to get outdated node from Kubernetes: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
-				continue
-			}
-			if config.Get().EagerCordoning {
-				if !node.Spec.Unschedulable {
-					// If EagerCordoning is enabled and the node is schedulable, we need to cordon it.
-					if err := client.Cordon(node.Name); err != nil {
-						metrics.Server.Errors.Inc()
-						log.Printf("[%s][%s] Skipping because ran into error while cordoning node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
+		if _, started := node.Annotations[k8s.AnnotationRollingUpdateStartedTimestamp]; !started && rolloutScheduleErr != nil {
+			log.Printf("[%s][%s] Skipping because node rollout process cannot be started: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), rolloutScheduleErr.Error())
+			continue
+		}
+		if asgConfig.EagerCordoning {
+			if !node.Spec.Unschedulable {
+				// If EagerCordoning is enabled and the node is schedulable, we need to cordon it.
+				plan.Record(autoScalingGroup, outdatedInstance, node, StepCordon, "eager cordoning is enabled")
+				if !plan.DryRun {
+					if err := client.Cordon(ctx, node.Name); err != nil {
+						errs = append(errs, fmt.Errorf("unable to cordon node %s: %w", node.Name, err))
 						continue
 					}
 				}
 			}
-			minutesSinceStarted, minutesSinceDrained, minutesSinceTerminated := getRollingUpdateTimestampsFromNode(node)
-			// Check if outdated nodes in k8s have been marked with annotation from aws-eks-asg-rolling-update-handler
-			if minutesSinceStarted == -1 {
+		}
+		minutesSinceStarted, minutesSinceDrained, minutesSinceTerminated := getRollingUpdateTimestampsFromNode(node)
+		// Check if outdated nodes in k8s have been marked with annotation from aws-eks-asg-rolling-update-handler
+		if minutesSinceStarted == -1 {
+			plan.Record(autoScalingGroup, outdatedInstance, node, StepStartRollout, "instance is outdated")
+			if !plan.DryRun {
 				log.Printf("[%s][%s] Starting node rollout process", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
 				// Annotate the node to persist the fact that the rolling update process has begun
-				err := k8s.AnnotateNodeByAutoScalingInstance(client, outdatedInstance, k8s.AnnotationRollingUpdateStartedTimestamp, time.Now().Format(time.RFC3339))
+				err := k8s.AnnotateNodeByAutoScalingInstance(ctx, client, outdatedInstance, k8s.AnnotationRollingUpdateStartedTimestamp, time.Now().Format(time.RFC3339))
 				if err != nil {
-					log.Printf("[%s][%s] Skipping because unable to annotate node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
+					errs = append(errs, fmt.Errorf("unable to annotate node %s: %w", node.Name, err))
 					continue
 				}
-			} else {
-				log.Printf("[%s][%s] Node already started rollout process", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-				// check if existing updatedInstances have the capacity to support what's inside this node
-				hasEnoughResources := k8s.CheckIfUpdatedNodesHaveEnoughResourcesToScheduleAllPodsFromOldNode(client, node, updatedReadyNodes)
-				if hasEnoughResources {
-					log.Printf("[%s][%s] Updated nodes have enough resources available", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-					if minutesSinceDrained == -1 {
-						if config.Get().ExcludeFromExternalLoadBalancers {
-							log.Printf("[%s][%s] Label node to exclude from external load balancers", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-							k8s.LabelNodeByAutoScalingInstance(client, outdatedInstance, k8s.LabelExcludeFromExternalLoadBalancers, "true")
-						}
-						log.Printf("[%s][%s] Draining node", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-						err := client.Drain(node.Name, config.Get().IgnoreDaemonSets, config.Get().DeleteEmptyDirData, config.Get().PodTerminationGracePeriod)
-						if err != nil {
-							metrics.Server.Errors.Inc()
-							log.Printf("[%s][%s] Skipping because ran into error while draining node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
-							continue
-						} else {
-							metrics.Server.DrainedNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
-							// Only annotate if no error was encountered
-							_ = k8s.AnnotateNodeByAutoScalingInstance(client, outdatedInstance, k8s.AnnotationRollingUpdateDrainedTimestamp, time.Now().Format(time.RFC3339))
-						}
-					} else {
-						log.Printf("[%s][%s] Node has already been drained %d minutes ago, skipping", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), minutesSinceDrained)
-					}
-					if minutesSinceTerminated == -1 {
-						// Terminate node
-						log.Printf("[%s][%s] Terminating node", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-						shouldDecrementDesiredCapacity := aws.Int64Value(autoScalingGroup.DesiredCapacity) != aws.Int64Value(autoScalingGroup.MinSize)
-						err = cloud.TerminateEc2Instance(autoScalingService, outdatedInstance, shouldDecrementDesiredCapacity)
-						if err != nil {
-							metrics.Server.Errors.Inc()
-							log.Printf("[%s][%s] Ran into error while terminating node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
-							continue
-						} else {
-							metrics.Server.ScaledDownNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
-							// Only annotate if no error was encountered
-							_ = k8s.AnnotateNodeByAutoScalingInstance(client, outdatedInstance, k8s.AnnotationRollingUpdateTerminatedTimestamp, time.Now().Format(time.RFC3339))
-						}
-					} else {
-						log.Printf("[%s][%s] Node is already in the process of being terminated since %d minutes ago, skipping", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), minutesSinceTerminated)
-						// TODO: check if minutesSinceTerminated > 10. If that happens, then there's clearly a problem, so we should do something about it
-						// The node has already been terminated, there's nothing to do here, continue to the next one
-						continue
-					}
-					// If this code is reached, it means that the current node has been successfully drained and
-					// scheduled for termination.
-					// As a result, we return here to make sure that multiple old instances didn't use the same updated
-					// instances to calculate resources available
-					log.Printf("[%s][%s] Node has been drained and scheduled for termination successfully", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-					if config.Get().SlowMode {
-						// If SlowMode is enabled, we'll return after draining a node and wait for the next execution
-						return true
-					}
-					// Move on to the next ASG
+				client.RecordEvent(node, v1.EventTypeNormal, k8s.EventReasonRollingUpdateStarted, fmt.Sprintf("Started rolling update because instance %s is outdated", aws.StringValue(outdatedInstance.InstanceId)))
+			}
+			continue
+		}
+		log.Printf("[%s][%s] Node already started rollout process", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+		if minutesSinceTerminated != -1 {
+			if time.Duration(minutesSinceTerminated)*time.Minute >= config.Get().StuckTerminationThreshold {
+				// The instance should've been gone by now, so there's clearly a problem
+				if err := handleStuckTermination(ctx, client, autoScalingService, autoScalingGroup, outdatedInstance, node, minutesSinceTerminated, plan); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			log.Printf("[%s][%s] Node is already in the process of being terminated since %d minutes ago, skipping", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), minutesSinceTerminated)
+			// The node has already been terminated, there's nothing to do here, continue to the next one
+			continue
+		}
+		if minutesSinceDrained != -1 {
+			log.Printf("[%s][%s] Node has already been drained %d minutes ago, skipping", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), minutesSinceDrained)
+			drainedOutdatedNodes = append(drainedOutdatedNodes, &outdatedNode{instance: outdatedInstance, node: node, drained: true})
+		} else {
+			undrainedOutdatedNodes = append(undrainedOutdatedNodes, &outdatedNode{instance: outdatedInstance, node: node})
+		}
+	}
+	// If a node failed to drain too many times and the ASG's drain escalation policy is to block, no other node is
+	// drained until the node's failed drain attempts are reset
+	blocked := false
+	if asgConfig.DrainEscalationPolicy == config.DrainEscalationPolicyBlock {
+		for _, undrainedOutdatedNode := range undrainedOutdatedNodes {
+			if isDrainEscalated(undrainedOutdatedNode.node) {
+				log.Printf("[%s][%s] Rolling update is blocked because node failed to drain too many times", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(undrainedOutdatedNode.instance.InstanceId))
+				blocked = true
+				break
+			}
+		}
+	}
+	if !plan.DryRun {
+		if blocked {
+			metrics.Server.BlockedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(1)
+		} else {
+			metrics.Server.BlockedNodeGroups.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Set(0)
+		}
+	}
+	maxUnavailable, maxSurge := getRolloutBudget(autoScalingGroup, asgConfig)
+	// Nodes that have already been drained only need to be terminated, but they still count towards the budget
+	var outdatedNodesToRollOut []*outdatedNode
+	for _, drainedOutdatedNode := range drainedOutdatedNodes {
+		if len(outdatedNodesToRollOut) >= maxUnavailable {
+			break
+		}
+		outdatedNodesToRollOut = append(outdatedNodesToRollOut, drainedOutdatedNode)
+	}
+	// Select as many outdated nodes as the budget allows, as long as the updated nodes have enough resources
+	// to schedule the pods from every selected outdated node. We need to check every selected node together to
+	// make sure that multiple old nodes don't use the same updated nodes to calculate resources available.
+	var oldNodesToDrain []*v1.Node
+	for i, undrainedOutdatedNode := range undrainedOutdatedNodes {
+		if blocked || len(outdatedNodesToRollOut) >= maxUnavailable || ctx.Err() != nil {
+			break
+		}
+		if isDrainEscalated(undrainedOutdatedNode.node) {
+			switch asgConfig.DrainEscalationPolicy {
+			case config.DrainEscalationPolicySkip:
+				log.Printf("[%s][%s] Skipping because node failed to drain too many times", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(undrainedOutdatedNode.instance.InstanceId))
+				continue
+			case config.DrainEscalationPolicyForce:
+				undrainedOutdatedNode.disableEviction = true
+			}
+		}
+		// Make sure that no PodDisruptionBudget would prevent the node from being drained, otherwise we'd
+		// just be waiting for the drain to time out. If that's the case, we'll try another outdated node instead.
+		// This doesn't apply to nodes drained without eviction, since PodDisruptionBudgets are bypassed.
+		if !undrainedOutdatedNode.disableEviction {
+			blockedByPodDisruptionBudgets, err := isBlockedByPodDisruptionBudgets(ctx, client, autoScalingGroup, asgConfig, undrainedOutdatedNode, plan)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if blockedByPodDisruptionBudgets {
+				continue
+			}
+		}
+		// check if existing updatedInstances have the capacity to support what's inside this node
+		simulation, err := k8s.SimulateSchedulingOfPodsFromOldNodes(client, append(oldNodesToDrain, undrainedOutdatedNode.node), updatedReadyNodes)
+		if err != nil {
+			log.Printf("[%s][%s] Unable to determine resources needed for old node, assuming that enough resources are available: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(undrainedOutdatedNode.instance.InstanceId), err.Error())
+		} else if !simulation.Fits() {
+			log.Printf("[%s][%s] Updated nodes do not have enough resources available: %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(undrainedOutdatedNode.instance.InstanceId), simulation.String())
+			if len(outdatedNodesToRollOut) > 0 {
+				// We're already rolling out other nodes, we'll scale up on the next execution if still necessary
+				break
+			}
+			increment := min(maxSurge, len(undrainedOutdatedNodes)-i)
+			plan.Record(autoScalingGroup, undrainedOutdatedNode.instance, undrainedOutdatedNode.node, StepScaleUp, fmt.Sprintf("%s, increasing desired count by %d", simulation.String(), increment))
+			if !plan.DryRun {
+				log.Printf("[%s][%s] Increasing desired count by %d", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(undrainedOutdatedNode.instance.InstanceId), increment)
+				scaledUpBy, err := cloud.IncrementAutoScalingGroupDesiredCount(ctx, autoScalingService, aws.StringValue(autoScalingGroup.AutoScalingGroupName), int64(increment))
+				if err != nil {
+					errs = append(errs, fmt.Errorf("unable to increase ASG desired size: %w", err))
 					break
-				} else {
-					// Don't increase the ASG if the node has already been drained or scheduled for termination
-					if minutesSinceDrained != -1 || minutesSinceTerminated != -1 {
-						continue
-					}
-					log.Printf("[%s][%s] Updated nodes do not have enough resources available, increasing desired count by 1", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-					err := cloud.IncrementAutoScalingGroupDesiredCount(autoScalingService, aws.StringValue(autoScalingGroup.AutoScalingGroupName))
-					if err != nil {
-						log.Printf("[%s][%s] Unable to increase ASG desired size: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
-						log.Printf("[%s][%s] Skipping", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
-						continue
-					} else {
-						metrics.Server.ScaledUpNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
-						// ASG was scaled up already, stop iterating over outdated instances in current ASG so we can
-						// move on to the next ASG
-						break
-					}
 				}
+				metrics.Server.ScaledUpNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Add(float64(scaledUpBy))
+				client.RecordEvent(undrainedOutdatedNode.node, v1.EventTypeNormal, k8s.EventReasonScaledUp, fmt.Sprintf("Increased desired capacity of ASG %s by %d because %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), scaledUpBy, simulation.String()))
+			}
+			// ASG was scaled up already, stop iterating over outdated instances in current ASG so we can
+			// move on to the next ASG
+			break
+		}
+		log.Printf("[%s][%s] Updated nodes have enough resources available", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(undrainedOutdatedNode.instance.InstanceId))
+		// The slot is released once the node has been rolled out. When planning, no slot is reserved, since no node
+		// is actually drained.
+		if !plan.DryRun && !drainingNodes.TryAcquire() {
+			log.Printf("[%s][%s] Skipping because %d nodes are already being drained across all ASGs", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(undrainedOutdatedNode.instance.InstanceId), config.Get().MaxConcurrentDrains)
+			break
+		}
+		oldNodesToDrain = append(oldNodesToDrain, undrainedOutdatedNode.node)
+		outdatedNodesToRollOut = append(outdatedNodesToRollOut, undrainedOutdatedNode)
+	}
+	if len(outdatedNodesToRollOut) == 0 || ctx.Err() != nil {
+		for _, outdatedNodeToRollOut := range outdatedNodesToRollOut {
+			if !outdatedNodeToRollOut.drained && !plan.DryRun {
+				drainingNodes.Release()
+			}
+		}
+		return ReconcileResult{Requeue: true}, errors.Join(errs...)
+	}
+	// Drain and terminate every selected node concurrently
+	numberOfDecrementsAllowed := aws.Int64Value(autoScalingGroup.DesiredCapacity) - aws.Int64Value(autoScalingGroup.MinSize)
+	terminated := make([]bool, len(outdatedNodesToRollOut))
+	terminationErrs := make([]error, len(outdatedNodesToRollOut))
+	wg := sync.WaitGroup{}
+	for i, outdatedNodeToRollOut := range outdatedNodesToRollOut {
+		// The desired capacity can only be decremented as long as it doesn't go below the ASG's min size
+		shouldDecrementDesiredCapacity := int64(i) < numberOfDecrementsAllowed
+		wg.Add(1)
+		go func(i int, outdatedNodeToRollOut *outdatedNode) {
+			defer wg.Done()
+			if !outdatedNodeToRollOut.drained && !plan.DryRun {
+				defer drainingNodes.Release()
+			}
+			terminated[i], terminationErrs[i] = rollOutNode(ctx, client, autoScalingService, autoScalingGroup, asgConfig, outdatedNodeToRollOut, shouldDecrementDesiredCapacity, plan)
+		}(i, outdatedNodeToRollOut)
+	}
+	wg.Wait()
+	errs = append(errs, terminationErrs...)
+	for _, nodeTerminated := range terminated {
+		if nodeTerminated && asgConfig.SlowMode {
+			// If SlowMode is enabled, we'll return after draining a node and wait for the next execution
+			return ReconcileResult{Requeue: true, SlowedDown: true}, errors.Join(errs...)
+		}
+	}
+	return ReconcileResult{Requeue: true}, errors.Join(errs...)
+}
+
+// outdatedNode is an outdated instance and its corresponding Kubernetes node
+type outdatedNode struct {
+	instance *autoscaling.Instance
+	node     *v1.Node
+	drained  bool
+	// disableEviction is whether the node's pods should be deleted instead of evicted when draining the node
+	disableEviction bool
+}
+
+// isBlockedByPodDisruptionBudgets checks whether at least one of the pods on the given outdated node cannot be evicted
+// because of a PodDisruptionBudget that doesn't allow any disruption, in which case it counts as a failed drain attempt
+//
+// Returns an error if the PodDisruptionBudgets couldn't be checked, in which case the node shouldn't be drained
+func isBlockedByPodDisruptionBudgets(ctx context.Context, client k8s.ClientAPI, autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig, outdatedNode *outdatedNode, plan *Plan) (bool, error) {
+	blockingPodDisruptionBudgets, err := k8s.GetPodDisruptionBudgetsBlockingDrain(ctx, client, outdatedNode.node)
+	if err != nil {
+		return false, fmt.Errorf("unable to check PodDisruptionBudgets of node %s: %w", outdatedNode.node.Name, err)
+	}
+	if len(blockingPodDisruptionBudgets) == 0 {
+		return false, nil
+	}
+	var names []string
+	for _, podDisruptionBudget := range blockingPodDisruptionBudgets {
+		names = append(names, podDisruptionBudget.Namespace+"/"+podDisruptionBudget.Name)
+	}
+	message := fmt.Sprintf("Skipping drain because PodDisruptionBudgets %s do not allow any disruption", strings.Join(names, ", "))
+	log.Printf("[%s][%s] %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedNode.instance.InstanceId), message)
+	if !plan.DryRun {
+		client.RecordEvent(outdatedNode.node, v1.EventTypeWarning, k8s.EventReasonBlockedByPodDisruptionBudget, message)
+		recordFailedDrainAttempt(ctx, client, autoScalingGroup, asgConfig, outdatedNode)
+	}
+	return true, nil
+}
+
+// rollOutNode drains the given outdated node unless it has already been drained, and then terminates it
+//
+// If the context is cancelled while the node is being drained, the node is not terminated.
+//
+// Returns whether the node has been scheduled for termination successfully, and the error that prevented the node from
+// being terminated, if any. Failed drains aren't returned as errors, because they're recorded as failed drain attempts.
+func rollOutNode(ctx context.Context, client k8s.ClientAPI, autoScalingService autoscalingiface.AutoScalingAPI, autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig, outdatedNode *outdatedNode, shouldDecrementDesiredCapacity bool, plan *Plan) (bool, error) {
+	outdatedInstance, node := outdatedNode.instance, outdatedNode.node
+	if !outdatedNode.drained {
+		if asgConfig.ExcludeFromExternalLoadBalancers {
+			plan.Record(autoScalingGroup, outdatedInstance, node, StepExcludeFromExternalLoadBalancers, "node is about to be drained")
+			if !plan.DryRun {
+				log.Printf("[%s][%s] Label node to exclude from external load balancers", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+				k8s.LabelNodeByAutoScalingInstance(ctx, client, outdatedInstance, k8s.LabelExcludeFromExternalLoadBalancers, "true")
+			}
+		}
+		reason := "updated nodes have enough resources available"
+		if outdatedNode.disableEviction {
+			reason += ", deleting pods without eviction because node failed to drain too many times"
+		}
+		plan.Record(autoScalingGroup, outdatedInstance, node, StepDrain, reason)
+		if !plan.DryRun {
+			log.Printf("[%s][%s] Draining node", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+			client.RecordEvent(node, v1.EventTypeNormal, k8s.EventReasonDrainStarted, "Draining node because "+reason)
+			err := client.Drain(ctx, node.Name, asgConfig.IgnoreDaemonSets, asgConfig.DeleteEmptyDirData, asgConfig.PodTerminationGracePeriod, outdatedNode.disableEviction)
+			if err != nil && ctx.Err() != nil {
+				// The drain was interrupted because the execution timed out or the application is shutting down
+				rollBackInterruptedDrain(ctx, client, autoScalingGroup, asgConfig, outdatedNode)
+				return false, nil
+			} else if err != nil {
+				metrics.Server.Errors.Inc()
+				client.RecordEvent(node, v1.EventTypeWarning, k8s.EventReasonDrainFailed, fmt.Sprintf("Failed to drain node: %v", err))
+				log.Printf("[%s][%s] Skipping because ran into error while draining node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
+				recordFailedDrainAttempt(ctx, client, autoScalingGroup, asgConfig, outdatedNode)
+				return false, nil
+			} else {
+				metrics.Server.DrainedNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
+				// Only annotate if no error was encountered. The annotation is added even if the execution has been
+				// cancelled in the meantime, otherwise the node would be drained again on the next execution.
+				cleanupCtx, cancel := withoutCancel(ctx)
+				_ = k8s.AnnotateNodeByAutoScalingInstance(cleanupCtx, client, outdatedInstance, k8s.AnnotationRollingUpdateDrainedTimestamp, time.Now().Format(time.RFC3339))
+				cancel()
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		log.Printf("[%s][%s] Skipping termination because execution was cancelled", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+		return false, nil
+	}
+	// Terminate node
+	plan.Record(autoScalingGroup, outdatedInstance, node, StepTerminate, "node has been drained")
+	if !plan.DryRun {
+		log.Printf("[%s][%s] Terminating node", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+		err := cloud.TerminateEc2Instance(ctx, autoScalingService, outdatedInstance, shouldDecrementDesiredCapacity)
+		if err != nil {
+			return false, fmt.Errorf("unable to terminate instance %s: %w", aws.StringValue(outdatedInstance.InstanceId), err)
+		} else {
+			metrics.Server.ScaledDownNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
+			client.RecordEvent(node, v1.EventTypeNormal, k8s.EventReasonTerminated, fmt.Sprintf("Instance %s has been scheduled for termination", aws.StringValue(outdatedInstance.InstanceId)))
+			// Only annotate if no error was encountered
+			cleanupCtx, cancel := withoutCancel(ctx)
+			_ = k8s.AnnotateNodeByAutoScalingInstance(cleanupCtx, client, outdatedInstance, k8s.AnnotationRollingUpdateTerminatedTimestamp, time.Now().Format(time.RFC3339))
+			cancel()
+		}
+	}
+	log.Printf("[%s][%s] Node has been drained and scheduled for termination successfully", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+	return true, nil
+}
+
+// handleStuckTermination remediates an outdated instance that is still part of its ASG even though it was scheduled
+// for termination more than StuckTerminationThreshold ago, based on the instance's lifecycle state:
+//   - If the instance is still InService, the termination request didn't go through, so it is issued again
+//   - If the instance is in Terminating:Wait, a termination lifecycle hook is holding it, so its pending lifecycle
+//     actions are completed if CompleteStuckLifecycleActions is enabled
+//   - Otherwise, the instance is already being terminated by AWS, so there is nothing else to do
+//
+// In every case, a warning event is recorded on the node and the node's termination timestamp is refreshed, so that
+// the instance is only remediated again if it is still there after another StuckTerminationThreshold.
+//
+// Returns the error that prevented the instance from being remediated, if any
+func handleStuckTermination(ctx context.Context, client k8s.ClientAPI, autoScalingService autoscalingiface.AutoScalingAPI, autoScalingGroup *autoscaling.Group, outdatedInstance *autoscaling.Instance, node *v1.Node, minutesSinceTerminated int, plan *Plan) error {
+	lifecycleState := aws.StringValue(outdatedInstance.LifecycleState)
+	log.Printf("[%s][%s] Instance is still in lifecycle state '%s' even though it was scheduled for termination %d minutes ago", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), lifecycleState, minutesSinceTerminated)
+	message := fmt.Sprintf("Instance %s is still in lifecycle state %s even though it was scheduled for termination %d minutes ago", aws.StringValue(outdatedInstance.InstanceId), lifecycleState, minutesSinceTerminated)
+	var remediationErr error
+	switch lifecycleState {
+	case autoscaling.LifecycleStateInService:
+		plan.Record(autoScalingGroup, outdatedInstance, node, StepTerminate, "previous termination is stuck")
+		if !plan.DryRun {
+			log.Printf("[%s][%s] Terminating node again", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+			// The desired capacity may have already been decremented by the previous termination
+			if err := cloud.TerminateEc2Instance(ctx, autoScalingService, outdatedInstance, false); err != nil {
+				remediationErr = fmt.Errorf("unable to terminate instance %s again: %w", aws.StringValue(outdatedInstance.InstanceId), err)
+				message += fmt.Sprintf(", failed to terminate it again: %v", err)
+			} else {
+				message += ", terminated it again"
 			}
 		}
+	case autoscaling.LifecycleStateTerminatingWait:
+		if !config.Get().CompleteStuckLifecycleActions {
+			message += ", a termination lifecycle hook is likely waiting for a lifecycle action to be completed"
+			break
+		}
+		plan.Record(autoScalingGroup, outdatedInstance, node, StepCompleteLifecycleAction, "previous termination is stuck in "+lifecycleState)
+		if !plan.DryRun {
+			log.Printf("[%s][%s] Completing termination lifecycle actions", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+			completed, err := cloud.CompleteTerminationLifecycleActions(ctx, autoScalingService, aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+			if err != nil {
+				remediationErr = fmt.Errorf("unable to complete lifecycle actions of instance %s: %w", aws.StringValue(outdatedInstance.InstanceId), err)
+				message += fmt.Sprintf(", failed to complete its lifecycle actions: %v", err)
+			} else {
+				message += fmt.Sprintf(", completed %d lifecycle action(s)", completed)
+			}
+		}
+	}
+	if plan.DryRun {
+		return nil
+	}
+	metrics.Server.StuckTerminations.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
+	client.RecordEvent(node, v1.EventTypeWarning, k8s.EventReasonTerminationStuck, message)
+	// The termination timestamp is refreshed even if the execution has been cancelled in the meantime, since the
+	// instance may have been terminated again
+	cleanupCtx, cancel := withoutCancel(ctx)
+	defer cancel()
+	_ = k8s.AnnotateNodeByAutoScalingInstance(cleanupCtx, client, outdatedInstance, k8s.AnnotationRollingUpdateTerminatedTimestamp, time.Now().Format(time.RFC3339))
+	return remediationErr
+}
+
+// rollBackInterruptedDrain reverts the changes made to an outdated node whose drain was interrupted, so that the node
+// isn't left cordoned with only some of its pods evicted. The node's rollout starts over on the next execution.
+//
+// The node is only uncordoned if it was cordoned by the drain itself.
+func rollBackInterruptedDrain(ctx context.Context, client k8s.ClientAPI, autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig, outdatedNode *outdatedNode) {
+	outdatedInstance, node := outdatedNode.instance, outdatedNode.node
+	log.Printf("[%s][%s] Rolling back node because its drain was interrupted", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId))
+	ctx, cancel := withoutCancel(ctx)
+	defer cancel()
+	revertDrain(ctx, client, autoScalingGroup, asgConfig, outdatedNode, k8s.AnnotationRollingUpdateStartedTimestamp)
+	metrics.Server.RolledBackNodes.WithLabelValues(aws.StringValue(autoScalingGroup.AutoScalingGroupName)).Inc()
+	client.RecordEvent(node, v1.EventTypeNormal, k8s.EventReasonDrainInterrupted, "Drain was interrupted, node has been rolled back and will be rolled out again later")
+}
+
+// revertDrain makes an outdated node that couldn't be drained schedulable again and, if applicable, removes the label
+// excluding it from external load balancers as well as the given annotations, all in a single patch
+//
+// The node is only uncordoned if it was cordoned by the drain itself.
+func revertDrain(ctx context.Context, client k8s.ClientAPI, autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig, outdatedNode *outdatedNode, annotationsToRemove ...string) {
+	outdatedInstance, node := outdatedNode.instance, outdatedNode.node
+	patch := k8s.NodePatch{Annotations: make(map[string]*string)}
+	if !asgConfig.EagerCordoning && !node.Spec.Unschedulable {
+		patch.Unschedulable = aws.Bool(false)
+	}
+	if asgConfig.ExcludeFromExternalLoadBalancers {
+		patch.Labels = map[string]*string{k8s.LabelExcludeFromExternalLoadBalancers: nil}
+	}
+	for _, annotation := range annotationsToRemove {
+		patch.Annotations[annotation] = nil
+	}
+	if patch.Unschedulable == nil && len(patch.Labels) == 0 && len(patch.Annotations) == 0 {
+		return
+	}
+	if err := k8s.PatchNodeByAutoScalingInstance(ctx, client, outdatedInstance, patch); err != nil {
+		metrics.Server.Errors.Inc()
+		log.Printf("[%s][%s] Unable to revert drain of node: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(outdatedInstance.InstanceId), err.Error())
 	}
-	return true
 }
 
-func getReadyNodesAndNumberOfNonReadyNodesOrInstances(client k8s.ClientAPI, updatedInstances []*autoscaling.Instance, autoScalingGroup *autoscaling.Group) ([]*v1.Node, int) {
+// withoutCancel returns a context that isn't cancelled along with the given context, but that expires after
+// CleanupTimeout. It is used for the steps that must complete to avoid leaving a node in an inconsistent state.
+func withoutCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), CleanupTimeout)
+}
+
+// getRolloutBudget resolves the maximum number of outdated nodes that can be rolled out at the same time as well as
+// the maximum number of nodes that can be added to the ASG at once for a given ASG and its effective configuration.
+//
+// Percentages are resolved based on the current number of instances in the ASG; MaxUnavailable is rounded down while
+// MaxSurge is rounded up, and both are never lower than 1.
+func getRolloutBudget(autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig) (maxUnavailable, maxSurge int) {
+	numberOfInstances := len(autoScalingGroup.Instances)
+	maxUnavailableValue, maxSurgeValue := asgConfig.MaxUnavailable, asgConfig.MaxSurge
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailableValue, numberOfInstances, false)
+	if err != nil || maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	maxSurge, err = intstr.GetScaledValueFromIntOrPercent(&maxSurgeValue, numberOfInstances, true)
+	if err != nil || maxSurge < 1 {
+		maxSurge = 1
+	}
+	return maxUnavailable, maxSurge
+}
+
+// getReadyNodesAndNonReadyInstances separates the updated instances whose node is ready and passes the ASG's health
+// gates from the updated instances that aren't ready yet
+func getReadyNodesAndNonReadyInstances(ctx context.Context, client k8s.ClientAPI, updatedInstances []*autoscaling.Instance, autoScalingGroup *autoscaling.Group, asgConfig *config.AutoScalingGroupConfig, plan *Plan) ([]*v1.Node, []*autoscaling.Instance) {
 	var updatedReadyNodes []*v1.Node
-	numberOfNonReadyNodesOrInstances := 0
+	var nonReadyInstances []*autoscaling.Instance
 	for _, updatedInstance := range updatedInstances {
 		if aws.StringValue(updatedInstance.LifecycleState) != "InService" {
-			numberOfNonReadyNodesOrInstances++
+			nonReadyInstances = append(nonReadyInstances, updatedInstance)
 			log.Printf("[%s][%s] Skipping because instance is not in LifecycleState 'InService', but is in '%s' instead", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(updatedInstance.InstanceId), aws.StringValue(updatedInstance.LifecycleState))
 			continue
 		}
 		updatedNode, err := client.GetNodeByAutoScalingInstance(updatedInstance)
 		if err != nil {
-			numberOfNonReadyNodesOrInstances++
+			nonReadyInstances = append(nonReadyInstances, updatedInstance)
 			log.Printf("[%s][%s] Skipping because unable to get updated node from Kubernetes: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(updatedInstance.InstanceId), err.Error())
 			continue
 		}
 		// Check if Kubelet is ready to accept pods on that node
-		conditions := updatedNode.Status.Conditions
-		if len(conditions) == 0 {
+		if len(updatedNode.Status.Conditions) == 0 {
 			log.Printf("[%s][%s] For some magical reason, %s doesn't have any conditions, therefore it is impossible to determine whether the node is ready to accept new pods or not", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(updatedInstance.InstanceId), updatedNode.Name)
-			numberOfNonReadyNodesOrInstances++
-		} else if kubeletCondition := conditions[len(conditions)-1]; kubeletCondition.Type == v1.NodeReady {
-			if kubeletCondition.Status == v1.ConditionTrue {
-				updatedReadyNodes = append(updatedReadyNodes, updatedNode)
-			} else {
+			nonReadyInstances = append(nonReadyInstances, updatedInstance)
+		} else if kubeletCondition := k8s.GetNodeCondition(updatedNode, v1.NodeReady); kubeletCondition != nil {
+			if kubeletCondition.Status != v1.ConditionTrue {
 				log.Printf("[%s][%s] Skipping because kubelet condition %s is reporting as %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(updatedInstance.InstanceId), kubeletCondition.Type, kubeletCondition.Status)
-				numberOfNonReadyNodesOrInstances++
+				nonReadyInstances = append(nonReadyInstances, updatedInstance)
+			} else if err := k8s.CheckNodeHealthGates(client, updatedNode, asgConfig.HealthGates); err != nil {
+				log.Printf("[%s][%s] Skipping because node hasn't passed its health gates yet: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(updatedInstance.InstanceId), err.Error())
+				nonReadyInstances = append(nonReadyInstances, updatedInstance)
+			} else {
+				updatedReadyNodes = append(updatedReadyNodes, updatedNode)
 			}
 		} else {
 			log.Printf("[%s][%s] Skipping because expected kubelet on node to have condition %s with value %s, but it didn't", aws.StringValue(autoScalingGroup.AutoScalingGroupName), aws.StringValue(updatedInstance.InstanceId), v1.NodeReady, v1.ConditionTrue)
-			numberOfNonReadyNodesOrInstances++
+			nonReadyInstances = append(nonReadyInstances, updatedInstance)
 		}
 
 		// Cleaning up
@@ -310,15 +762,21 @@ func getReadyNodesAndNumberOfNonReadyNodesOrInstances(client k8s.ClientAPI, upda
 					// If the annotation can't be parsed OR the taint was added after the rolling updated started,
 					// we need to remove that taint
 					if err != nil || taint.TimeAdded.Time.After(startedAt) {
+						plan.Record(autoScalingGroup, updatedInstance, updatedNode, StepRemoveTaint, "EDGE-0001: updated node was tainted by a previous rolling update")
+						if plan.DryRun {
+							break
+						}
 						log.Printf("[%s] EDGE-0001: Attempting to remove taint from updated node %s", aws.StringValue(autoScalingGroup.AutoScalingGroupName), updatedNode.Name)
 						// Remove the taint
 						updatedNode.Spec.Taints = append(updatedNode.Spec.Taints[:i], updatedNode.Spec.Taints[i+1:]...)
 						// Remove the annotation
 						delete(updatedNode.Annotations, k8s.AnnotationRollingUpdateStartedTimestamp)
 						// Update the node
-						err = client.UpdateNode(updatedNode)
+						err = client.UpdateNode(ctx, updatedNode)
 						if err != nil {
 							log.Printf("[%s] EDGE-0001: Unable to update tainted node %s: %v", aws.StringValue(autoScalingGroup.AutoScalingGroupName), updatedNode.Name, err.Error())
+						} else {
+							client.RecordEvent(updatedNode, v1.EventTypeNormal, k8s.EventReasonTaintRemoved, fmt.Sprintf("EDGE-0001: Removed %s taint %s added by a previous rolling update", taint.Effect, taint.Key))
 						}
 						break
 					}
@@ -326,7 +784,19 @@ func getReadyNodesAndNumberOfNonReadyNodesOrInstances(client k8s.ClientAPI, upda
 			}
 		}
 	}
-	return updatedReadyNodes, numberOfNonReadyNodesOrInstances
+	return updatedReadyNodes, nonReadyInstances
+}
+
+// isNodePaused checks whether the given outdated node is exempted from being drained through AnnotationPaused.
+//
+// A node that has already been drained or terminated is never considered as paused, because it's too late to exempt it.
+func isNodePaused(node *v1.Node) bool {
+	if strings.ToLower(strings.TrimSpace(node.Annotations[k8s.AnnotationPaused])) != "true" {
+		return false
+	}
+	_, drained := node.Annotations[k8s.AnnotationRollingUpdateDrainedTimestamp]
+	_, terminated := node.Annotations[k8s.AnnotationRollingUpdateTerminatedTimestamp]
+	return !drained && !terminated
 }
 
 func getRollingUpdateTimestampsFromNode(node *v1.Node) (minutesSinceStarted, minutesSinceDrained, minutesSinceTerminated int) {
@@ -362,7 +832,7 @@ func getRollingUpdateTimestampsFromNode(node *v1.Node) (minutesSinceStarted, min
 
 // SeparateOutdatedFromUpdatedInstances splits a list of instances into a list of outdated
 // instances and a list of updated instances.
-func SeparateOutdatedFromUpdatedInstances(asg *autoscaling.Group, ec2Svc ec2iface.EC2API) ([]*autoscaling.Instance, []*autoscaling.Instance, error) {
+func SeparateOutdatedFromUpdatedInstances(ctx context.Context, asg *autoscaling.Group, ec2Svc ec2iface.EC2API) ([]*autoscaling.Instance, []*autoscaling.Instance, error) {
 	if config.Get().Debug {
 		log.Printf("[%s] Separating outdated from updated instances", aws.StringValue(asg.AutoScalingGroupName))
 	}
@@ -377,7 +847,7 @@ func SeparateOutdatedFromUpdatedInstances(asg *autoscaling.Group, ec2Svc ec2ifac
 		targetLaunchTemplateOverrides = asg.MixedInstancesPolicy.LaunchTemplate.Overrides
 	}
 	if targetLaunchTemplate != nil {
-		return SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(aws.StringValue(asg.AutoScalingGroupName), targetLaunchTemplate, targetLaunchTemplateOverrides, asg.Instances, ec2Svc)
+		return SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(ctx, aws.StringValue(asg.AutoScalingGroupName), targetLaunchTemplate, targetLaunchTemplateOverrides, asg.Instances, ec2Svc)
 	} else if targetLaunchConfiguration != nil {
 		return SeparateOutdatedFromUpdatedInstancesUsingLaunchConfiguration(targetLaunchConfiguration, asg.Instances)
 	}
@@ -386,7 +856,7 @@ func SeparateOutdatedFromUpdatedInstances(asg *autoscaling.Group, ec2Svc ec2ifac
 
 // SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate separates a list of instances into a list of outdated
 // instances and a list of updated instances.
-func SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(asgName string, targetLaunchTemplate *autoscaling.LaunchTemplateSpecification, overrides []*autoscaling.LaunchTemplateOverrides, instances []*autoscaling.Instance, ec2Svc ec2iface.EC2API) ([]*autoscaling.Instance, []*autoscaling.Instance, error) {
+func SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(ctx context.Context, asgName string, targetLaunchTemplate *autoscaling.LaunchTemplateSpecification, overrides []*autoscaling.LaunchTemplateOverrides, instances []*autoscaling.Instance, ec2Svc ec2iface.EC2API) ([]*autoscaling.Instance, []*autoscaling.Instance, error) {
 	var (
 		oldInstances   []*autoscaling.Instance
 		newInstances   []*autoscaling.Instance
@@ -395,12 +865,12 @@ func SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(asgName string, tar
 	)
 	switch {
 	case targetLaunchTemplate.LaunchTemplateId != nil && aws.StringValue(targetLaunchTemplate.LaunchTemplateId) != "":
-		if targetTemplate, err = cloud.DescribeLaunchTemplateByID(ec2Svc, aws.StringValue(targetLaunchTemplate.LaunchTemplateId)); err != nil {
-			return nil, nil, fmt.Errorf("error retrieving information about launch template %s: %v", aws.StringValue(targetLaunchTemplate.LaunchTemplateId), err)
+		if targetTemplate, err = cloud.DescribeLaunchTemplateByID(ctx, ec2Svc, aws.StringValue(targetLaunchTemplate.LaunchTemplateId)); err != nil {
+			return nil, nil, fmt.Errorf("error retrieving information about launch template %s: %w", aws.StringValue(targetLaunchTemplate.LaunchTemplateId), err)
 		}
 	case targetLaunchTemplate.LaunchTemplateName != nil && aws.StringValue(targetLaunchTemplate.LaunchTemplateName) != "":
-		if targetTemplate, err = cloud.DescribeLaunchTemplateByName(ec2Svc, aws.StringValue(targetLaunchTemplate.LaunchTemplateName)); err != nil {
-			return nil, nil, fmt.Errorf("error retrieving information about launch template name %s: %v", aws.StringValue(targetLaunchTemplate.LaunchTemplateName), err)
+		if targetTemplate, err = cloud.DescribeLaunchTemplateByName(ctx, ec2Svc, aws.StringValue(targetLaunchTemplate.LaunchTemplateName)); err != nil {
+			return nil, nil, fmt.Errorf("error retrieving information about launch template name %s: %w", aws.StringValue(targetLaunchTemplate.LaunchTemplateName), err)
 		}
 	default:
 		return nil, nil, fmt.Errorf("invalid launch template name")
@@ -418,7 +888,7 @@ func SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(asgName string, tar
 			)
 			for _, override := range overrides {
 				if aws.StringValue(override.InstanceType) == aws.StringValue(instance.InstanceType) && override.LaunchTemplateSpecification != nil {
-					if overrideTargetTemplate, err = cloud.DescribeLaunchTemplateByName(ec2Svc, aws.StringValue(override.LaunchTemplateSpecification.LaunchTemplateName)); err != nil {
+					if overrideTargetTemplate, err = cloud.DescribeLaunchTemplateByName(ctx, ec2Svc, aws.StringValue(override.LaunchTemplateSpecification.LaunchTemplateName)); err != nil {
 						log.Printf("[%s][%s] Unable to retrieve information for launch template with name '%s': %v", asgName, aws.StringValue(instance.InstanceId), aws.StringValue(override.LaunchTemplateSpecification.LaunchTemplateName), err)
 					}
 					overrideTargetLaunchTemplate = override.LaunchTemplateSpecification
@@ -515,15 +985,35 @@ func compareLaunchTemplateVersions(targetTemplate *ec2.LaunchTemplate, lt1, lt2
 //
 // The logic behind this is that the more nodes are ready and updated, the higher the confidence we have that the
 // upgrade is going well, so we can ramp things up faster the deeper we are in the upgrade process.
-func HasAcceptableNumberOfUpdatedNonReadyNodes(numberOfUpdatedNonReadyNodes, numberOfUpdatedReadyNodes int) bool {
+//
+// Updated nodes that have been ready for less than the ASG's UpdatedNodeMinReadyDuration are considered non-ready.
+func HasAcceptableNumberOfUpdatedNonReadyNodes(numberOfUpdatedNonReadyNodes int, updatedReadyNodes []*v1.Node, asgConfig *config.AutoScalingGroupConfig) bool {
+	numberOfUpdatedReadyNodes := 0
+	for _, updatedReadyNode := range updatedReadyNodes {
+		if isNodeReadyForAtLeast(updatedReadyNode, asgConfig.UpdatedNodeMinReadyDuration) {
+			numberOfUpdatedReadyNodes++
+		} else {
+			numberOfUpdatedNonReadyNodes++
+		}
+	}
 	if numberOfUpdatedNonReadyNodes == 0 {
 		return true // all updated nodes are ready, so we can proceed
 	}
 	if numberOfUpdatedReadyNodes == 0 {
 		return false // there are no ready nodes AND there are non-ready nodes (we know this because of the previous check), so we cannot proceed
 	}
-	if numberOfUpdatedNonReadyNodes > MaximumNumberOfUpdatedNonReadyNodes {
+	if numberOfUpdatedNonReadyNodes > asgConfig.MaxUpdatedNonReadyNodes {
 		return false // there are too many non-ready nodes, so we cannot proceed
 	}
-	return float64(numberOfUpdatedNonReadyNodes)/float64(numberOfUpdatedReadyNodes) <= MaximumAcceptableUpdatedNonReadyToUpdatedReadyNodesRatio
+	return float64(numberOfUpdatedNonReadyNodes)/float64(numberOfUpdatedReadyNodes) <= asgConfig.MaxUpdatedNonReadyNodesRatio
+}
+
+// isNodeReadyForAtLeast checks whether the NodeReady condition of the given node has been true for at least the
+// given duration
+func isNodeReadyForAtLeast(node *v1.Node, duration time.Duration) bool {
+	if duration <= 0 {
+		return true
+	}
+	condition := k8s.GetNodeCondition(node, v1.NodeReady)
+	return condition != nil && condition.Status == v1.ConditionTrue && time.Since(condition.LastTransitionTime.Time) >= duration
 }
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/TwiN/aws-eks-asg-rolling-update-handler/cloudtest"
 	"github.com/TwiN/aws-eks-asg-rolling-update-handler/config"
@@ -9,8 +14,12 @@ import (
 	"github.com/TwiN/aws-eks-asg-rolling-update-handler/k8stest"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestSeparateOutdatedFromUpdatedInstancesUsingLaunchConfiguration_whenInstanceIsOutdated(t *testing.T) {
@@ -69,7 +78,7 @@ func TestSeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate_whenInstanceIsO
 		LaunchTemplateName:   updatedLaunchTemplate.LaunchTemplateName,
 	}
 	instance := cloudtest.CreateTestAutoScalingInstance("instance", "", outdatedLaunchTemplate, "InService")
-	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate("test", updatedLaunchTemplate, nil, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
+	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(context.Background(), "test", updatedLaunchTemplate, nil, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
 	if err != nil {
 		t.Fatal("Shouldn't have returned an error, but returned:", err)
 	}
@@ -97,7 +106,7 @@ func TestSeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate_whenInstanceIsO
 		{InstanceType: aws.String("c5d.2xlarge")},
 	}
 	// Notice: The instance's instance type isn't part of the overrides.
-	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate("test", launchTemplate, overrides, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
+	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(context.Background(), "test", launchTemplate, overrides, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
 	if err != nil {
 		t.Fatal("Shouldn't have returned an error, but returned:", err)
 	}
@@ -119,7 +128,7 @@ func TestSeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate_whenInstanceIsU
 		LaunchTemplateName:   updatedLaunchTemplate.LaunchTemplateName,
 	}
 	instance := cloudtest.CreateTestAutoScalingInstance("instance", "", updatedLaunchTemplate, "InService")
-	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate("test", updatedLaunchTemplate, nil, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
+	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(context.Background(), "test", updatedLaunchTemplate, nil, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
 	if err != nil {
 		t.Fatal("Shouldn't have returned an error, but returned:", err)
 	}
@@ -146,7 +155,7 @@ func TestSeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate_whenInstanceWit
 		{InstanceType: aws.String("c5.2xlarge")},
 		{InstanceType: aws.String("c5d.2xlarge")},
 	}
-	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate("test", launchTemplate, overrides, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
+	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(context.Background(), "test", launchTemplate, overrides, []*autoscaling.Instance{instance}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
 	if err != nil {
 		t.Fatal("Shouldn't have returned an error, but returned:", err)
 	}
@@ -175,7 +184,7 @@ func TestSeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate_whenInstanceWit
 		{InstanceType: aws.String("c5.2xlarge"), LaunchTemplateSpecification: launchTemplate},
 		{InstanceType: aws.String("c5d.2xlarge")},
 	}
-	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate("test", launchTemplate, overrides, []*autoscaling.Instance{instance, instanceWithLaunchTemplateOverride}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
+	outdated, updated, err := SeparateOutdatedFromUpdatedInstancesUsingLaunchTemplate(context.Background(), "test", launchTemplate, overrides, []*autoscaling.Instance{instance, instanceWithLaunchTemplateOverride}, cloudtest.NewMockEC2Service([]*ec2.LaunchTemplate{updatedEc2LaunchTemplate}))
 	if err != nil {
 		t.Fatal("Shouldn't have returned an error, but returned:", err)
 	}
@@ -191,7 +200,7 @@ func TestSeparateOutdatedFromUpdatedInstances_withLaunchConfigurationWhenOneInst
 
 	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{firstInstance, secondInstance, thirdInstance}, false)
 
-	outdated, updated, err := SeparateOutdatedFromUpdatedInstances(asg, nil)
+	outdated, updated, err := SeparateOutdatedFromUpdatedInstances(context.Background(), asg, nil)
 	if err != nil {
 		t.Fatal("Shouldn't have returned an error, but returned", err)
 	}
@@ -203,7 +212,7 @@ func TestSeparateOutdatedFromUpdatedInstances_withLaunchConfigurationWhenOneInst
 	}
 }
 
-func TestHandleRollingUpgrade(t *testing.T) {
+func TestReconcileAutoScalingGroup(t *testing.T) {
 	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
 	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance}, false)
 
@@ -215,12 +224,12 @@ func TestHandleRollingUpgrade(t *testing.T) {
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (Node rollout process gets marked as started)
-	err := HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
-	if mockClient.Counter["UpdateNode"] != 1 {
-		t.Error("Node should've been annotated, meaning that UpdateNode should've been called once")
+	if mockClient.Counter["PatchNode"] != 1 {
+		t.Error("Node should've been annotated, meaning that PatchNode should've been called once")
 	}
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateStartedTimestamp]; !ok {
@@ -234,7 +243,7 @@ func TestHandleRollingUpgrade(t *testing.T) {
 	}
 
 	// Second run (ASG's desired capacity gets increased)
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -251,7 +260,7 @@ func TestHandleRollingUpgrade(t *testing.T) {
 	}
 
 	// Third run (Nothing changed)
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -270,7 +279,7 @@ func TestHandleRollingUpgrade(t *testing.T) {
 	// Fourth run (new instance has been registered to ASG, but is pending)
 	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "Pending")
 	asg.Instances = append(asg.Instances, newInstance)
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -284,7 +293,7 @@ func TestHandleRollingUpgrade(t *testing.T) {
 
 	// Fifth run (new instance is now InService, but node has still not joined cluster (GetNodeByAutoScalingInstance should return not found))
 	newInstance.SetLifecycleState("InService")
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -297,7 +306,7 @@ func TestHandleRollingUpgrade(t *testing.T) {
 	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
 	mockClient.Nodes[newNode.Name] = newNode
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -310,7 +319,7 @@ func TestHandleRollingUpgrade(t *testing.T) {
 	newNode = mockClient.Nodes[newNode.Name]
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
 	mockClient.Nodes[newNode.Name] = newNode
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -321,9 +330,13 @@ func TestHandleRollingUpgrade(t *testing.T) {
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateTerminatedTimestamp]; !ok {
 		t.Error("Node should've been terminated")
 	}
+	expectedEvents := []string{k8s.EventReasonRollingUpdateStarted, k8s.EventReasonScaledUp, k8s.EventReasonDrainStarted, k8s.EventReasonTerminated}
+	if !reflect.DeepEqual(mockClient.Events[oldNode.Name], expectedEvents) {
+		t.Errorf("expected events %v to have been recorded for the old node, got %v", expectedEvents, mockClient.Events[oldNode.Name])
+	}
 }
 
-func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
+func TestReconcileAutoScalingGroup_withLaunchTemplate(t *testing.T) {
 	oldLaunchTemplateSpecification := &autoscaling.LaunchTemplateSpecification{
 		LaunchTemplateId:   aws.String("lt1"),
 		LaunchTemplateName: aws.String("lt1"),
@@ -351,9 +364,9 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (Node rollout process gets marked as started)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
-	if mockClient.Counter["UpdateNode"] != 1 {
-		t.Error("Node should've been annotated, meaning that UpdateNode should've been called once")
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if mockClient.Counter["PatchNode"] != 1 {
+		t.Error("Node should've been annotated, meaning that PatchNode should've been called once")
 	}
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateStartedTimestamp]; !ok {
@@ -367,7 +380,7 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 	}
 
 	// Second run (ASG's desired capacity gets increased)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 1 {
 		t.Error("ASG should've been increased because there's no updated nodes yet")
 	}
@@ -381,7 +394,7 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 	}
 
 	// Third run (Nothing changed)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 1 {
 		t.Error("Desired capacity shouldn't have been updated")
 	}
@@ -397,7 +410,7 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 	// Fourth run (new instance has been registered to ASG, but is pending)
 	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "", newLaunchTemplateSpecification, "Pending")
 	asg.Instances = append(asg.Instances, newInstance)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 1 {
 		t.Error("Desired capacity shouldn't have been updated")
 	}
@@ -408,7 +421,7 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 
 	// Fifth run (new instance is now InService, but node has still not joined cluster (GetNodeByAutoScalingInstance should return not found))
 	newInstance.SetLifecycleState("InService")
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateDrainedTimestamp]; ok {
 		t.Error("Node shouldn't have been drained yet, therefore shouldn't have been annotated with", k8s.AnnotationRollingUpdateDrainedTimestamp)
@@ -418,7 +431,7 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
 	mockClient.Nodes[newNode.Name] = newNode
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateDrainedTimestamp]; ok {
 		t.Error("Node shouldn't have been drained yet, therefore shouldn't have been annotated with", k8s.AnnotationRollingUpdateDrainedTimestamp)
@@ -428,7 +441,7 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 	newNode = mockClient.Nodes[newNode.Name]
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
 	mockClient.Nodes[newNode.Name] = newNode
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateDrainedTimestamp]; !ok {
 		t.Error("Node should've been drained")
@@ -438,7 +451,7 @@ func TestHandleRollingUpgrade_withLaunchTemplate(t *testing.T) {
 	}
 }
 
-func TestHandleRollingUpgrade_withLaunchTemplateWhenLaunchTemplateDidNotUpdate(t *testing.T) {
+func TestReconcileAutoScalingGroup_withLaunchTemplateWhenLaunchTemplateDidNotUpdate(t *testing.T) {
 	launchTemplateSpecification := &autoscaling.LaunchTemplateSpecification{
 		LaunchTemplateId:   aws.String("lt1"),
 		LaunchTemplateName: aws.String("lt1"),
@@ -460,13 +473,13 @@ func TestHandleRollingUpgrade_withLaunchTemplateWhenLaunchTemplateDidNotUpdate(t
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (No changes, no updates)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
-	if mockClient.Counter["UpdateNode"] != 0 {
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if mockClient.Counter["PatchNode"] != 0 {
 		t.Error("The LT hasn't been updated, therefore nothing should've changed")
 	}
 }
 
-func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
+func TestReconcileAutoScalingGroup_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
 	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance}, false)
 
@@ -483,9 +496,9 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (Node rollout process gets marked as started)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
-	if mockClient.Counter["UpdateNode"] != 1 {
-		t.Error("Node should've been annotated, meaning that UpdateNode should've been called once")
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if mockClient.Counter["PatchNode"] != 1 {
+		t.Error("Node should've been annotated, meaning that PatchNode should've been called once")
 	}
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateStartedTimestamp]; !ok {
@@ -499,7 +512,7 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	}
 
 	// Second run (ASG's desired capacity gets increased)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 1 {
 		t.Error("ASG should've been increased because there's no updated nodes yet")
 	}
@@ -513,7 +526,7 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	}
 
 	// Third run (Nothing changed)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 1 {
 		t.Error("Desired capacity shouldn't have been updated")
 	}
@@ -529,7 +542,7 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	// Fourth run (new instance has been registered to ASG, but is pending)
 	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "Pending")
 	asg.Instances = append(asg.Instances, newInstance)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 1 {
 		t.Error("Desired capacity shouldn't have been updated")
 	}
@@ -540,7 +553,7 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 
 	// Fifth run (new instance is now InService, but node has still not joined cluster (GetNodeByAutoScalingInstance should return not found))
 	newInstance.SetLifecycleState("InService")
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateDrainedTimestamp]; ok {
 		t.Error("Node shouldn't have been drained yet, therefore shouldn't have been annotated with", k8s.AnnotationRollingUpdateDrainedTimestamp)
@@ -550,7 +563,7 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
 	mockClient.Nodes[newNode.Name] = newNode
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateDrainedTimestamp]; ok {
 		t.Error("Node shouldn't have been drained yet, therefore shouldn't have been annotated with", k8s.AnnotationRollingUpdateDrainedTimestamp)
@@ -560,14 +573,14 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	newNode = mockClient.Nodes[newNode.Name]
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
 	mockClient.Nodes[newNode.Name] = newNode
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateDrainedTimestamp]; ok {
 		t.Error("Node shouldn't have been drained yet, therefore shouldn't have been annotated with", k8s.AnnotationRollingUpdateDrainedTimestamp)
 	}
 
 	// Eight run (ASG's desired capacity gets increased)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 2 {
 		t.Error("ASG should've been increased again")
 	}
@@ -586,7 +599,7 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 	newSecondNode := k8stest.CreateTestNode("new-node-2", aws.StringValue(newSecondInstance.AvailabilityZone), aws.StringValue(newSecondInstance.InstanceId), "1000m", "1000Mi")
 	newSecondNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
 	mockClient.Nodes[newSecondNode.Name] = newSecondNode
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	oldNode = mockClient.Nodes[oldNode.Name]
 	if _, ok := oldNode.GetAnnotations()[k8s.AnnotationRollingUpdateDrainedTimestamp]; !ok {
 		t.Error("Node should've been drained")
@@ -600,7 +613,7 @@ func TestHandleRollingUpgrade_withEnoughPodsToRequireTwoNewNodes(t *testing.T) {
 // This means that not only must we check the launch template version (it doesn't change in this test), but
 // we must also check if the instance's instance type is part of the MixedInstancesPolicy's instance types.
 // If it isn't, then it means the ASG has been modified, and the instance is old.
-func TestHandleRollingUpgrade_withMixedInstancePolicyWhenOneOfTheInstanceTypesOverrideChanges(t *testing.T) {
+func TestReconcileAutoScalingGroup_withMixedInstancePolicyWhenOneOfTheInstanceTypesOverrideChanges(t *testing.T) {
 	launchTemplateSpecification := &autoscaling.LaunchTemplateSpecification{
 		LaunchTemplateId:   aws.String("lt1"),
 		LaunchTemplateName: aws.String("lt1"),
@@ -628,8 +641,8 @@ func TestHandleRollingUpgrade_withMixedInstancePolicyWhenOneOfTheInstanceTypesOv
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (Nothing changed)
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
-	if mockClient.Counter["UpdateNode"] != 0 {
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if mockClient.Counter["PatchNode"] != 0 {
 		t.Error("Nothing should've changed")
 	}
 
@@ -641,8 +654,8 @@ func TestHandleRollingUpgrade_withMixedInstancePolicyWhenOneOfTheInstanceTypesOv
 	})
 
 	// Second run
-	HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
-	if mockClient.Counter["UpdateNode"] != 1 {
+	reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if mockClient.Counter["PatchNode"] != 1 {
 		t.Error("The old instance's instance type is no longer part of the ASG's MixedInstancePolicy's LaunchTemplate overrides, therefore, it is outdated and should've been annotated")
 	}
 }
@@ -650,39 +663,68 @@ func TestHandleRollingUpgrade_withMixedInstancePolicyWhenOneOfTheInstanceTypesOv
 func TestHasAcceptableNumberOfUpdatedNonReadyNodes(t *testing.T) {
 	// false: there's too many non-ready nodes
 	// true:  there's an acceptable amount of non-ready nodes given how many ready nodes there are
-	if HasAcceptableNumberOfUpdatedNonReadyNodes(100, 0) {
+	asgConfig := config.Get().ForAutoScalingGroup("asg", nil)
+	if HasAcceptableNumberOfUpdatedNonReadyNodes(100, createUpdatedReadyNodes(0, 0), asgConfig) {
 		t.Error("100NR/0R ready should not be acceptable")
 	}
-	if HasAcceptableNumberOfUpdatedNonReadyNodes(50, 50) {
+	if HasAcceptableNumberOfUpdatedNonReadyNodes(50, createUpdatedReadyNodes(50, 0), asgConfig) {
 		t.Error("50NR/50R should not be acceptable")
 	}
-	if HasAcceptableNumberOfUpdatedNonReadyNodes(6, 10000) {
-		t.Error("6NR/10000R should not be acceptable, because MaximumNumberOfUpdatedNonReadyNodes is set to", MaximumNumberOfUpdatedNonReadyNodes)
+	if HasAcceptableNumberOfUpdatedNonReadyNodes(6, createUpdatedReadyNodes(10000, 0), asgConfig) {
+		t.Error("6NR/10000R should not be acceptable, because MaxUpdatedNonReadyNodes is set to", asgConfig.MaxUpdatedNonReadyNodes)
 	}
-	if !HasAcceptableNumberOfUpdatedNonReadyNodes(5, 10000) {
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(5, createUpdatedReadyNodes(10000, 0), asgConfig) {
 		t.Error("5NR/10000R should be acceptable")
 	}
-	if !HasAcceptableNumberOfUpdatedNonReadyNodes(4, 100) {
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(4, createUpdatedReadyNodes(100, 0), asgConfig) {
 		t.Error("4NR/100R should be acceptable")
 	}
-	if !HasAcceptableNumberOfUpdatedNonReadyNodes(1, 99) {
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(1, createUpdatedReadyNodes(99, 0), asgConfig) {
 		t.Error("1NR/99R should be acceptable")
 	}
-	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, 100) {
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, createUpdatedReadyNodes(100, 0), asgConfig) {
 		t.Error("0NR/100R should be acceptable")
 	}
-	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, 1) {
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, createUpdatedReadyNodes(1, 0), asgConfig) {
 		t.Error("0NR/1R should be acceptable")
 	}
-	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, 0) {
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, createUpdatedReadyNodes(0, 0), asgConfig) {
 		t.Error("0NR/0R should be acceptable")
 	}
-	if !HasAcceptableNumberOfUpdatedNonReadyNodes(1, 11) {
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(1, createUpdatedReadyNodes(11, 0), asgConfig) {
 		t.Error("1NR/11R should be acceptable")
 	}
+	asgConfig.MaxUpdatedNonReadyNodes, asgConfig.MaxUpdatedNonReadyNodesRatio = 1, 1
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(1, createUpdatedReadyNodes(1, 0), asgConfig) {
+		t.Error("1NR/1R should be acceptable, because MaxUpdatedNonReadyNodesRatio is set to 1")
+	}
+	if HasAcceptableNumberOfUpdatedNonReadyNodes(2, createUpdatedReadyNodes(100, 0), asgConfig) {
+		t.Error("2NR/100R should not be acceptable, because MaxUpdatedNonReadyNodes is set to 1")
+	}
+	asgConfig.UpdatedNodeMinReadyDuration = 5 * time.Minute
+	if HasAcceptableNumberOfUpdatedNonReadyNodes(0, createUpdatedReadyNodes(1, time.Minute), asgConfig) {
+		t.Error("0NR/1R should not be acceptable, because the ready node hasn't been ready for UpdatedNodeMinReadyDuration")
+	}
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, createUpdatedReadyNodes(1, 10*time.Minute), asgConfig) {
+		t.Error("0NR/1R should be acceptable, because the ready node has been ready for longer than UpdatedNodeMinReadyDuration")
+	}
+	if !HasAcceptableNumberOfUpdatedNonReadyNodes(0, append(createUpdatedReadyNodes(1, time.Minute), createUpdatedReadyNodes(1, 10*time.Minute)...), asgConfig) {
+		t.Error("1 node ready for less than UpdatedNodeMinReadyDuration and 1 node ready for longer should be acceptable, because MaxUpdatedNonReadyNodesRatio is set to 1")
+	}
+}
+
+// createUpdatedReadyNodes creates the given number of nodes that have been ready for the given duration
+func createUpdatedReadyNodes(numberOfNodes int, readyFor time.Duration) []*v1.Node {
+	var nodes []*v1.Node
+	for i := 0; i < numberOfNodes; i++ {
+		node := k8stest.CreateTestNode(fmt.Sprintf("new-node-%d", i), "us-west-2a", fmt.Sprintf("new-%d", i), "1000m", "1000Mi")
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(time.Now().Add(-readyFor))}}
+		nodes = append(nodes, &node)
+	}
+	return nodes
 }
 
-func TestHandleRollingUpgrade_withEagerCordoning(t *testing.T) {
+func TestReconcileAutoScalingGroup_withEagerCordoning(t *testing.T) {
 	config.Set(nil, true, true, true, false)
 	defer config.Set(nil, true, true, false, false)
 
@@ -703,12 +745,12 @@ func TestHandleRollingUpgrade_withEagerCordoning(t *testing.T) {
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (Node rollout process gets marked as started)
-	err := HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
-	if mockClient.Counter["UpdateNode"] != 3 {
-		t.Error("Node should've been annotated as started, meaning that UpdateNode should've been called once")
+	if mockClient.Counter["PatchNode"] != 3 {
+		t.Error("Node should've been annotated as started, meaning that PatchNode should've been called once")
 	}
 	// Make sure that all nodes were "eagerly cordoned"
 	if mockClient.Counter["Cordon"] != 3 {
@@ -716,7 +758,7 @@ func TestHandleRollingUpgrade_withEagerCordoning(t *testing.T) {
 	}
 }
 
-func TestHandleRollingUpgrade_withEagerCordoningDisabled(t *testing.T) {
+func TestReconcileAutoScalingGroup_withEagerCordoningDisabled(t *testing.T) {
 	// explicitly setting this, but eager cordoning is disabled by default anyways
 	config.Set(nil, true, true, false, true)
 	defer config.Set(nil, true, true, true, false)
@@ -735,12 +777,12 @@ func TestHandleRollingUpgrade_withEagerCordoningDisabled(t *testing.T) {
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (Node rollout process gets marked as started)
-	err := HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
-	if mockClient.Counter["UpdateNode"] != 2 {
-		t.Error("Nodes should've been annotated as started, meaning that UpdateNode should've been called twice")
+	if mockClient.Counter["PatchNode"] != 2 {
+		t.Error("Nodes should've been annotated as started, meaning that PatchNode should've been called twice")
 	}
 	// Make sure that all nodes were NOT "eagerly cordoned"
 	if mockClient.Counter["Cordon"] != 0 {
@@ -748,7 +790,129 @@ func TestHandleRollingUpgrade_withEagerCordoningDisabled(t *testing.T) {
 	}
 }
 
-func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T) {
+func TestReconcileAutoScalingGroup_withEagerCordoningEnabledThroughAutoScalingGroupTag(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+
+	oldInstance1 := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	oldInstance2 := cloudtest.CreateTestAutoScalingInstance("old-2", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance1, oldInstance2}, false)
+	asg.Tags = []*autoscaling.TagDescription{{Key: aws.String(config.TagEagerCordoning), Value: aws.String("true")}}
+
+	oldNode1 := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance1.AvailabilityZone), aws.StringValue(oldInstance1.InstanceId), "1000m", "1000Mi")
+	oldNode2 := k8stest.CreateTestNode("old-node-2", aws.StringValue(oldInstance2.AvailabilityZone), aws.StringValue(oldInstance2.InstanceId), "1000m", "1000Mi")
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode1, oldNode2}, []v1.Pod{})
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockClient.Counter["Cordon"] != 2 {
+		t.Error("Eager cordoning is enabled through the ASG's tags, so both nodes should've been cordoned, but Cordon was called", mockClient.Counter["Cordon"], "times")
+	}
+}
+
+func TestReconcileAutoScalingGroup_whenAutoScalingGroupIsPausedThroughTag(t *testing.T) {
+	config.Set(nil, true, true, true, false)
+	defer config.Set(nil, true, true, false, false)
+
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance}, false)
+	asg.Tags = []*autoscaling.TagDescription{{Key: aws.String(config.TagPaused), Value: aws.String("true")}}
+
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode}, []v1.Pod{})
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	result, err := ReconcileAutoScalingGroup(context.Background(), mockClient, mockEc2Service, mockAutoScalingService, asg, NewPlan(false))
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !result.Requeue {
+		t.Error("a paused ASG with outdated instances should've been requeued")
+	}
+	if mockClient.Counter["Cordon"] != 0 || mockClient.Counter["PatchNode"] != 0 {
+		t.Error("the rolling update of the ASG is paused, so no node should've been cordoned or annotated")
+	}
+
+	asg.Tags = nil
+	if _, err := ReconcileAutoScalingGroup(context.Background(), mockClient, mockEc2Service, mockAutoScalingService, asg, NewPlan(false)); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockClient.Counter["Cordon"] != 1 {
+		t.Error("the rolling update of the ASG is no longer paused, so the node should've been cordoned")
+	}
+}
+
+func TestReconcileAutoScalingGroup_whenNodeIsPausedThroughAnnotation(t *testing.T) {
+	config.Set(nil, true, true, true, false)
+	defer config.Set(nil, true, true, false, false)
+
+	oldInstance1 := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	oldInstance2 := cloudtest.CreateTestAutoScalingInstance("old-2", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance1, oldInstance2}, false)
+
+	pausedNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance1.AvailabilityZone), aws.StringValue(oldInstance1.InstanceId), "1000m", "1000Mi")
+	pausedNode.Annotations[k8s.AnnotationPaused] = "true"
+	oldNode := k8stest.CreateTestNode("old-node-2", aws.StringValue(oldInstance2.AvailabilityZone), aws.StringValue(oldInstance2.InstanceId), "1000m", "1000Mi")
+
+	mockClient := k8stest.NewMockClient([]v1.Node{pausedNode, oldNode}, []v1.Pod{})
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockClient.Counter["Cordon"] != 1 {
+		t.Error("only the node without the paused annotation should've been cordoned, but Cordon was called", mockClient.Counter["Cordon"], "times")
+	}
+	if _, ok := mockClient.Nodes[pausedNode.Name].Annotations[k8s.AnnotationRollingUpdateStartedTimestamp]; ok {
+		t.Error("the rollout of the paused node shouldn't have started")
+	}
+	if _, ok := mockClient.Nodes[oldNode.Name].Annotations[k8s.AnnotationRollingUpdateStartedTimestamp]; !ok {
+		t.Error("the rollout of the node without the paused annotation should've started")
+	}
+}
+
+func TestReconcileAutoScalingGroup_duringBlackoutPeriod(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+
+	startedInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-2", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{startedInstance, oldInstance}, false)
+	asg.Tags = []*autoscaling.TagDescription{{
+		Key:   aws.String(config.TagBlackoutPeriod),
+		Value: aws.String(time.Now().Add(-time.Hour).UTC().Format(time.RFC3339) + "/" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339)),
+	}}
+
+	startedNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(startedInstance.AvailabilityZone), aws.StringValue(startedInstance.InstanceId), "1000m", "1000Mi")
+	startedNode.Annotations[k8s.AnnotationRollingUpdateStartedTimestamp] = time.Now().Add(-time.Minute).Format(time.RFC3339)
+	oldNode := k8stest.CreateTestNode("old-node-2", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+
+	mockClient := k8stest.NewMockClient([]v1.Node{startedNode, oldNode}, []v1.Pod{})
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if _, ok := mockClient.Nodes[oldNode.Name].Annotations[k8s.AnnotationRollingUpdateStartedTimestamp]; ok {
+		t.Error("the rollout of a new node shouldn't have started during a blackout period")
+	}
+	if _, ok := mockClient.Nodes[startedNode.Name].Annotations[k8s.AnnotationRollingUpdateTerminatedTimestamp]; !ok {
+		t.Error("the rollout of the node that had already started should've continued until its termination")
+	}
+}
+
+func TestReconcileAutoScalingGroup_withExcludeFromExternalLoadBalancers(t *testing.T) {
 	config.Set(nil, true, true, false, true)
 	defer config.Set(nil, true, true, false, false)
 
@@ -763,16 +927,16 @@ func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T)
 	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
 
 	// First run (Node rollout process gets marked as started)
-	err := HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
-	if mockClient.Counter["UpdateNode"] != 1 {
-		t.Error("Node should've been annotated, meaning that UpdateNode should've been called once")
+	if mockClient.Counter["PatchNode"] != 1 {
+		t.Error("Node should've been annotated, meaning that PatchNode should've been called once")
 	}
 
 	// Second run (ASG's desired capacity gets increased)
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -785,7 +949,7 @@ func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T)
 	}
 
 	// Third run (Nothing changed)
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -800,7 +964,7 @@ func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T)
 	// Fourth run (new instance has been registered to ASG, but is pending)
 	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "Pending")
 	asg.Instances = append(asg.Instances, newInstance)
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -810,7 +974,7 @@ func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T)
 
 	// Fifth run (new instance is now InService, but node has still not joined cluster (GetNodeByAutoScalingInstance should return not found))
 	newInstance.SetLifecycleState("InService")
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -819,7 +983,7 @@ func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T)
 	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
 	mockClient.Nodes[newNode.Name] = newNode
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -828,7 +992,7 @@ func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T)
 	newNode = mockClient.Nodes[newNode.Name]
 	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
 	mockClient.Nodes[newNode.Name] = newNode
-	err = HandleRollingUpgrade(mockClient, mockEc2Service, mockAutoScalingService, []*autoscaling.Group{asg})
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
 	if err != nil {
 		t.Error("unexpected error:", err)
 	}
@@ -837,3 +1001,560 @@ func TestHandleRollingUpgrade_withExcludeFromExternalLoadBalancers(t *testing.T)
 		t.Error("Node should've been labeled")
 	}
 }
+
+func TestReconcileAutoScalingGroup_withDryRun(t *testing.T) {
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+	oldNodePod := k8stest.CreateTestPod("old-pod-1", oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{oldNodePod})
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	plan := NewPlan(true)
+	if _, err := ReconcileAutoScalingGroup(context.Background(), mockClient, mockEc2Service, mockAutoScalingService, asg, plan); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockClient.Counter["PatchNode"] != 0 || mockClient.Counter["Drain"] != 0 || mockClient.Counter["Cordon"] != 0 {
+		t.Error("No node should've been modified in dry run mode")
+	}
+	if mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"] != 0 || mockAutoScalingService.Counter["SetDesiredCapacity"] != 0 {
+		t.Error("No ASG should've been modified in dry run mode")
+	}
+	if len(plan.Actions) != 2 {
+		t.Fatalf("expected 2 actions to have been planned, got %d", len(plan.Actions))
+	}
+	if plan.Actions[0].Step != StepDrain || plan.Actions[1].Step != StepTerminate {
+		t.Errorf("expected plan to be [%s %s], got [%s %s]", StepDrain, StepTerminate, plan.Actions[0].Step, plan.Actions[1].Step)
+	}
+	if plan.Actions[0].NodeName != oldNode.Name || plan.Actions[0].InstanceID != aws.StringValue(oldInstance.InstanceId) || plan.Actions[0].AutoScalingGroupName != "asg" {
+		t.Error("planned action should've targeted the old node")
+	}
+}
+
+func TestReconcileAutoScalingGroup_withDryRunWhenUpdatedNodesDoNotHaveEnoughResources(t *testing.T) {
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance}, false)
+
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	oldNodePod := k8stest.CreateTestPod("old-pod-1", oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode}, []v1.Pod{oldNodePod})
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	// First run (Node rollout process would get marked as started)
+	plan := NewPlan(true)
+	if _, err := ReconcileAutoScalingGroup(context.Background(), mockClient, mockEc2Service, mockAutoScalingService, asg, plan); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Step != StepStartRollout {
+		t.Fatal("expected the rollout to have been planned")
+	}
+	if mockClient.Counter["PatchNode"] != 0 {
+		t.Error("Node shouldn't have been annotated in dry run mode")
+	}
+
+	// Second run (ASG's desired capacity would get increased)
+	oldNode = mockClient.Nodes[oldNode.Name]
+	oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+	mockClient.Nodes[oldNode.Name] = oldNode
+	plan = NewPlan(true)
+	if _, err := ReconcileAutoScalingGroup(context.Background(), mockClient, mockEc2Service, mockAutoScalingService, asg, plan); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Step != StepScaleUp {
+		t.Fatal("expected a scale up to have been planned")
+	}
+	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 0 {
+		t.Error("ASG shouldn't have been scaled up in dry run mode")
+	}
+}
+
+func TestReconcileAutoScalingGroup_withMaxUnavailable(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	config.Get().MaxUnavailable = intstr.FromInt32(2)
+	defer config.Set(nil, true, true, false, false)
+
+	oldInstance1 := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	oldInstance2 := cloudtest.CreateTestAutoScalingInstance("old-2", "v1", nil, "InService")
+	oldInstance3 := cloudtest.CreateTestAutoScalingInstance("old-3", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance1, oldInstance2, oldInstance3, newInstance}, false)
+
+	var nodes []v1.Node
+	var pods []v1.Pod
+	for _, oldInstance := range []*autoscaling.Instance{oldInstance1, oldInstance2, oldInstance3} {
+		oldNode := k8stest.CreateTestNode("node-"+aws.StringValue(oldInstance.InstanceId), aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+		oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+		nodes = append(nodes, oldNode)
+		pods = append(pods, k8stest.CreateTestPod("pod-"+aws.StringValue(oldInstance.InstanceId), oldNode.Name, "100m", "100Mi", false, v1.PodRunning))
+	}
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+	nodes = append(nodes, newNode)
+
+	mockClient := k8stest.NewMockClient(nodes, pods)
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockClient.Counter["Drain"] != 2 {
+		t.Error("Two nodes should've been drained, but", mockClient.Counter["Drain"], "were drained instead")
+	}
+	if mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"] != 2 {
+		t.Error("Two nodes should've been terminated, but", mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"], "were terminated instead")
+	}
+}
+
+func TestReconcileAutoScalingGroup_whenPodDisruptionBudgetDoesNotAllowDisruptions(t *testing.T) {
+	oldInstance1 := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	oldInstance2 := cloudtest.CreateTestAutoScalingInstance("old-2", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance1, oldInstance2, newInstance}, false)
+
+	oldNode1 := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance1.AvailabilityZone), aws.StringValue(oldInstance1.InstanceId), "1000m", "1000Mi")
+	oldNode1.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+	oldNode2 := k8stest.CreateTestNode("old-node-2", aws.StringValue(oldInstance2.AvailabilityZone), aws.StringValue(oldInstance2.InstanceId), "1000m", "1000Mi")
+	oldNode2.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+	blockedPod := k8stest.CreateTestPod("blocked-pod", oldNode1.Name, "100m", "100Mi", false, v1.PodRunning)
+	blockedPod.SetLabels(map[string]string{"app": "blocked"})
+	otherPod := k8stest.CreateTestPod("other-pod", oldNode2.Name, "100m", "100Mi", false, v1.PodRunning)
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode1, oldNode2, newNode}, []v1.Pod{blockedPod, otherPod})
+	mockClient.PodDisruptionBudgets = append(mockClient.PodDisruptionBudgets, k8stest.CreateTestPodDisruptionBudget("blocked-pdb", map[string]string{"app": "blocked"}, 0))
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	// The outdated instances are shuffled, so we run it several times to make sure the blocked node is never picked
+	for i := 0; i < 5; i++ {
+		err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+	}
+	if _, ok := mockClient.Nodes[oldNode1.Name].Annotations[k8s.AnnotationRollingUpdateDrainedTimestamp]; ok {
+		t.Error("old-node-1 shouldn't have been drained, because its pod is protected by a PodDisruptionBudget that doesn't allow any disruption")
+	}
+	if _, ok := mockClient.Nodes[oldNode2.Name].Annotations[k8s.AnnotationRollingUpdateDrainedTimestamp]; !ok {
+		t.Error("old-node-2 should've been drained instead of old-node-1")
+	}
+	if mockClient.Counter["Drain"] != 1 {
+		t.Error("Only one node should've been drained, but Drain was called", mockClient.Counter["Drain"], "times")
+	}
+	if len(mockClient.Events[oldNode1.Name]) == 0 || mockClient.Events[oldNode1.Name][0] != k8s.EventReasonBlockedByPodDisruptionBudget {
+		t.Error("an event should've been recorded for old-node-1 to report that it is blocked by a PodDisruptionBudget")
+	}
+}
+
+func TestReconcileAutoScalingGroup_withMaxSurge(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	config.Get().MaxSurge = intstr.FromString("100%")
+	defer config.Set(nil, true, true, false, false)
+
+	oldInstance1 := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	oldInstance2 := cloudtest.CreateTestAutoScalingInstance("old-2", "v1", nil, "InService")
+	oldInstance3 := cloudtest.CreateTestAutoScalingInstance("old-3", "v1", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance1, oldInstance2, oldInstance3}, false)
+
+	var nodes []v1.Node
+	var pods []v1.Pod
+	for _, oldInstance := range []*autoscaling.Instance{oldInstance1, oldInstance2, oldInstance3} {
+		oldNode := k8stest.CreateTestNode("node-"+aws.StringValue(oldInstance.InstanceId), aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+		oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+		nodes = append(nodes, oldNode)
+		pods = append(pods, k8stest.CreateTestPod("pod-"+aws.StringValue(oldInstance.InstanceId), oldNode.Name, "100m", "100Mi", false, v1.PodRunning))
+	}
+
+	mockClient := k8stest.NewMockClient(nodes, pods)
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockAutoScalingService.Counter["SetDesiredCapacity"] != 1 {
+		t.Error("ASG should've been scaled up once")
+	}
+	asg = mockAutoScalingService.AutoScalingGroups[aws.StringValue(asg.AutoScalingGroupName)]
+	if aws.Int64Value(asg.DesiredCapacity) != 6 {
+		t.Error("The desired capacity of the ASG should've been increased from 3 to 6, but was", aws.Int64Value(asg.DesiredCapacity))
+	}
+}
+
+func TestGetRolloutBudget(t *testing.T) {
+	defer config.Set(nil, true, true, false, false)
+	scenarios := []struct {
+		maxUnavailable         intstr.IntOrString
+		maxSurge               intstr.IntOrString
+		numberOfInstances      int
+		expectedMaxUnavailable int
+		expectedMaxSurge       int
+	}{
+		{maxUnavailable: intstr.FromInt32(1), maxSurge: intstr.FromInt32(1), numberOfInstances: 10, expectedMaxUnavailable: 1, expectedMaxSurge: 1},
+		{maxUnavailable: intstr.FromInt32(3), maxSurge: intstr.FromInt32(5), numberOfInstances: 10, expectedMaxUnavailable: 3, expectedMaxSurge: 5},
+		{maxUnavailable: intstr.FromString("25%"), maxSurge: intstr.FromString("25%"), numberOfInstances: 10, expectedMaxUnavailable: 2, expectedMaxSurge: 3},
+		{maxUnavailable: intstr.FromString("10%"), maxSurge: intstr.FromString("10%"), numberOfInstances: 1, expectedMaxUnavailable: 1, expectedMaxSurge: 1},
+	}
+	for _, scenario := range scenarios {
+		config.Set(nil, true, true, false, false)
+		config.Get().MaxUnavailable = scenario.maxUnavailable
+		config.Get().MaxSurge = scenario.maxSurge
+		asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, make([]*autoscaling.Instance, scenario.numberOfInstances), false)
+		maxUnavailable, maxSurge := getRolloutBudget(asg, config.Get().ForAutoScalingGroup("asg", nil))
+		if maxUnavailable != scenario.expectedMaxUnavailable {
+			t.Errorf("maxUnavailable=%s with %d instances should've resolved to %d, got %d", scenario.maxUnavailable.String(), scenario.numberOfInstances, scenario.expectedMaxUnavailable, maxUnavailable)
+		}
+		if maxSurge != scenario.expectedMaxSurge {
+			t.Errorf("maxSurge=%s with %d instances should've resolved to %d, got %d", scenario.maxSurge.String(), scenario.numberOfInstances, scenario.expectedMaxSurge, maxSurge)
+		}
+	}
+}
+
+func TestReconcileAutoScalingGroup_whenExecutionTimesOutWhileDrainingRollsBackNode(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	config.Get().ExecutionTimeout = 50 * time.Millisecond
+	defer config.Set(nil, true, true, false, false)
+
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+	oldNodePod := k8stest.CreateTestPod("old-pod-1", oldNode.Name, "100m", "100Mi", false, v1.PodRunning)
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{oldNodePod})
+	mockClient.DrainDuration = time.Minute
+	// The node is rolled back based on the node from before it was cordoned by the drain
+	mockClient.StaleReads = true
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	start := time.Now()
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != ErrTimedOut {
+		t.Error("expected ErrTimedOut, got", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("The reconciliation should've returned as soon as the drain was cancelled")
+	}
+	if mockClient.Counter["Drain"] != 1 {
+		t.Error("The old node should've started draining, but", mockClient.Counter["Drain"], "nodes were drained instead")
+	}
+	if mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"] != 0 {
+		t.Error("The old node shouldn't have been terminated, because its drain was cancelled")
+	}
+	if mockClient.Nodes[oldNode.Name].Spec.Unschedulable {
+		t.Error("The old node should've been uncordoned after its drain was interrupted")
+	}
+	if _, ok := mockClient.Nodes[oldNode.Name].Annotations[k8s.AnnotationRollingUpdateStartedTimestamp]; ok {
+		t.Errorf("The old node should no longer have the annotation %s after its drain was interrupted", k8s.AnnotationRollingUpdateStartedTimestamp)
+	}
+	if events := mockClient.Events[oldNode.Name]; len(events) == 0 || events[len(events)-1] != k8s.EventReasonDrainInterrupted {
+		t.Errorf("expected last event of old node to be %s, got %v", k8s.EventReasonDrainInterrupted, events)
+	}
+	if drainingNodes.draining != 0 {
+		t.Error("Every drain slot should've been released, but", drainingNodes.draining, "are still in use")
+	}
+}
+
+func TestReconcileAutoScalingGroup_whenTerminationIsStuck(t *testing.T) {
+	defer config.Set(nil, true, true, false, false)
+	scenarios := []struct {
+		name                                 string
+		lifecycleState                       string
+		minutesSinceTerminated               int
+		completeStuckLifecycleActions        bool
+		expectedTerminateInstanceCalls       int64
+		expectedCompleteLifecycleActionCalls int64
+		expectedTerminationStuckEvent        bool
+	}{
+		{name: "in-service-below-threshold", lifecycleState: "InService", minutesSinceTerminated: 5},
+		{name: "in-service", lifecycleState: "InService", minutesSinceTerminated: 15, expectedTerminateInstanceCalls: 1, expectedTerminationStuckEvent: true},
+		{name: "terminating-wait", lifecycleState: "Terminating:Wait", minutesSinceTerminated: 15, expectedTerminationStuckEvent: true},
+		{name: "terminating-wait-with-complete-stuck-lifecycle-actions", lifecycleState: "Terminating:Wait", minutesSinceTerminated: 15, completeStuckLifecycleActions: true, expectedCompleteLifecycleActionCalls: 1, expectedTerminationStuckEvent: true},
+		{name: "terminating-proceed", lifecycleState: "Terminating:Proceed", minutesSinceTerminated: 15, completeStuckLifecycleActions: true, expectedTerminationStuckEvent: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			config.Set(nil, true, true, false, false)
+			config.Get().CompleteStuckLifecycleActions = scenario.completeStuckLifecycleActions
+
+			oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, scenario.lifecycleState)
+			newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+			asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+
+			terminatedAt := time.Now().Add(-time.Duration(scenario.minutesSinceTerminated) * time.Minute).Format(time.RFC3339)
+			oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+			oldNode.SetAnnotations(map[string]string{
+				k8s.AnnotationRollingUpdateStartedTimestamp:    terminatedAt,
+				k8s.AnnotationRollingUpdateDrainedTimestamp:    terminatedAt,
+				k8s.AnnotationRollingUpdateTerminatedTimestamp: terminatedAt,
+			})
+			newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+			newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+
+			mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{})
+			mockEc2Service := cloudtest.NewMockEC2Service(nil)
+			mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+			mockAutoScalingService.LifecycleHooks["asg"] = []*autoscaling.LifecycleHook{
+				{LifecycleHookName: aws.String("launching"), LifecycleTransition: aws.String("autoscaling:EC2_INSTANCE_LAUNCHING")},
+				{LifecycleHookName: aws.String("terminating"), LifecycleTransition: aws.String("autoscaling:EC2_INSTANCE_TERMINATING")},
+			}
+
+			err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"] != scenario.expectedTerminateInstanceCalls {
+				t.Errorf("expected the instance to have been terminated %d times, got %d", scenario.expectedTerminateInstanceCalls, mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"])
+			}
+			if mockAutoScalingService.Counter["CompleteLifecycleAction"] != scenario.expectedCompleteLifecycleActionCalls {
+				t.Errorf("expected %d lifecycle actions to have been completed, got %d", scenario.expectedCompleteLifecycleActionCalls, mockAutoScalingService.Counter["CompleteLifecycleAction"])
+			}
+			events := mockClient.Events[oldNode.Name]
+			if recorded := len(events) > 0 && events[len(events)-1] == k8s.EventReasonTerminationStuck; recorded != scenario.expectedTerminationStuckEvent {
+				t.Errorf("expected %s event to have been recorded to be %v, got events %v", k8s.EventReasonTerminationStuck, scenario.expectedTerminationStuckEvent, events)
+			}
+			terminatedAtAfterExecution := mockClient.Nodes[oldNode.Name].Annotations[k8s.AnnotationRollingUpdateTerminatedTimestamp]
+			if scenario.expectedTerminationStuckEvent == (terminatedAtAfterExecution == terminatedAt) {
+				t.Error("The termination timestamp should've only been refreshed if the termination was stuck")
+			}
+		})
+	}
+}
+
+func TestReconcileAutoScalingGroup_whenDrainKeepsFailing(t *testing.T) {
+	defer config.Set(nil, true, true, false, false)
+	scenarios := []struct {
+		name                              string
+		drainEscalationPolicy             string
+		drainEscalationTimeout            time.Duration
+		failedDrainAttempts               string
+		firstFailedDrainAt                time.Time
+		expectedDrainCalls                int64
+		expectedDrainWithoutEvictionCalls int64
+		expectedUnschedulable             bool
+		expectedTerminateInstanceCalls    int64
+	}{
+		{name: "none", drainEscalationPolicy: config.DrainEscalationPolicyNone, failedDrainAttempts: "4", expectedDrainCalls: 2, expectedUnschedulable: true},
+		{name: "skip", drainEscalationPolicy: config.DrainEscalationPolicySkip, failedDrainAttempts: "4", expectedDrainCalls: 1, expectedUnschedulable: false},
+		{name: "force", drainEscalationPolicy: config.DrainEscalationPolicyForce, failedDrainAttempts: "4", expectedDrainCalls: 2, expectedDrainWithoutEvictionCalls: 1, expectedUnschedulable: true, expectedTerminateInstanceCalls: 1},
+		{name: "block", drainEscalationPolicy: config.DrainEscalationPolicyBlock, failedDrainAttempts: "4", expectedDrainCalls: 1, expectedUnschedulable: true},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			config.Set(nil, true, true, false, false)
+			config.Get().DrainEscalationPolicy = scenario.drainEscalationPolicy
+
+			oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+			newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+			asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+
+			oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+			oldNode.SetAnnotations(map[string]string{
+				k8s.AnnotationRollingUpdateStartedTimestamp:          time.Now().Format(time.RFC3339),
+				k8s.AnnotationRollingUpdateFailedDrainAttempts:       scenario.failedDrainAttempts,
+				k8s.AnnotationRollingUpdateFirstFailedDrainTimestamp: time.Now().Format(time.RFC3339),
+			})
+			newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+			newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+
+			mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{})
+			mockClient.DrainError = errors.New("cannot evict pod")
+			mockEc2Service := cloudtest.NewMockEC2Service(nil)
+			mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+			// First run (drain fails once more, which escalates the drain)
+			err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			oldNode = mockClient.Nodes[oldNode.Name]
+			if events := mockClient.Events[oldNode.Name]; len(events) == 0 || events[len(events)-1] != k8s.EventReasonDrainEscalated {
+				t.Errorf("expected last event of old node to be %s, got %v", k8s.EventReasonDrainEscalated, events)
+			}
+			if !isDrainEscalated(&oldNode) {
+				t.Error("the drain of the old node should've been escalated, got annotations", oldNode.Annotations)
+			}
+
+			// Second run (escalation policy is applied)
+			err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if mockClient.Counter["Drain"] != scenario.expectedDrainCalls {
+				t.Errorf("expected Drain to have been called %d times, got %d", scenario.expectedDrainCalls, mockClient.Counter["Drain"])
+			}
+			if mockClient.Counter["DrainWithoutEviction"] != scenario.expectedDrainWithoutEvictionCalls {
+				t.Errorf("expected the node to have been drained without eviction %d times, got %d", scenario.expectedDrainWithoutEvictionCalls, mockClient.Counter["DrainWithoutEviction"])
+			}
+			if unschedulable := mockClient.Nodes[oldNode.Name].Spec.Unschedulable; unschedulable != scenario.expectedUnschedulable {
+				t.Errorf("expected the old node to be unschedulable to be %v, got %v", scenario.expectedUnschedulable, unschedulable)
+			}
+			if mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"] != scenario.expectedTerminateInstanceCalls {
+				t.Errorf("expected the instance to have been terminated %d times, got %d", scenario.expectedTerminateInstanceCalls, mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"])
+			}
+			if events := mockClient.Events[oldNode.Name]; countOccurrences(events, k8s.EventReasonDrainEscalated) != 1 {
+				t.Errorf("expected exactly one %s event to have been recorded, got %v", k8s.EventReasonDrainEscalated, events)
+			}
+		})
+	}
+}
+
+func countOccurrences(values []string, value string) (count int) {
+	for _, v := range values {
+		if v == value {
+			count++
+		}
+	}
+	return
+}
+
+func TestReconcileAutoScalingGroup_whenUpdatedNodesNeverBecomeReady(t *testing.T) {
+	defer config.Set(nil, true, true, false, false)
+	scenarios := []struct {
+		name                     string
+		rolloutAbortTimeout      time.Duration
+		minutesSinceLaunched     int
+		expectedRolloutAborted   bool
+		expectedUnschedulable    bool
+		expectedAnnotationsAfter int
+	}{
+		{name: "disabled", rolloutAbortTimeout: 0, minutesSinceLaunched: 120, expectedUnschedulable: true, expectedAnnotationsAfter: 2},
+		{name: "below-timeout", rolloutAbortTimeout: time.Hour, minutesSinceLaunched: 30, expectedUnschedulable: true, expectedAnnotationsAfter: 2},
+		{name: "past-timeout", rolloutAbortTimeout: time.Hour, minutesSinceLaunched: 120, expectedRolloutAborted: true, expectedUnschedulable: false},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			config.Set(nil, true, true, false, false)
+			config.Get().RolloutAbortTimeout = scenario.rolloutAbortTimeout
+
+			oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+			newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+			asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+
+			startedAt := time.Now().Add(-3 * time.Hour).Format(time.RFC3339)
+			oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+			oldNode.Spec.Unschedulable = true
+			oldNode.SetAnnotations(map[string]string{
+				k8s.AnnotationRollingUpdateStartedTimestamp: startedAt,
+				k8s.AnnotationRollingUpdateDrainedTimestamp: startedAt,
+			})
+			newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+			newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
+
+			mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{})
+			mockEc2Service := cloudtest.NewMockEC2Service(nil)
+			newEc2Instance := cloudtest.CreateTestEc2Instance(aws.StringValue(newInstance.InstanceId))
+			newEc2Instance.LaunchTime = aws.Time(time.Now().Add(-time.Duration(scenario.minutesSinceLaunched) * time.Minute))
+			mockEc2Service.Instances = []*ec2.Instance{newEc2Instance}
+			mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+			err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if unschedulable := mockClient.Nodes[oldNode.Name].Spec.Unschedulable; unschedulable != scenario.expectedUnschedulable {
+				t.Errorf("expected the outdated node to be unschedulable to be %v, got %v", scenario.expectedUnschedulable, unschedulable)
+			}
+			if annotations := mockClient.Nodes[oldNode.Name].Annotations; len(annotations) != scenario.expectedAnnotationsAfter {
+				t.Errorf("expected the outdated node to have %d annotations, got %v", scenario.expectedAnnotationsAfter, annotations)
+			}
+			if aborted := countOccurrences(mockClient.Events[oldNode.Name], k8s.EventReasonRolloutAborted) == 1; aborted != scenario.expectedRolloutAborted {
+				t.Errorf("expected %s event to have been recorded to be %v, got events %v", k8s.EventReasonRolloutAborted, scenario.expectedRolloutAborted, mockClient.Events[oldNode.Name])
+			}
+			if mockAutoScalingService.Counter["SetDesiredCapacity"] != 0 || mockAutoScalingService.Counter["TerminateInstanceInAutoScalingGroup"] != 0 {
+				t.Error("ASG shouldn't have been scaled up nor had any of its instances terminated")
+			}
+
+			// Reconciling again shouldn't revert the outdated node twice
+			patches := mockClient.Counter["PatchNode"]
+			_ = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+			if mockClient.Counter["PatchNode"] != patches {
+				t.Errorf("expected the outdated node not to have been patched after reconciling again, got %d more patches", mockClient.Counter["PatchNode"]-patches)
+			}
+		})
+	}
+}
+
+func TestReconcileAutoScalingGroup_withHealthGates(t *testing.T) {
+	config.Set(nil, true, true, false, false)
+	defer config.Set(nil, true, true, false, false)
+	config.Get().HealthGates = config.HealthGates{
+		NodeConditions: map[string]string{"NetworkingReady": "True"},
+		StartupTaints:  []string{"node.example.com/csi-not-ready"},
+	}
+
+	oldInstance := cloudtest.CreateTestAutoScalingInstance("old-1", "v1", nil, "InService")
+	newInstance := cloudtest.CreateTestAutoScalingInstance("new-1", "v2", nil, "InService")
+	asg := cloudtest.CreateTestAutoScalingGroup("asg", "v2", nil, []*autoscaling.Instance{oldInstance, newInstance}, false)
+
+	oldNode := k8stest.CreateTestNode("old-node-1", aws.StringValue(oldInstance.AvailabilityZone), aws.StringValue(oldInstance.InstanceId), "1000m", "1000Mi")
+	oldNode.SetAnnotations(map[string]string{k8s.AnnotationRollingUpdateStartedTimestamp: time.Now().Format(time.RFC3339)})
+	// The NodeReady condition isn't the last condition, which shouldn't matter
+	newNode := k8stest.CreateTestNode("new-node-1", aws.StringValue(newInstance.AvailabilityZone), aws.StringValue(newInstance.InstanceId), "1000m", "1000Mi")
+	newNode.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}, {Type: "NetworkingReady", Status: v1.ConditionFalse}}
+	newNode.Spec.Taints = []v1.Taint{{Key: "node.example.com/csi-not-ready", Effect: v1.TaintEffectNoSchedule}}
+
+	mockClient := k8stest.NewMockClient([]v1.Node{oldNode, newNode}, []v1.Pod{})
+	mockEc2Service := cloudtest.NewMockEC2Service(nil)
+	mockAutoScalingService := cloudtest.NewMockAutoScalingService([]*autoscaling.Group{asg})
+
+	// First run (new node is ready, but hasn't passed its health gates yet)
+	err := reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockClient.Counter["Drain"] != 0 {
+		t.Error("Node shouldn't have been drained, because the updated node hasn't passed its health gates yet")
+	}
+
+	// Second run (new node's networking is ready, but its startup taint is still present)
+	newNode = mockClient.Nodes[newNode.Name]
+	newNode.Status.Conditions[1].Status = v1.ConditionTrue
+	mockClient.Nodes[newNode.Name] = newNode
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if mockClient.Counter["Drain"] != 0 {
+		t.Error("Node shouldn't have been drained, because the updated node still has its startup taint")
+	}
+
+	// Third run (new node has passed all of its health gates)
+	newNode = mockClient.Nodes[newNode.Name]
+	newNode.Spec.Taints = nil
+	mockClient.Nodes[newNode.Name] = newNode
+	err = reconcileThroughController(mockClient, mockEc2Service, mockAutoScalingService, asg)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if _, ok := mockClient.Nodes[oldNode.Name].Annotations[k8s.AnnotationRollingUpdateDrainedTimestamp]; !ok {
+		t.Error("Node should've been drained, because the updated node has passed all of its health gates")
+	}
+}
+
+// reconcileThroughController reconciles the given ASG once through Controller.reconcile, like the Controller's workers
+// do for every ASG they take from the work queue
+func reconcileThroughController(client k8s.ClientAPI, ec2Service ec2iface.EC2API, autoScalingService autoscalingiface.AutoScalingAPI, autoScalingGroup *autoscaling.Group) error {
+	controller := NewController(client, ec2Service, autoScalingService)
+	controller.autoScalingGroupNames[aws.StringValue(autoScalingGroup.AutoScalingGroupName)] = true
+	_, err := controller.reconcile(context.Background(), aws.StringValue(autoScalingGroup.AutoScalingGroupName))
+	return err
+}
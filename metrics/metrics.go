@@ -1,9 +1,11 @@
 package metrics
 
 import (
+	"context"
 	"net/http"
 	"reflect"
 	"strconv"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -16,14 +18,25 @@ var (
 
 type metricServer struct {
 	registry *prometheus.Registry
+	mux      *http.ServeMux
 
-	NodeGroups      *prometheus.GaugeVec
-	OutdatedNodes   *prometheus.GaugeVec
-	UpdatedNodes    *prometheus.GaugeVec
-	ScaledUpNodes   *prometheus.CounterVec
-	ScaledDownNodes *prometheus.CounterVec
-	DrainedNodes    *prometheus.CounterVec
-	Errors          prometheus.Counter
+	mutex      sync.Mutex
+	httpServer *http.Server
+
+	NodeGroups        *prometheus.GaugeVec
+	OutdatedNodes     *prometheus.GaugeVec
+	UpdatedNodes      *prometheus.GaugeVec
+	ScaledUpNodes     *prometheus.CounterVec
+	ScaledDownNodes   *prometheus.CounterVec
+	DrainedNodes      *prometheus.CounterVec
+	RolledBackNodes   *prometheus.CounterVec
+	StuckTerminations *prometheus.CounterVec
+	DrainFailures     *prometheus.CounterVec
+	BlockedNodeGroups *prometheus.GaugeVec
+	AbortedNodeGroups *prometheus.GaugeVec
+	PausedNodeGroups  *prometheus.GaugeVec
+	Errors            prometheus.Counter
+	Degraded          prometheus.Gauge
 }
 
 func init() {
@@ -33,6 +46,7 @@ func init() {
 func newMetricServer() *metricServer {
 	m := &metricServer{
 		registry: prometheus.NewPedanticRegistry(),
+		mux:      http.NewServeMux(),
 		NodeGroups: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "node_groups",
@@ -63,18 +77,53 @@ func newMetricServer() *metricServer {
 			Name:      "drained_nodes_total",
 			Help:      "The total number of drained nodes",
 		}, []string{"node_group"}),
+		RolledBackNodes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rolled_back_nodes_total",
+			Help:      "The total number of nodes rolled back because their drain was interrupted",
+		}, []string{"node_group"}),
+		StuckTerminations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stuck_terminations_total",
+			Help:      "The total number of instances still present long after being scheduled for termination",
+		}, []string{"node_group"}),
+		DrainFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "drain_failures_total",
+			Help:      "The total number of failed drain attempts",
+		}, []string{"node_group"}),
+		BlockedNodeGroups: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "blocked_node_groups",
+			Help:      "Whether the rolling update of a node group is blocked by a node that cannot be drained",
+		}, []string{"node_group"}),
+		AbortedNodeGroups: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "aborted_node_groups",
+			Help:      "Whether the rolling update of a node group is aborted because its updated nodes are not becoming ready",
+		}, []string{"node_group"}),
+		PausedNodeGroups: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "paused_node_groups",
+			Help:      "Whether the rolling update of a node group is paused",
+		}, []string{"node_group"}),
 		Errors: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Name:      "errors",
 			Help:      "The total number of errors",
 		}),
+		Degraded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "degraded",
+			Help:      "Whether the handler is degraded because of repeated failures",
+		}),
 	}
 	m.register()
 	return m
 }
 
 func (m *metricServer) register() {
-	v := reflect.ValueOf(*m)
+	v := reflect.ValueOf(m).Elem()
 	for i := 0; i < v.NumField(); i++ {
 		if v.Field(i).CanInterface() {
 			if metric, ok := v.Field(i).Interface().(prometheus.Collector); ok {
@@ -84,8 +133,32 @@ func (m *metricServer) register() {
 	}
 }
 
-func (m *metricServer) Listen(port int) error {
+// Handle registers an additional handler on the server, which can be done before or after Listen is called
+func (m *metricServer) Handle(pattern string, handler http.Handler) {
+	m.mux.Handle(pattern, handler)
+}
+
+// HandleMetrics registers the handler exposing the metrics in Prometheus format at /metrics
+func (m *metricServer) HandleMetrics() {
 	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, m.registry}
-	http.Handle("/metrics", promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}))
-	return http.ListenAndServe(":"+strconv.Itoa(port), nil)
+	m.mux.Handle("/metrics", promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}))
+}
+
+// Listen serves every registered handler on the given port until the server is shut down
+func (m *metricServer) Listen(port int) error {
+	httpServer := &http.Server{Addr: ":" + strconv.Itoa(port), Handler: m.mux}
+	m.mutex.Lock()
+	m.httpServer = httpServer
+	m.mutex.Unlock()
+	return httpServer.ListenAndServe()
+}
+
+// Shutdown gracefully shuts down the metrics server, giving in-flight scrapes a chance to complete
+func (m *metricServer) Shutdown(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.httpServer == nil {
+		return nil
+	}
+	return m.httpServer.Shutdown(ctx)
 }
@@ -22,8 +22,26 @@ func TestMetricServer(t *testing.T) {
 	Server.UpdatedNodes.WithLabelValues("nodeg-2").Set(1)
 	Server.DrainedNodes.WithLabelValues("nodeg-1").Inc()
 	Server.DrainedNodes.WithLabelValues("nodeg-2").Inc()
+	Server.RolledBackNodes.WithLabelValues("nodeg-1").Inc()
+	Server.StuckTerminations.WithLabelValues("nodeg-2").Inc()
+	Server.DrainFailures.WithLabelValues("nodeg-1").Add(3)
+	Server.BlockedNodeGroups.WithLabelValues("nodeg-1").Set(1)
+	Server.AbortedNodeGroups.WithLabelValues("nodeg-2").Set(1)
+	Server.Degraded.Set(1)
 
 	err := testutil.GatherAndCompare(prometheus.Gatherers{Server.registry}, bytes.NewBufferString(`
+# HELP rolling_update_handler_aborted_node_groups Whether the rolling update of a node group is aborted because its updated nodes are not becoming ready
+# TYPE rolling_update_handler_aborted_node_groups gauge
+rolling_update_handler_aborted_node_groups{node_group="nodeg-2"} 1
+# HELP rolling_update_handler_blocked_node_groups Whether the rolling update of a node group is blocked by a node that cannot be drained
+# TYPE rolling_update_handler_blocked_node_groups gauge
+rolling_update_handler_blocked_node_groups{node_group="nodeg-1"} 1
+# HELP rolling_update_handler_degraded Whether the handler is degraded because of repeated failures
+# TYPE rolling_update_handler_degraded gauge
+rolling_update_handler_degraded 1
+# HELP rolling_update_handler_drain_failures_total The total number of failed drain attempts
+# TYPE rolling_update_handler_drain_failures_total counter
+rolling_update_handler_drain_failures_total{node_group="nodeg-1"} 3
 # HELP rolling_update_handler_drained_nodes_total The total number of drained nodes
 # TYPE rolling_update_handler_drained_nodes_total counter
 rolling_update_handler_drained_nodes_total{node_group="nodeg-1"} 1
@@ -38,6 +56,9 @@ rolling_update_handler_node_groups 5
 # TYPE rolling_update_handler_outdated_nodes gauge
 rolling_update_handler_outdated_nodes{node_group="nodeg-1"} 1
 rolling_update_handler_outdated_nodes{node_group="nodeg-2"} 1
+# HELP rolling_update_handler_rolled_back_nodes_total The total number of nodes rolled back because their drain was interrupted
+# TYPE rolling_update_handler_rolled_back_nodes_total counter
+rolling_update_handler_rolled_back_nodes_total{node_group="nodeg-1"} 1
 # HELP rolling_update_handler_scaled_down_nodes The total number of nodes scaled down
 # TYPE rolling_update_handler_scaled_down_nodes counter
 rolling_update_handler_scaled_down_nodes{node_group="nodeg-1"} 1
@@ -46,6 +67,9 @@ rolling_update_handler_scaled_down_nodes{node_group="nodeg-2"} 1
 # TYPE rolling_update_handler_scaled_up_nodes counter
 rolling_update_handler_scaled_up_nodes{node_group="nodeg-1"} 1
 rolling_update_handler_scaled_up_nodes{node_group="nodeg-2"} 1
+# HELP rolling_update_handler_stuck_terminations_total The total number of instances still present long after being scheduled for termination
+# TYPE rolling_update_handler_stuck_terminations_total counter
+rolling_update_handler_stuck_terminations_total{node_group="nodeg-2"} 1
 # HELP rolling_update_handler_updated_nodes The number of updated nodes
 # TYPE rolling_update_handler_updated_nodes gauge
 rolling_update_handler_updated_nodes{node_group="nodeg-1"} 1
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Step is a single step of the rolling update process
+type Step string
+
+const (
+	StepCordon                           Step = "cordon"
+	StepStartRollout                     Step = "start-rollout"
+	StepExcludeFromExternalLoadBalancers Step = "exclude-from-external-load-balancers"
+	StepDrain                            Step = "drain"
+	StepTerminate                        Step = "terminate"
+	StepCompleteLifecycleAction          Step = "complete-lifecycle-action"
+	StepScaleUp                          Step = "scale-up"
+	StepRemoveTaint                      Step = "remove-taint"
+)
+
+// Action is an action taken by the handler, or that would have been taken if the handler wasn't in dry run mode
+type Action struct {
+	AutoScalingGroupName string `json:"autoScalingGroupName"`
+	InstanceID           string `json:"instanceId,omitempty"`
+	NodeName             string `json:"nodeName,omitempty"`
+	Step                 Step   `json:"step"`
+	Reason               string `json:"reason"`
+}
+
+// Plan is the list of actions taken during an execution.
+//
+// If DryRun is true, none of the actions are actually executed; they are only recorded.
+type Plan struct {
+	DryRun  bool
+	Actions []Action
+
+	mutex sync.Mutex
+}
+
+// NewPlan creates a new Plan
+func NewPlan(dryRun bool) *Plan {
+	return &Plan{DryRun: dryRun}
+}
+
+// Record adds an action to the plan
+func (p *Plan) Record(autoScalingGroup *autoscaling.Group, instance *autoscaling.Instance, node *v1.Node, step Step, reason string) {
+	action := Action{
+		AutoScalingGroupName: aws.StringValue(autoScalingGroup.AutoScalingGroupName),
+		Step:                 step,
+		Reason:               reason,
+	}
+	if instance != nil {
+		action.InstanceID = aws.StringValue(instance.InstanceId)
+	}
+	if node != nil {
+		action.NodeName = node.Name
+	}
+	p.mutex.Lock()
+	p.Actions = append(p.Actions, action)
+	p.mutex.Unlock()
+	if p.DryRun {
+		log.Printf("[%s][%s] DRY RUN: Would %s node %s: %s", action.AutoScalingGroupName, action.InstanceID, action.Step, action.NodeName, action.Reason)
+	}
+}
+
+// Log prints a summary of every action in the plan
+func (p *Plan) Log() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.Actions) == 0 {
+		log.Println("Plan: no actions")
+		return
+	}
+	log.Printf("Plan: %d action(s)", len(p.Actions))
+	for i, action := range p.Actions {
+		log.Printf("Plan: %d. asg=%s instance=%s node=%s step=%s reason=%s", i+1, action.AutoScalingGroupName, action.InstanceID, action.NodeName, action.Step, action.Reason)
+	}
+}